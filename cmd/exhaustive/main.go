@@ -0,0 +1,12 @@
+// Command exhaustive reports switches over the events and
+// projection states of packages generated by goesgen
+// that don't handle all of them.
+package main
+
+import (
+	"github.com/romshark/goesgen/exhaustive"
+
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() { singlechecker.Main(exhaustive.Analyzer) }
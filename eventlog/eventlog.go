@@ -0,0 +1,66 @@
+// Package eventlog provides primitives shared by the event log
+// implementations satisfying the generated EventLogger interface.
+package eventlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrOffsetOutOfBound    = errors.New("offset out of bound")
+	ErrMismatchingVersions = errors.New("mismatching versions")
+	ErrInvalidPayload      = errors.New("invalid payload")
+	ErrInvalidVersion      = errors.New("invalid version")
+)
+
+// IsOffsetOutOfBoundErr returns true if the given error
+// is an offset-out-of-bound error
+func IsOffsetOutOfBoundErr(err error) bool {
+	return errors.Is(err, ErrOffsetOutOfBound)
+}
+
+// IsMismatchingVersionsErr returns true if the given error
+// is a mismatching-versions error
+func IsMismatchingVersionsErr(err error) bool {
+	return errors.Is(err, ErrMismatchingVersions)
+}
+
+// SplitJSON splits a payload containing either a single JSON object
+// or a JSON array of objects into separate events.
+// Returns ErrInvalidPayload if the payload is neither of both.
+func SplitJSON(payload []byte) ([][]byte, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) < 1 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidPayload)
+	}
+	switch payload[0] {
+	case '{':
+		if !json.Valid(payload) {
+			return nil, fmt.Errorf("%w: malformed JSON", ErrInvalidPayload)
+		}
+		return [][]byte{payload}, nil
+	case '[':
+		var a []json.RawMessage
+		if err := json.Unmarshal(payload, &a); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPayload, err)
+		}
+		if len(a) < 1 {
+			return nil, fmt.Errorf("%w: empty array", ErrInvalidPayload)
+		}
+		e := make([][]byte, len(a))
+		for i, p := range a {
+			p = bytes.TrimSpace(p)
+			if len(p) < 1 || p[0] != '{' {
+				return nil, fmt.Errorf(
+					"%w: array item %d isn't an object", ErrInvalidPayload, i,
+				)
+			}
+			e[i] = p
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("%w: expected object or array", ErrInvalidPayload)
+}
@@ -0,0 +1,55 @@
+package eventlog_test
+
+import (
+	"testing"
+
+	"github.com/romshark/goesgen/eventlog"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitJSON(t *testing.T) {
+	for _, t1 := range []struct {
+		name   string
+		input  string
+		expect []string
+	}{
+		{"object", `{"a":1}`, []string{`{"a":1}`}},
+		{"object spaces", " \n{\"a\":1}\t", []string{`{"a":1}`}},
+		{"array one", `[{"a":1}]`, []string{`{"a":1}`}},
+		{"array", `[{"a":1}, {"b":2}]`, []string{`{"a":1}`, `{"b":2}`}},
+	} {
+		t.Run(t1.name, func(t *testing.T) {
+			e, err := eventlog.SplitJSON([]byte(t1.input))
+			require.NoError(t, err)
+			s := make([]string, len(e))
+			for i, e := range e {
+				s[i] = string(e)
+			}
+			require.Equal(t, t1.expect, s)
+		})
+	}
+}
+
+func TestSplitJSONErr(t *testing.T) {
+	for _, t1 := range []struct {
+		name  string
+		input string
+	}{
+		{"empty", ``},
+		{"spaces", `  `},
+		{"string", `"foo"`},
+		{"number", `42`},
+		{"empty array", `[]`},
+		{"array of numbers", `[1,2]`},
+		{"malformed object", `{"a":`},
+		{"malformed array", `[{"a":1}`},
+	} {
+		t.Run(t1.name, func(t *testing.T) {
+			e, err := eventlog.SplitJSON([]byte(t1.input))
+			require.Error(t, err)
+			require.ErrorIs(t, err, eventlog.ErrInvalidPayload)
+			require.Nil(t, e)
+		})
+	}
+}
@@ -0,0 +1,726 @@
+// Package file provides a durable single-node event log persisted in an
+// append-only segment file satisfying the generated EventLogger interface.
+//
+// Versions are decimal byte offsets of records within the segment file.
+// The record index is persisted in an index file next to the segment file
+// when the log is closed. Opening the log loads the index and only scans
+// the records appended after it, the index is rebuilt from the whole
+// segment file if the index file is missing or doesn't match it.
+// Each record counts the records following it within the same append,
+// which allows recovery to truncate a partially written trailing append
+// left behind by a crash as a whole, keeping appends atomic.
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog"
+)
+
+// SyncPolicy defines when appended records are flushed to stable storage
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes the segment file after every append
+	SyncAlways SyncPolicy = 0
+
+	// SyncInterval flushes the segment file during an append if
+	// the last flush is older than the sync interval
+	// and when the log is closed.
+	SyncInterval SyncPolicy = 1
+
+	// SyncNever leaves flushing to the operating system
+	// except for when the log is closed.
+	SyncNever SyncPolicy = 2
+)
+
+// Options defines the options of a file event log
+type Options struct {
+	// Sync defines when appends are flushed to stable storage.
+	//
+	// Sync is SyncAlways by default.
+	Sync SyncPolicy
+
+	// SyncInterval is the minimum interval between flushes
+	// when Sync is SyncInterval.
+	//
+	// SyncInterval is 1 second by default.
+	SyncInterval time.Duration
+
+	// Clock returns the time events are appended at.
+	//
+	// Clock is time.Now by default.
+	Clock func() time.Time
+}
+
+// SetDefaults sets default values to unspecified options
+func (o *Options) SetDefaults() {
+	if o.SyncInterval == 0 {
+		o.SyncInterval = time.Second
+	}
+	if o.Clock == nil {
+		o.Clock = time.Now
+	}
+}
+
+// ErrCorrupted is returned by Open when a record
+// other than the trailing one is corrupted
+var ErrCorrupted = errors.New("corrupted segment file")
+
+// ErrFailed is returned by appends after a failed append couldn't be
+// discarded from the segment file. The log must be reopened to recover.
+var ErrFailed = errors.New("event log failed")
+
+// Record layout:
+//
+//	size     uint32 (length of the body)
+//	checksum uint32 (CRC-32C of the body)
+//	body:
+//	  time      int64 (unix nanoseconds)
+//	  following uint32 (number of records following within the append)
+//	  streams   uint16 (number of streams)
+//	  stream    uint16 (length) + bytes (repeated for every stream)
+//	  payload   bytes
+const recordHeaderLen = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Index file layout:
+//
+//	magic    [4]byte ("GEIX")
+//	size     int64 (size of the indexed segment file)
+//	records  uint64 (number of records)
+//	record   uvarint (length of the record, repeated for every record)
+//	streams  uint64 (number of streams)
+//	stream   uint16 (length) + bytes + int64 (version, repeated)
+//	checksum uint32 (CRC-32C of everything preceding it)
+const indexMagic = "GEIX"
+
+// IndexSuffix is appended to the path of the segment file
+// to get the path of its index file
+const IndexSuffix = ".index"
+
+// EventLog is a file event log
+type EventLog struct {
+	lock     sync.RWMutex
+	file     *os.File
+	path     string
+	options  Options
+	size     int64
+	index    []int64          // Offsets of all records
+	streams  map[string]int64 // Version after the last event of a stream
+	lastSync time.Time
+
+	// failed is set when a failed append couldn't be discarded
+	failed error
+}
+
+// Open opens the segment file at the given path creating it if necessary
+// and recovers the log.
+func Open(path string, options Options) (*EventLog, error) {
+	options.SetDefaults()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening segment file: %w", err)
+	}
+	l := &EventLog{
+		file:     f,
+		path:     path,
+		options:  options,
+		streams:  map[string]int64{},
+		lastSync: options.Clock(),
+	}
+	if err := l.recover(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// recover loads the persisted index, indexes the records
+// appended after it and truncates a partially written trailing append
+func (l *EventLog) recover() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("reading segment file info: %w", err)
+	}
+	fileSize := info.Size()
+
+	if err := l.loadIndex(fileSize); err != nil {
+		return err
+	}
+
+	var (
+		offset    = l.size
+		following uint32  // Records following within the current append
+		appended  []int64 // Offsets of the records of the current append
+		streams   []string
+	)
+	for offset < fileSize {
+		r, err := l.readRecord(offset, fileSize)
+		if errors.Is(err, errTornRecord) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if len(appended) > 0 && r.following != following-1 {
+			return fmt.Errorf(
+				"%w: unexpected record at offset %d", ErrCorrupted, offset,
+			)
+		}
+		appended = append(appended, offset)
+		offset += r.len
+		following, streams = r.following, r.streams
+		if following > 0 {
+			continue
+		}
+		// The append is complete
+		l.index = append(l.index, appended...)
+		l.size, appended = offset, appended[:0]
+		for _, s := range streams {
+			l.streams[s] = offset
+		}
+	}
+	if l.size < fileSize {
+		// Truncate the partially written trailing append
+		if err := l.file.Truncate(l.size); err != nil {
+			return fmt.Errorf("truncating torn append: %w", err)
+		}
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("syncing after truncation: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadIndex loads the persisted index of the segment file of the given
+// size. The index is ignored if it's missing, malformed or doesn't
+// match the segment file, which is then indexed from the beginning.
+func (l *EventLog) loadIndex(fileSize int64) error {
+	b, err := os.ReadFile(l.path + IndexSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("reading index file: %w", err)
+	}
+	size, index, streams, ok := decodeIndex(b)
+	if !ok || size > fileSize {
+		// The segment file was truncated after the index was persisted
+		return nil
+	}
+	if len(index) > 0 {
+		// Make sure the index matches the segment file
+		last := index[len(index)-1]
+		r, err := l.readRecord(last, size)
+		if err != nil || r.following != 0 || last+r.len != size {
+			return nil
+		}
+	}
+	l.size, l.index, l.streams = size, index, streams
+	return nil
+}
+
+// decodeIndex decodes an index file.
+// Returns false if the index file is malformed.
+func decodeIndex(b []byte) (
+	size int64,
+	index []int64,
+	streams map[string]int64,
+	ok bool,
+) {
+	if len(b) < len(indexMagic)+4 ||
+		string(b[:len(indexMagic)]) != indexMagic {
+		return 0, nil, nil, false
+	}
+	body, checksum := b[:len(b)-4], binary.BigEndian.Uint32(b[len(b)-4:])
+	if crc32.Checksum(body, crcTable) != checksum {
+		return 0, nil, nil, false
+	}
+	rd := bytes.NewReader(body[len(indexMagic):])
+	var records, n uint64
+	if binary.Read(rd, binary.BigEndian, &size) != nil ||
+		binary.Read(rd, binary.BigEndian, &records) != nil ||
+		records > uint64(rd.Len()) {
+		return 0, nil, nil, false
+	}
+	index = make([]int64, records)
+	var offset int64
+	for i := range index {
+		l, err := binary.ReadUvarint(rd)
+		if err != nil {
+			return 0, nil, nil, false
+		}
+		index[i], offset = offset, offset+int64(l)
+	}
+	if offset != size ||
+		binary.Read(rd, binary.BigEndian, &n) != nil ||
+		n > uint64(rd.Len()) {
+		return 0, nil, nil, false
+	}
+	streams = make(map[string]int64, n)
+	for i := uint64(0); i < n; i++ {
+		var (
+			l uint16
+			v int64
+		)
+		if binary.Read(rd, binary.BigEndian, &l) != nil ||
+			int(l) > rd.Len() {
+			return 0, nil, nil, false
+		}
+		s := make([]byte, l)
+		if _, err := io.ReadFull(rd, s); err != nil ||
+			binary.Read(rd, binary.BigEndian, &v) != nil {
+			return 0, nil, nil, false
+		}
+		streams[string(s)] = v
+	}
+	return size, index, streams, rd.Len() == 0
+}
+
+// encodeIndex encodes the index file
+func (l *EventLog) encodeIndex() []byte {
+	var b bytes.Buffer
+	b.WriteString(indexMagic)
+	binary.Write(&b, binary.BigEndian, l.size)
+	binary.Write(&b, binary.BigEndian, uint64(len(l.index)))
+	var v [binary.MaxVarintLen64]byte
+	for i, of := range l.index {
+		end := l.size
+		if i+1 < len(l.index) {
+			end = l.index[i+1]
+		}
+		b.Write(v[:binary.PutUvarint(v[:], uint64(end-of))])
+	}
+	binary.Write(&b, binary.BigEndian, uint64(len(l.streams)))
+	for s, v := range l.streams {
+		binary.Write(&b, binary.BigEndian, uint16(len(s)))
+		b.WriteString(s)
+		binary.Write(&b, binary.BigEndian, v)
+	}
+	binary.Write(&b, binary.BigEndian, crc32.Checksum(b.Bytes(), crcTable))
+	return b.Bytes()
+}
+
+// persistIndex atomically replaces the index file
+func (l *EventLog) persistIndex() error {
+	tmp := l.path + IndexSuffix + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("creating index file: %w", err)
+	}
+	if _, err := f.Write(l.encodeIndex()); err != nil {
+		f.Close()
+		return fmt.Errorf("writing index file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing index file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing index file: %w", err)
+	}
+	if err := os.Rename(tmp, l.path+IndexSuffix); err != nil {
+		return fmt.Errorf("replacing index file: %w", err)
+	}
+	return nil
+}
+
+var errTornRecord = errors.New("torn record")
+
+type record struct {
+	len       int64
+	time      time.Time
+	following uint32
+	streams   []string
+	payload   []byte
+}
+
+// readRecord reads the record at the given offset.
+// Returns errTornRecord if the record is incomplete or its checksum
+// doesn't match while it's the trailing record within limit.
+func (l *EventLog) readRecord(offset, limit int64) (record, error) {
+	var h [recordHeaderLen]byte
+	if offset+recordHeaderLen > limit {
+		return record{}, errTornRecord
+	}
+	if _, err := l.file.ReadAt(h[:], offset); err != nil {
+		return record{}, fmt.Errorf("reading record header: %w", err)
+	}
+	size := int64(binary.BigEndian.Uint32(h[0:4]))
+	checksum := binary.BigEndian.Uint32(h[4:8])
+	end := offset + recordHeaderLen + size
+	if end > limit {
+		return record{}, errTornRecord
+	}
+
+	b := make([]byte, size)
+	if _, err := l.file.ReadAt(b, offset+recordHeaderLen); err != nil {
+		return record{}, fmt.Errorf("reading record body: %w", err)
+	}
+	if crc32.Checksum(b, crcTable) != checksum {
+		if end == limit {
+			return record{}, errTornRecord
+		}
+		return record{}, fmt.Errorf(
+			"%w: checksum mismatch at offset %d", ErrCorrupted, offset,
+		)
+	}
+
+	r := record{len: recordHeaderLen + size}
+	if len(b) < 14 {
+		return record{}, fmt.Errorf(
+			"%w: malformed record at offset %d", ErrCorrupted, offset,
+		)
+	}
+	r.time = time.Unix(0, int64(binary.BigEndian.Uint64(b[0:8]))).UTC()
+	r.following = binary.BigEndian.Uint32(b[8:12])
+	n := int(binary.BigEndian.Uint16(b[12:14]))
+	b = b[14:]
+	r.streams = make([]string, n)
+	for i := range r.streams {
+		if len(b) < 2 {
+			return record{}, fmt.Errorf(
+				"%w: malformed record at offset %d", ErrCorrupted, offset,
+			)
+		}
+		l := int(binary.BigEndian.Uint16(b[0:2]))
+		if len(b) < 2+l {
+			return record{}, fmt.Errorf(
+				"%w: malformed record at offset %d", ErrCorrupted, offset,
+			)
+		}
+		r.streams[i] = string(b[2 : 2+l])
+		b = b[2+l:]
+	}
+	r.payload = b
+	return r, nil
+}
+
+// encodeRecord appends the encoded record to buf
+func encodeRecord(
+	buf []byte,
+	tm time.Time,
+	following uint32,
+	streams []string,
+	payload []byte,
+) []byte {
+	start := len(buf)
+	buf = append(buf, make([]byte, recordHeaderLen+14)...)
+	binary.BigEndian.PutUint64(
+		buf[start+recordHeaderLen:], uint64(tm.UnixNano()),
+	)
+	binary.BigEndian.PutUint32(buf[start+recordHeaderLen+8:], following)
+	binary.BigEndian.PutUint16(
+		buf[start+recordHeaderLen+12:], uint16(len(streams)),
+	)
+	for _, s := range streams {
+		var l [2]byte
+		binary.BigEndian.PutUint16(l[:], uint16(len(s)))
+		buf = append(buf, l[:]...)
+		buf = append(buf, s...)
+	}
+	buf = append(buf, payload...)
+	body := buf[start+recordHeaderLen:]
+	binary.BigEndian.PutUint32(buf[start:], uint32(len(body)))
+	binary.BigEndian.PutUint32(
+		buf[start+4:], crc32.Checksum(body, crcTable),
+	)
+	return buf
+}
+
+// Close flushes and closes the segment file
+// and persists the index next to it.
+func (l *EventLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return fmt.Errorf("syncing segment file: %w", err)
+	}
+	if l.failed == nil {
+		if err := l.persistIndex(); err != nil {
+			l.file.Close()
+			return err
+		}
+	}
+	return l.file.Close()
+}
+
+// IsOffsetOutOfBoundErr returns true if the given error
+// is an offset-out-of-bound error
+func (l *EventLog) IsOffsetOutOfBoundErr(err error) bool {
+	return eventlog.IsOffsetOutOfBoundErr(err)
+}
+
+// IsMismatchingVersionsErr returns true if the given error
+// is a mismatching-versions error returned by AppendCheckJSON
+func (l *EventLog) IsMismatchingVersionsErr(err error) bool {
+	return eventlog.IsMismatchingVersionsErr(err)
+}
+
+// Begin returns the first offset version of the eventlog.
+func (l *EventLog) Begin(context.Context) (string, error) {
+	return "0", nil
+}
+
+// Version returns the current version of the eventlog.
+func (l *EventLog) Version() string {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return formatVersion(l.size)
+}
+
+// Scan reads a limited number of events at the given offset version
+// calling the onEvent callback for every received event.
+// Returns eventlog.ErrOffsetOutOfBound if version is the
+// latest version of the log or beyond and eventlog.ErrInvalidVersion
+// if version isn't the offset of a record.
+func (l *EventLog) Scan(
+	ctx context.Context,
+	version string,
+	limit uint,
+	onEvent func(
+		offset string,
+		tm time.Time,
+		payload []byte,
+		next string,
+	) error,
+) error {
+	offset, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+
+	// Records are immutable, the callback can safely be invoked
+	// on a snapshot of the index without holding the lock
+	l.lock.RLock()
+	index, size := l.index, l.size
+	l.lock.RUnlock()
+
+	if offset >= size {
+		return eventlog.ErrOffsetOutOfBound
+	}
+	i := sort.Search(len(index), func(i int) bool {
+		return index[i] >= offset
+	})
+	if i >= len(index) || index[i] != offset {
+		return eventlog.ErrInvalidVersion
+	}
+
+	for n := uint(0); i < len(index); i, n = i+1, n+1 {
+		if limit > 0 && n >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r, err := l.readRecord(index[i], size)
+		if err != nil {
+			return fmt.Errorf("reading record at %d: %w", index[i], err)
+		}
+		if err := onEvent(
+			formatVersion(index[i]),
+			r.time,
+			r.payload,
+			formatVersion(index[i]+r.len),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams.
+func (l *EventLog) AppendJSON(
+	ctx context.Context,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	return l.append(ctx, streams, payload, func() error { return nil })
+}
+
+// AppendCheckJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if the assumed version matches the actual version of the log,
+// otherwise eventlog.ErrMismatchingVersions is returned.
+func (l *EventLog) AppendCheckJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	assumed, err := parseVersion(assumedVersion)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return l.append(ctx, streams, payload, func() error {
+		if assumed != l.size {
+			return eventlog.ErrMismatchingVersions
+		}
+		return nil
+	})
+}
+
+// AppendCheckStreamsJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if none of the given streams received any events after
+// the assumed version, otherwise eventlog.ErrMismatchingVersions
+// is returned.
+func (l *EventLog) AppendCheckStreamsJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	assumed, err := parseVersion(assumedVersion)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return l.append(ctx, streams, payload, func() error {
+		if assumed > l.size {
+			return eventlog.ErrMismatchingVersions
+		}
+		for _, s := range streams {
+			if l.streams[s] > assumed {
+				return eventlog.ErrMismatchingVersions
+			}
+		}
+		return nil
+	})
+}
+
+// append appends the events if check returns no error
+func (l *EventLog) append(
+	ctx context.Context,
+	streams []string,
+	payload []byte,
+	check func() error,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	p, err := eventlog.SplitJSON(payload)
+	if err != nil {
+		return
+	}
+	for _, s := range streams {
+		if len(s) > 0xFFFF {
+			err = fmt.Errorf("stream ID too long (%d)", len(s))
+			return
+		}
+	}
+	if len(streams) > 0xFFFF {
+		err = fmt.Errorf("too many streams (%d)", len(streams))
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.failed != nil {
+		err = l.failed
+		return
+	}
+	if err = check(); err != nil {
+		return
+	}
+
+	tm = l.options.Clock()
+	var buf []byte
+	offsets := make([]int64, len(p))
+	for i, e := range p {
+		offsets[i] = l.size + int64(len(buf))
+		buf = encodeRecord(buf, tm, uint32(len(p)-1-i), streams, e)
+	}
+
+	if _, err = l.file.WriteAt(buf, l.size); err != nil {
+		err = l.discard(fmt.Errorf("writing records: %w", err))
+		return
+	}
+	if err = l.sync(tm); err != nil {
+		err = l.discard(err)
+		return
+	}
+
+	of := l.size
+	l.size += int64(len(buf))
+	l.index = append(l.index, offsets...)
+	for _, s := range streams {
+		l.streams[s] = l.size
+	}
+	return formatVersion(of), formatVersion(l.size), tm.UTC(), nil
+}
+
+// discard discards the records of a failed append from the segment file
+// and returns err. The log is marked failed if they can't be discarded.
+func (l *EventLog) discard(err error) error {
+	if errTrunc := l.file.Truncate(l.size); errTrunc != nil {
+		l.failed = fmt.Errorf(
+			"%w: discarding failed append: %s", ErrFailed, errTrunc,
+		)
+		return fmt.Errorf("%w (%s)", l.failed, err)
+	}
+	return err
+}
+
+// sync flushes the segment file according to the sync policy
+func (l *EventLog) sync(now time.Time) error {
+	switch l.options.Sync {
+	case SyncInterval:
+		if now.Sub(l.lastSync) < l.options.SyncInterval {
+			return nil
+		}
+	case SyncNever:
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("syncing segment file: %w", err)
+	}
+	l.lastSync = now
+	return nil
+}
+
+func formatVersion(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func parseVersion(v string) (int64, error) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, eventlog.ErrInvalidVersion
+	}
+	return n, nil
+}
@@ -0,0 +1,358 @@
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog"
+	"github.com/romshark/goesgen/eventlog/file"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendScan(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _, now := newLog(t)
+
+	b, err := l.Begin(ctx)
+	r.NoError(err)
+	r.Equal("0", b)
+	r.Equal("0", l.Version())
+
+	// Scanning an empty log
+	err = l.Scan(ctx, b, 0, nil)
+	r.True(l.IsOffsetOutOfBoundErr(err))
+
+	of, nv, tm, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.Equal("0", of)
+	r.Equal(now(), tm)
+	v1 := nv
+
+	of, nv, _, err = l.AppendJSON(ctx, nil, []byte(`[{"i":1},{"i":2}]`))
+	r.NoError(err)
+	r.Equal(v1, of)
+	r.Equal(nv, l.Version())
+
+	e := scan(t, l, "0", 0)
+	r.Len(e, 3)
+	r.Equal(Event{"0", now(), `{"i":0}`, v1}, e[0])
+	r.Equal(v1, e[1].Offset)
+	r.Equal(`{"i":1}`, e[1].Payload)
+	r.Equal(e[1].Next, e[2].Offset)
+	r.Equal(`{"i":2}`, e[2].Payload)
+	r.Equal(nv, e[2].Next)
+
+	r.Equal(e[1:2], scan(t, l, v1, 1))
+
+	// Scanning at the tip
+	err = l.Scan(ctx, nv, 0, nil)
+	r.True(l.IsOffsetOutOfBoundErr(err))
+
+	// Scanning in the middle of a record
+	err = l.Scan(ctx, "1", 0, nil)
+	r.ErrorIs(err, eventlog.ErrInvalidVersion)
+}
+
+func TestReopen(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, path, _ := newLog(t)
+
+	_, v1, _, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	_, v2, _, err := l.AppendJSON(ctx, []string{"B/1"}, []byte(`{"i":1}`))
+	r.NoError(err)
+	before := scan(t, l, "0", 0)
+	r.NoError(l.Close())
+
+	l = open(t, path)
+	r.Equal(v2, l.Version())
+	r.Equal(before, scan(t, l, "0", 0))
+
+	// The stream index is rebuilt
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v1, []string{"A/1"}, []byte(`{"i":2}`),
+	)
+	r.NoError(err)
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v1, []string{"B/1"}, []byte(`{"i":3}`),
+	)
+	r.True(l.IsMismatchingVersionsErr(err))
+}
+
+func TestRecoverTornRecord(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, path, _ := newLog(t)
+
+	_, v1, _, err := l.AppendJSON(ctx, nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	_, v2, _, err := l.AppendJSON(ctx, nil, []byte(`{"i":1}`))
+	r.NoError(err)
+	r.NoError(l.Close())
+
+	// Simulate a crash during the second append
+	info, err := os.Stat(path)
+	r.NoError(err)
+	r.NoError(os.Truncate(path, info.Size()-3))
+
+	l = open(t, path)
+	r.Equal(v1, l.Version())
+	r.Len(scan(t, l, "0", 0), 1)
+
+	// The torn record is overwritten by the next append
+	of, nv, _, err := l.AppendJSON(ctx, nil, []byte(`{"i":2}`))
+	r.NoError(err)
+	r.Equal(v1, of)
+	r.Equal(v2, nv)
+	e := scan(t, l, v1, 0)
+	r.Len(e, 1)
+	r.Equal(`{"i":2}`, e[0].Payload)
+}
+
+func TestRecoverTornAppend(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, path, _ := newLog(t)
+
+	_, v1, _, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	_, _, _, err = l.AppendJSON(
+		ctx, []string{"A/1"}, []byte(`[{"i":1},{"i":2}]`),
+	)
+	r.NoError(err)
+	e := scan(t, l, "0", 0)
+	r.Len(e, 3)
+	r.NoError(l.Close())
+
+	// Simulate a crash after the first record of the second append
+	// was written completely
+	of, err := strconv.ParseInt(e[2].Offset, 10, 64)
+	r.NoError(err)
+	r.NoError(os.Truncate(path, of))
+
+	// The whole append is discarded
+	l = open(t, path)
+	r.Equal(v1, l.Version())
+	r.Equal(e[:1], scan(t, l, "0", 0))
+
+	// The stream index doesn't include the discarded append
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v1, []string{"A/1"}, []byte(`{"i":3}`),
+	)
+	r.NoError(err)
+}
+
+func TestAppendErrFailed(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, path, _ := newLog(t)
+
+	_, v1, _, err := l.AppendJSON(ctx, nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.NoError(l.Close())
+
+	// The failed append can't be discarded from the closed file
+	_, _, _, err = l.AppendJSON(ctx, nil, []byte(`{"i":1}`))
+	r.ErrorIs(err, file.ErrFailed)
+	_, _, _, err = l.AppendJSON(ctx, nil, []byte(`{"i":1}`))
+	r.ErrorIs(err, file.ErrFailed)
+	r.Equal(v1, l.Version())
+
+	l = open(t, path)
+	r.Equal(v1, l.Version())
+}
+
+func TestRecoverErrCorrupted(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, path, _ := newLog(t)
+
+	_, _, _, err := l.AppendJSON(ctx, nil, []byte(`[{"i":0},{"i":1}]`))
+	r.NoError(err)
+	r.NoError(l.Close())
+
+	// Corrupt the payload of the first record
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	r.NoError(err)
+	_, err = f.WriteAt([]byte("X"), 20)
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	// Records covered by the index are only checked when read
+	l = open(t, path)
+	err = l.Scan(ctx, "0", 0, func(string, time.Time, []byte, string) error {
+		return nil
+	})
+	r.ErrorIs(err, file.ErrCorrupted)
+	r.NoError(l.Close())
+
+	// Rebuilding the index checks all records
+	r.NoError(os.Remove(path + file.IndexSuffix))
+	_, err = file.Open(path, file.Options{})
+	r.ErrorIs(err, file.ErrCorrupted)
+}
+
+func TestReopenIndexTail(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, path, _ := newLog(t)
+
+	_, v1, _, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.NoError(l.Close())
+	r.FileExists(path + file.IndexSuffix)
+
+	// Simulate a crash after appending to the reopened log
+	l = open(t, path)
+	_, v2, _, err := l.AppendJSON(
+		ctx, []string{"B/1"}, []byte(`[{"i":1},{"i":2}]`),
+	)
+	r.NoError(err)
+	before := scan(t, l, "0", 0)
+
+	// The records appended after the index are indexed
+	l = open(t, path)
+	r.Equal(v2, l.Version())
+	r.Equal(before, scan(t, l, "0", 0))
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v1, []string{"A/1"}, []byte(`{"i":3}`),
+	)
+	r.NoError(err)
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v1, []string{"B/1"}, []byte(`{"i":4}`),
+	)
+	r.True(l.IsMismatchingVersionsErr(err))
+}
+
+func TestReopenIndexInvalid(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, path, _ := newLog(t)
+
+	_, v1, _, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	_, v2, _, err := l.AppendJSON(ctx, nil, []byte(`[{"i":1},{"i":2}]`))
+	r.NoError(err)
+	before := scan(t, l, "0", 0)
+	r.NoError(l.Close())
+
+	// A malformed index is rebuilt from the segment file
+	b, err := os.ReadFile(path + file.IndexSuffix)
+	r.NoError(err)
+	b[len(b)-1]++
+	r.NoError(os.WriteFile(path+file.IndexSuffix, b, 0644))
+
+	l = open(t, path)
+	r.Equal(v2, l.Version())
+	r.Equal(before, scan(t, l, "0", 0))
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v1, []string{"A/1"}, []byte(`{"i":3}`),
+	)
+	r.NoError(err)
+}
+
+func TestAppendCheckJSON(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _, _ := newLog(t)
+
+	_, nv, _, err := l.AppendCheckJSON(ctx, "0", nil, []byte(`{"i":0}`))
+	r.NoError(err)
+
+	// Outdated version
+	_, _, _, err = l.AppendCheckJSON(ctx, "0", nil, []byte(`{"i":1}`))
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	_, _, _, err = l.AppendCheckJSON(ctx, nv, nil, []byte(`{"i":1}`))
+	r.NoError(err)
+}
+
+func TestAppendCheckStreamsJSON(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _, _ := newLog(t)
+
+	_, v1, _, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	_, v2, _, err := l.AppendJSON(ctx, []string{"B/1"}, []byte(`{"i":1}`))
+	r.NoError(err)
+
+	// A/1 didn't change after v1
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v1, []string{"A/1"}, []byte(`{"i":2}`),
+	)
+	r.NoError(err)
+
+	// A/1 changed after v2
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v2, []string{"A/1"}, []byte(`{"i":3}`),
+	)
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	// A/2 never changed
+	_, v4, _, err := l.AppendCheckStreamsJSON(
+		ctx, "0", []string{"A/2"}, []byte(`{"i":3}`),
+	)
+	r.NoError(err)
+
+	// Version from the future
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, v4+"0", []string{"A/3"}, []byte(`{"i":4}`),
+	)
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	r.Equal(v4, l.Version())
+}
+
+func TestAppendErrInvalid(t *testing.T) {
+	l, _, _ := newLog(t)
+	_, _, _, err := l.AppendJSON(context.Background(), nil, []byte(`42`))
+	require.ErrorIs(t, err, eventlog.ErrInvalidPayload)
+	require.Equal(t, "0", l.Version())
+}
+
+type Event struct {
+	Offset  string
+	Time    time.Time
+	Payload string
+	Next    string
+}
+
+func newLog(t *testing.T) (*file.EventLog, string, func() time.Time) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	return open(t, path), path, now
+}
+
+func now() time.Time {
+	return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func open(t *testing.T, path string) *file.EventLog {
+	l, err := file.Open(path, file.Options{Clock: now})
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func scan(
+	t *testing.T,
+	l *file.EventLog,
+	version string,
+	limit uint,
+) (e []Event) {
+	require.NoError(t, l.Scan(
+		context.Background(), version, limit,
+		func(offset string, tm time.Time, payload []byte, next string) error {
+			e = append(e, Event{offset, tm, string(payload), next})
+			return nil
+		},
+	))
+	return
+}
@@ -0,0 +1,249 @@
+// Package inmem provides a volatile thread-safe in-memory event log
+// satisfying the generated EventLogger interface.
+// It's primarily intended for tests and local development.
+//
+// Versions are decimal event indexes starting at "0".
+package inmem
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog"
+)
+
+// Options defines the options of an in-memory event log
+type Options struct {
+	// Clock returns the time events are appended at.
+	// Use a fake clock to get deterministic timestamps in tests.
+	//
+	// Clock is time.Now by default.
+	Clock func() time.Time
+}
+
+type event struct {
+	time    time.Time
+	payload []byte
+}
+
+// EventLog is an in-memory event log
+type EventLog struct {
+	lock    sync.RWMutex
+	clock   func() time.Time
+	events  []event
+	streams map[string]uint64 // Version after the last event of a stream
+}
+
+// New creates a new empty in-memory event log
+func New(options Options) *EventLog {
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	return &EventLog{
+		clock:   options.Clock,
+		streams: map[string]uint64{},
+	}
+}
+
+// IsOffsetOutOfBoundErr returns true if the given error
+// is an offset-out-of-bound error
+func (l *EventLog) IsOffsetOutOfBoundErr(err error) bool {
+	return eventlog.IsOffsetOutOfBoundErr(err)
+}
+
+// IsMismatchingVersionsErr returns true if the given error
+// is a mismatching-versions error returned by AppendCheckJSON
+func (l *EventLog) IsMismatchingVersionsErr(err error) bool {
+	return eventlog.IsMismatchingVersionsErr(err)
+}
+
+// Begin returns the first offset version of the eventlog.
+func (l *EventLog) Begin(context.Context) (string, error) {
+	return "0", nil
+}
+
+// Version returns the current version of the eventlog.
+func (l *EventLog) Version() string {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return formatVersion(uint64(len(l.events)))
+}
+
+// Scan reads a limited number of events at the given offset version
+// calling the onEvent callback for every received event.
+// Returns eventlog.ErrOffsetOutOfBound if version is the
+// latest version of the log or beyond.
+func (l *EventLog) Scan(
+	ctx context.Context,
+	version string,
+	limit uint,
+	onEvent func(
+		offset string,
+		tm time.Time,
+		payload []byte,
+		next string,
+	) error,
+) error {
+	offset, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+
+	// Events are immutable, the callback can safely be invoked
+	// on a snapshot without holding the lock
+	l.lock.RLock()
+	events := l.events
+	l.lock.RUnlock()
+
+	if offset >= uint64(len(events)) {
+		return eventlog.ErrOffsetOutOfBound
+	}
+	for i := uint(0); offset < uint64(len(events)); i, offset = i+1, offset+1 {
+		if limit > 0 && i >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		e := events[offset]
+		if err := onEvent(
+			formatVersion(offset),
+			e.time,
+			e.payload,
+			formatVersion(offset+1),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams.
+func (l *EventLog) AppendJSON(
+	ctx context.Context,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	return l.append(ctx, streams, payload, func() error { return nil })
+}
+
+// AppendCheckJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if the assumed version matches the actual version of the log,
+// otherwise eventlog.ErrMismatchingVersions is returned.
+func (l *EventLog) AppendCheckJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	assumed, err := parseVersion(assumedVersion)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return l.append(ctx, streams, payload, func() error {
+		if assumed != uint64(len(l.events)) {
+			return eventlog.ErrMismatchingVersions
+		}
+		return nil
+	})
+}
+
+// AppendCheckStreamsJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if none of the given streams received any events after
+// the assumed version, otherwise eventlog.ErrMismatchingVersions
+// is returned.
+func (l *EventLog) AppendCheckStreamsJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	assumed, err := parseVersion(assumedVersion)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return l.append(ctx, streams, payload, func() error {
+		if assumed > uint64(len(l.events)) {
+			return eventlog.ErrMismatchingVersions
+		}
+		for _, s := range streams {
+			if l.streams[s] > assumed {
+				return eventlog.ErrMismatchingVersions
+			}
+		}
+		return nil
+	})
+}
+
+// append appends the events if check returns no error
+func (l *EventLog) append(
+	ctx context.Context,
+	streams []string,
+	payload []byte,
+	check func() error,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	p, err := eventlog.SplitJSON(payload)
+	if err != nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err = check(); err != nil {
+		return
+	}
+
+	tm = l.clock()
+	of := uint64(len(l.events))
+	for _, p := range p {
+		c := make([]byte, len(p))
+		copy(c, p)
+		l.events = append(l.events, event{time: tm, payload: c})
+	}
+	nv := uint64(len(l.events))
+	for _, s := range streams {
+		l.streams[s] = nv
+	}
+	return formatVersion(of), formatVersion(nv), tm, nil
+}
+
+func formatVersion(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func parseVersion(v string) (uint64, error) {
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, eventlog.ErrInvalidVersion
+	}
+	return n, nil
+}
@@ -0,0 +1,225 @@
+package inmem_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog"
+	"github.com/romshark/goesgen/eventlog/inmem"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendScan(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, now := newLog()
+
+	b, err := l.Begin(ctx)
+	r.NoError(err)
+	r.Equal("0", b)
+	r.Equal("0", l.Version())
+
+	// Scanning an empty log
+	err = l.Scan(ctx, b, 0, nil)
+	r.True(l.IsOffsetOutOfBoundErr(err))
+
+	of, nv, tm, err := l.AppendJSON(ctx, nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.Equal("0", of)
+	r.Equal("1", nv)
+	r.Equal(now(), tm)
+
+	of, nv, tm, err = l.AppendJSON(ctx, nil, []byte(`[{"i":1},{"i":2}]`))
+	r.NoError(err)
+	r.Equal("1", of)
+	r.Equal("3", nv)
+	r.Equal(now(), tm)
+	r.Equal("3", l.Version())
+
+	r.Equal([]Event{
+		{"0", now(), `{"i":0}`, "1"},
+		{"1", now(), `{"i":1}`, "2"},
+		{"2", now(), `{"i":2}`, "3"},
+	}, scan(t, l, "0", 0))
+	r.Equal([]Event{
+		{"1", now(), `{"i":1}`, "2"},
+	}, scan(t, l, "1", 1))
+
+	// Scanning at the tip
+	err = l.Scan(ctx, "3", 0, nil)
+	r.True(l.IsOffsetOutOfBoundErr(err))
+}
+
+func TestAppendErrInvalid(t *testing.T) {
+	l, _ := newLog()
+	_, _, _, err := l.AppendJSON(context.Background(), nil, []byte(`42`))
+	require.ErrorIs(t, err, eventlog.ErrInvalidPayload)
+	require.Equal(t, "0", l.Version())
+}
+
+func TestScanErrInvalidVersion(t *testing.T) {
+	l, _ := newLog()
+	err := l.Scan(context.Background(), "x", 0, nil)
+	require.ErrorIs(t, err, eventlog.ErrInvalidVersion)
+}
+
+func TestAppendCheckJSON(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _ := newLog()
+
+	_, nv, _, err := l.AppendCheckJSON(ctx, "0", nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.Equal("1", nv)
+
+	// Outdated version
+	_, _, _, err = l.AppendCheckJSON(ctx, "0", nil, []byte(`{"i":1}`))
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	// Version from the future
+	_, _, _, err = l.AppendCheckJSON(ctx, "2", nil, []byte(`{"i":1}`))
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	r.Equal("1", l.Version())
+}
+
+func TestAppendCheckStreamsJSON(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _ := newLog()
+
+	_, _, _, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	_, _, _, err = l.AppendJSON(ctx, []string{"B/1"}, []byte(`{"i":1}`))
+	r.NoError(err)
+
+	// A/1 didn't change after version 1
+	_, nv, _, err := l.AppendCheckStreamsJSON(
+		ctx, "1", []string{"A/1"}, []byte(`{"i":2}`),
+	)
+	r.NoError(err)
+	r.Equal("3", nv)
+
+	// A/1 changed after version 1
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, "1", []string{"A/1"}, []byte(`{"i":3}`),
+	)
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	// A/2 never changed
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, "0", []string{"A/2"}, []byte(`{"i":3}`),
+	)
+	r.NoError(err)
+
+	// Version from the future
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, "5", []string{"A/3"}, []byte(`{"i":4}`),
+	)
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	r.Equal("4", l.Version())
+}
+
+func TestAppendCheckConcurrent(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		append func(
+			l *inmem.EventLog, version string, payload []byte,
+		) error
+	}{
+		{"AppendCheckJSON", func(
+			l *inmem.EventLog, version string, payload []byte,
+		) error {
+			_, _, _, err := l.AppendCheckJSON(
+				context.Background(), version, nil, payload,
+			)
+			return err
+		}},
+		{"AppendCheckStreamsJSON", func(
+			l *inmem.EventLog, version string, payload []byte,
+		) error {
+			_, _, _, err := l.AppendCheckStreamsJSON(
+				context.Background(), version, []string{"A/1"}, payload,
+			)
+			return err
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			l, _ := newLog()
+			for i := 0; i < 100; i++ {
+				version := l.Version()
+
+				// Race two appends at the same version
+				var wg sync.WaitGroup
+				var start sync.WaitGroup
+				start.Add(1)
+				errs := make([]error, 2)
+				for j := range errs {
+					wg.Add(1)
+					go func(j int) {
+						defer wg.Done()
+						start.Wait()
+						errs[j] = tt.append(l, version, []byte(`{"i":0}`))
+					}(j)
+				}
+				start.Done()
+				wg.Wait()
+
+				succeeded := 0
+				for _, err := range errs {
+					if err == nil {
+						succeeded++
+						continue
+					}
+					r.True(l.IsMismatchingVersionsErr(err))
+				}
+				r.Equal(1, succeeded)
+			}
+			r.Equal("100", l.Version())
+		})
+	}
+}
+
+func TestAppendCanceled(t *testing.T) {
+	l, _ := newLog()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, _, err := l.AppendJSON(ctx, nil, []byte(`{"i":0}`))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, "0", l.Version())
+}
+
+type Event struct {
+	Offset  string
+	Time    time.Time
+	Payload string
+	Next    string
+}
+
+func newLog() (*inmem.EventLog, func() time.Time) {
+	now := func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return inmem.New(inmem.Options{Clock: now}), now
+}
+
+func scan(
+	t *testing.T,
+	l *inmem.EventLog,
+	version string,
+	limit uint,
+) (e []Event) {
+	require.NoError(t, l.Scan(
+		context.Background(), version, limit,
+		func(offset string, tm time.Time, payload []byte, next string) error {
+			e = append(e, Event{offset, tm, string(payload), next})
+			return nil
+		},
+	))
+	return
+}
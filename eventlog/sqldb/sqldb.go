@@ -0,0 +1,551 @@
+// Package sqldb provides an event log on top of database/sql
+// satisfying the generated EventLogger interface.
+//
+// Events are stored in a table with a gap-free monotonic sequence,
+// versions are decimal sequence numbers of the latest event.
+// Queries are compatible with SQLite and PostgreSQL.
+//
+// Appends can share a database transaction with a store handler,
+// see WithTx. Concurrent appends conflicting on the sequence
+// are rejected with eventlog.ErrMismatchingVersions, see IsConflictErr,
+// except for appends of AppendJSON, which are retried.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog"
+)
+
+// Dialect defines the SQL dialect of the database
+type Dialect int
+
+const (
+	// SQLite uses ? placeholders
+	SQLite Dialect = 0
+
+	// PostgreSQL uses $n placeholders
+	PostgreSQL Dialect = 1
+)
+
+// Options defines the options of an SQL event log
+type Options struct {
+	// Table is the name of the events table.
+	// The stream index is stored in a table with the suffix "_streams".
+	//
+	// Table is "eventlog" by default.
+	Table string
+
+	// Dialect is SQLite by default.
+	Dialect Dialect
+
+	// Clock returns the time events are appended at.
+	//
+	// Clock is time.Now by default.
+	Clock func() time.Time
+
+	// IsConflictErr returns true if the given database error was caused
+	// by a concurrent append, in which case the append is rejected
+	// with eventlog.ErrMismatchingVersions allowing the service to retry.
+	//
+	// IsConflictErr is IsConflictErr by default.
+	IsConflictErr func(error) bool
+
+	// MaxAttempts limits the attempts of AppendJSON, which retries
+	// appends conflicting with concurrent appends because it doesn't
+	// check versions. Appends within a transaction carried by
+	// the context aren't retried since the transaction must be retried.
+	//
+	// MaxAttempts is DefaultMaxAttempts by default.
+	MaxAttempts uint
+}
+
+// DefaultMaxAttempts is the default value of Options.MaxAttempts
+const DefaultMaxAttempts = 16
+
+// SetDefaults sets default values to unspecified options
+func (o *Options) SetDefaults() {
+	if o.Table == "" {
+		o.Table = "eventlog"
+	}
+	if o.Clock == nil {
+		o.Clock = time.Now
+	}
+	if o.IsConflictErr == nil {
+		o.IsConflictErr = IsConflictErr
+	}
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+}
+
+// IsConflictErr returns true if err carries the SQLSTATE code
+// of a unique violation, a serialization failure or a deadlock,
+// which PostgreSQL reports when a concurrent transaction
+// appended an event with the same sequence number.
+// The SQLSTATE code is read from errors implementing
+// SQLState() string, which the common PostgreSQL drivers do.
+//
+// SQLite databases limited to a single open connection never report
+// conflicts since appends are serialized.
+func IsConflictErr(err error) bool {
+	var e interface{ SQLState() string }
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.SQLState() {
+	case "23505", // unique_violation
+		"40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return true
+	}
+	return false
+}
+
+// conflictErr is a database error caused by a concurrent append
+// satisfying eventlog.IsMismatchingVersionsErr
+type conflictErr struct{ err error }
+
+func (e conflictErr) Error() string {
+	return eventlog.ErrMismatchingVersions.Error() + ": " + e.err.Error()
+}
+
+func (e conflictErr) Is(target error) bool {
+	return target == eventlog.ErrMismatchingVersions
+}
+
+func (e conflictErr) Unwrap() error { return e.err }
+
+// scanBatchSize is the maximum number of events
+// Scan reads at once when no limit is given
+const scanBatchSize = 1024
+
+// EventLog is an SQL event log
+type EventLog struct {
+	db      *sql.DB
+	options Options
+
+	queryVersion       string
+	queryStreamVersion string
+	queryScan          string
+	queryInsert        string
+	queryUpsertStream  string
+}
+
+const savepoint = "goesgen_eventlog_append"
+
+// ctxKeyTx is the context key of the transaction bound to db
+type ctxKeyTx struct{ db *sql.DB }
+
+// WithTx returns a context carrying tx of database db.
+// Calls to an EventLog of db with the returned context are executed
+// within tx instead of a new transaction, tx is neither
+// committed nor rolled back by the EventLog.
+// Event logs of other databases ignore tx.
+//
+// A store handler's read-write transaction can bind itself
+// to the context of the service by implementing
+// StoreTransactionContextBinder, as the generated SQLTransaction does,
+// which makes projection updates and appends of transaction methods
+// atomic when the store and the event log share the database.
+func WithTx(ctx context.Context, db *sql.DB, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, ctxKeyTx{db}, tx)
+}
+
+// txFromContext returns the transaction of db carried by ctx, if any
+func txFromContext(ctx context.Context, db *sql.DB) *sql.Tx {
+	tx, _ := ctx.Value(ctxKeyTx{db}).(*sql.Tx)
+	return tx
+}
+
+// New creates the tables if they don't exist yet.
+//
+// SQLite databases should be limited to a single open connection
+// using db.SetMaxOpenConns(1) to avoid busy errors on concurrent writes.
+func New(ctx context.Context, db *sql.DB, options Options) (*EventLog, error) {
+	if db == nil {
+		panic("db is nil in New")
+	}
+	options.SetDefaults()
+	t, ts := options.Table, options.Table+"_streams"
+	p := options.Dialect.placeholder
+
+	for _, q := range []string{
+		`CREATE TABLE IF NOT EXISTS ` + t + ` (` +
+			`seq BIGINT PRIMARY KEY, ` +
+			`time BIGINT NOT NULL, ` +
+			`payload TEXT NOT NULL)`,
+		`CREATE TABLE IF NOT EXISTS ` + ts + ` (` +
+			`stream TEXT PRIMARY KEY, ` +
+			`seq BIGINT NOT NULL)`,
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return nil, fmt.Errorf("creating tables: %w", err)
+		}
+	}
+
+	return &EventLog{
+		db:      db,
+		options: options,
+
+		queryVersion: `SELECT COALESCE(MAX(seq), 0) FROM ` + t,
+		queryStreamVersion: `SELECT seq FROM ` + ts +
+			` WHERE stream = ` + p(1),
+		queryScan: `SELECT seq, time, payload FROM ` + t +
+			` WHERE seq > ` + p(1) + ` ORDER BY seq LIMIT ` + p(2),
+		queryInsert: `INSERT INTO ` + t + ` (seq, time, payload) ` +
+			`VALUES (` + p(1) + `, ` + p(2) + `, ` + p(3) + `)`,
+		queryUpsertStream: `INSERT INTO ` + ts + ` (stream, seq) ` +
+			`VALUES (` + p(1) + `, ` + p(2) + `) ` +
+			`ON CONFLICT (stream) DO UPDATE SET seq = excluded.seq`,
+	}, nil
+}
+
+func (d Dialect) placeholder(i int) string {
+	if d == PostgreSQL {
+		return "$" + strconv.Itoa(i)
+	}
+	return "?"
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+// querier returns the transaction carried by ctx or the database
+func (l *EventLog) querier(ctx context.Context) querier {
+	if tx := txFromContext(ctx, l.db); tx != nil {
+		return tx
+	}
+	return l.db
+}
+
+// IsOffsetOutOfBoundErr returns true if the given error
+// is an offset-out-of-bound error
+func (l *EventLog) IsOffsetOutOfBoundErr(err error) bool {
+	return eventlog.IsOffsetOutOfBoundErr(err)
+}
+
+// IsMismatchingVersionsErr returns true if the given error
+// is a mismatching-versions error returned by AppendCheckJSON
+func (l *EventLog) IsMismatchingVersionsErr(err error) bool {
+	return eventlog.IsMismatchingVersionsErr(err)
+}
+
+// Begin returns the first offset version of the eventlog.
+func (l *EventLog) Begin(context.Context) (string, error) {
+	return "0", nil
+}
+
+// Version returns the current version of the eventlog.
+func (l *EventLog) Version(ctx context.Context) (string, error) {
+	v, err := l.version(ctx, l.querier(ctx))
+	if err != nil {
+		return "", err
+	}
+	return formatVersion(v), nil
+}
+
+func (l *EventLog) version(ctx context.Context, q querier) (v int64, err error) {
+	if err = q.QueryRowContext(ctx, l.queryVersion).Scan(&v); err != nil {
+		err = fmt.Errorf("querying version: %w", err)
+	}
+	return
+}
+
+// Scan reads a limited number of events at the given offset version
+// calling the onEvent callback for every received event.
+// Returns eventlog.ErrOffsetOutOfBound if version is the
+// latest version of the log or beyond.
+//
+// Events are read in batches and onEvent is only invoked after
+// a batch was read which allows onEvent to use the same transaction.
+func (l *EventLog) Scan(
+	ctx context.Context,
+	version string,
+	limit uint,
+	onEvent func(
+		offset string,
+		tm time.Time,
+		payload []byte,
+		next string,
+	) error,
+) error {
+	offset, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+	q := l.querier(ctx)
+
+	v, err := l.version(ctx, q)
+	if err != nil {
+		return err
+	}
+	if offset >= v {
+		return eventlog.ErrOffsetOutOfBound
+	}
+
+	type event struct {
+		seq     int64
+		time    int64
+		payload []byte
+	}
+	var batch []event
+	for n := uint(0); limit == 0 || n < limit; {
+		size := uint(scanBatchSize)
+		if limit > 0 && limit-n < size {
+			size = limit - n
+		}
+
+		rows, err := q.QueryContext(ctx, l.queryScan, offset, size)
+		if err != nil {
+			return fmt.Errorf("querying events: %w", err)
+		}
+		batch = batch[:0]
+		for rows.Next() {
+			var e event
+			if err := rows.Scan(&e.seq, &e.time, &e.payload); err != nil {
+				rows.Close()
+				return fmt.Errorf("scanning event: %w", err)
+			}
+			batch = append(batch, e)
+		}
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("reading events: %w", err)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("reading events: %w", err)
+		}
+
+		for _, e := range batch {
+			if err := onEvent(
+				formatVersion(e.seq-1),
+				time.Unix(0, e.time).UTC(),
+				e.payload,
+				formatVersion(e.seq),
+			); err != nil {
+				return err
+			}
+			offset = e.seq
+		}
+		n += uint(len(batch))
+		if uint(len(batch)) < size {
+			break
+		}
+	}
+	return nil
+}
+
+// AppendJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams.
+// Appends conflicting with concurrent appends are retried
+// up to Options.MaxAttempts times unless ctx carries a transaction.
+func (l *EventLog) AppendJSON(
+	ctx context.Context,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	shared := txFromContext(ctx, l.db) != nil
+	for n := uint(1); ; n++ {
+		offset, newVersion, tm, err = l.append(ctx, streams, payload, nil)
+		if shared || n >= l.options.MaxAttempts ||
+			!errors.Is(err, eventlog.ErrMismatchingVersions) {
+			return
+		}
+	}
+}
+
+// AppendCheckJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if the assumed version matches the actual version of the log,
+// otherwise eventlog.ErrMismatchingVersions is returned.
+func (l *EventLog) AppendCheckJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	assumed, err := parseVersion(assumedVersion)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return l.append(ctx, streams, payload, func(
+		ctx context.Context,
+		q querier,
+		version int64,
+	) error {
+		if assumed != version {
+			return eventlog.ErrMismatchingVersions
+		}
+		return nil
+	})
+}
+
+// AppendCheckStreamsJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if none of the given streams received any events after
+// the assumed version, otherwise eventlog.ErrMismatchingVersions
+// is returned.
+func (l *EventLog) AppendCheckStreamsJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	assumed, err := parseVersion(assumedVersion)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return l.append(ctx, streams, payload, func(
+		ctx context.Context,
+		q querier,
+		version int64,
+	) error {
+		if assumed > version {
+			return eventlog.ErrMismatchingVersions
+		}
+		for _, s := range streams {
+			var v int64
+			err := q.QueryRowContext(ctx, l.queryStreamVersion, s).Scan(&v)
+			switch {
+			case err == sql.ErrNoRows:
+				continue
+			case err != nil:
+				return fmt.Errorf("querying stream version: %w", err)
+			case v > assumed:
+				return eventlog.ErrMismatchingVersions
+			}
+		}
+		return nil
+	})
+}
+
+// append appends the events within the transaction carried by ctx
+// or a new serializable transaction if check returns no error.
+// Appends within the transaction carried by ctx are enclosed in
+// a savepoint to keep the transaction usable after a failed append.
+// Database errors caused by concurrent appends
+// are reported as eventlog.ErrMismatchingVersions.
+func (l *EventLog) append(
+	ctx context.Context,
+	streams []string,
+	payload []byte,
+	check func(context.Context, querier, int64) error,
+) (
+	offset string,
+	newVersion string,
+	tm time.Time,
+	err error,
+) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	p, err := eventlog.SplitJSON(payload)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil && !errors.Is(err, eventlog.ErrMismatchingVersions) &&
+			l.options.IsConflictErr(err) {
+			err = conflictErr{err}
+		}
+	}()
+
+	tx := txFromContext(ctx, l.db)
+	if tx == nil {
+		if tx, err = l.db.BeginTx(ctx, &sql.TxOptions{
+			Isolation: sql.LevelSerializable,
+		}); err != nil {
+			err = fmt.Errorf("beginning transaction: %w", err)
+			return
+		}
+		defer func() {
+			if err != nil {
+				_ = tx.Rollback()
+				return
+			}
+			if err = tx.Commit(); err != nil {
+				err = fmt.Errorf("committing transaction: %w", err)
+			}
+		}()
+	} else {
+		if _, err = tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+			err = fmt.Errorf("setting savepoint: %w", err)
+			return
+		}
+		defer func() {
+			if err != nil {
+				_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			}
+			if _, errRelease := tx.ExecContext(
+				ctx, "RELEASE SAVEPOINT "+savepoint,
+			); errRelease != nil && err == nil {
+				err = fmt.Errorf("releasing savepoint: %w", errRelease)
+			}
+		}()
+	}
+
+	v, err := l.version(ctx, tx)
+	if err != nil {
+		return
+	}
+	if check != nil {
+		if err = check(ctx, tx, v); err != nil {
+			return
+		}
+	}
+
+	tm = l.options.Clock().UTC()
+	for i, p := range p {
+		if _, err = tx.ExecContext(
+			ctx, l.queryInsert, v+int64(i)+1, tm.UnixNano(), string(p),
+		); err != nil {
+			err = fmt.Errorf("inserting event: %w", err)
+			return
+		}
+	}
+	nv := v + int64(len(p))
+	for _, s := range streams {
+		if _, err = tx.ExecContext(ctx, l.queryUpsertStream, s, nv); err != nil {
+			err = fmt.Errorf("updating stream version: %w", err)
+			return
+		}
+	}
+	return formatVersion(v), formatVersion(nv), tm, nil
+}
+
+func formatVersion(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func parseVersion(v string) (int64, error) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, eventlog.ErrInvalidVersion
+	}
+	return n, nil
+}
@@ -0,0 +1,352 @@
+package sqldb_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog"
+	"github.com/romshark/goesgen/eventlog/sqldb"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendScan(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _ := newLog(t)
+
+	b, err := l.Begin(ctx)
+	r.NoError(err)
+	r.Equal("0", b)
+	r.Equal("0", version(t, l))
+
+	// Scanning an empty log
+	err = l.Scan(ctx, b, 0, nil)
+	r.True(l.IsOffsetOutOfBoundErr(err))
+
+	of, nv, tm, err := l.AppendJSON(ctx, nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.Equal("0", of)
+	r.Equal("1", nv)
+	r.Equal(now(), tm)
+
+	of, nv, _, err = l.AppendJSON(ctx, nil, []byte(`[{"i":1},{"i":2}]`))
+	r.NoError(err)
+	r.Equal("1", of)
+	r.Equal("3", nv)
+	r.Equal("3", version(t, l))
+
+	r.Equal([]Event{
+		{"0", now(), `{"i":0}`, "1"},
+		{"1", now(), `{"i":1}`, "2"},
+		{"2", now(), `{"i":2}`, "3"},
+	}, scan(t, l, "0", 0))
+	r.Equal([]Event{
+		{"1", now(), `{"i":1}`, "2"},
+	}, scan(t, l, "1", 1))
+
+	// Scanning at the tip
+	err = l.Scan(ctx, "3", 0, nil)
+	r.True(l.IsOffsetOutOfBoundErr(err))
+}
+
+func TestAppendCheckJSON(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _ := newLog(t)
+
+	_, nv, _, err := l.AppendCheckJSON(ctx, "0", nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.Equal("1", nv)
+
+	// Outdated version
+	_, _, _, err = l.AppendCheckJSON(ctx, "0", nil, []byte(`{"i":1}`))
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	r.Equal("1", version(t, l))
+}
+
+func TestAppendCheckStreamsJSON(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _ := newLog(t)
+
+	_, _, _, err := l.AppendJSON(ctx, []string{"A/1"}, []byte(`{"i":0}`))
+	r.NoError(err)
+	_, _, _, err = l.AppendJSON(ctx, []string{"B/1"}, []byte(`{"i":1}`))
+	r.NoError(err)
+
+	// A/1 didn't change after version 1
+	_, nv, _, err := l.AppendCheckStreamsJSON(
+		ctx, "1", []string{"A/1"}, []byte(`{"i":2}`),
+	)
+	r.NoError(err)
+	r.Equal("3", nv)
+
+	// A/1 changed after version 1
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, "1", []string{"A/1"}, []byte(`{"i":3}`),
+	)
+	r.True(l.IsMismatchingVersionsErr(err))
+
+	// A/2 never changed
+	_, _, _, err = l.AppendCheckStreamsJSON(
+		ctx, "0", []string{"A/2"}, []byte(`{"i":3}`),
+	)
+	r.NoError(err)
+
+	r.Equal("4", version(t, l))
+}
+
+func TestSharedTx(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, db := newLog(t)
+
+	// Rolled back
+	tx, err := db.BeginTx(ctx, nil)
+	r.NoError(err)
+	txCtx := sqldb.WithTx(ctx, db, tx)
+	_, _, _, err = l.AppendJSON(txCtx, nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	v, err := l.Version(txCtx)
+	r.NoError(err)
+	r.Equal("1", v)
+	r.NoError(tx.Rollback())
+	r.Equal("0", version(t, l))
+
+	// Committed
+	tx, err = db.BeginTx(ctx, nil)
+	r.NoError(err)
+	_, _, _, err = l.AppendJSON(sqldb.WithTx(ctx, db, tx), nil, []byte(`{"i":1}`))
+	r.NoError(err)
+	r.NoError(tx.Commit())
+	r.Equal([]Event{
+		{"0", now(), `{"i":1}`, "1"},
+	}, scan(t, l, "0", 0))
+}
+
+func TestAppendConflict(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	r.NoError(err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	var tx *sql.Tx
+	var concurrent bool
+	l, err := sqldb.New(ctx, db, sqldb.Options{
+		Clock: func() time.Time {
+			if concurrent {
+				// Simulate a concurrent append taking the next
+				// sequence number after the version was read
+				_, err := tx.Exec(
+					`INSERT INTO eventlog (seq, time, payload)
+					VALUES (1, 0, '{}')`,
+				)
+				r.NoError(err)
+			}
+			return now()
+		},
+		IsConflictErr: func(err error) bool {
+			var e sqlite3.Error
+			return errors.As(err, &e) && e.Code == sqlite3.ErrConstraint
+		},
+	})
+	r.NoError(err)
+
+	tx, err = db.BeginTx(ctx, nil)
+	r.NoError(err)
+	defer tx.Rollback()
+	txCtx := sqldb.WithTx(ctx, db, tx)
+
+	concurrent = true
+	_, _, _, err = l.AppendJSON(txCtx, nil, []byte(`{"i":0}`))
+	r.True(l.IsMismatchingVersionsErr(err))
+	var errSQLite sqlite3.Error
+	r.ErrorAs(err, &errSQLite)
+
+	// The failed append was rolled back keeping the transaction usable
+	concurrent = false
+	_, nv, _, err := l.AppendJSON(txCtx, nil, []byte(`{"i":1}`))
+	r.NoError(err)
+	r.Equal("1", nv)
+	r.NoError(tx.Commit())
+}
+
+func TestAppendJSONRetry(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	db := openWAL(t)
+
+	concurrent := true
+	l, err := sqldb.New(ctx, db, sqldb.Options{
+		Clock: func() time.Time {
+			if concurrent {
+				// Simulate a concurrent append committed
+				// after the version was read
+				concurrent = false
+				_, err := db.Exec(
+					`INSERT INTO eventlog (seq, time, payload)
+					VALUES (1, 0, '{}')`,
+				)
+				r.NoError(err)
+			}
+			return now()
+		},
+		IsConflictErr: isSQLiteConflictErr,
+	})
+	r.NoError(err)
+
+	of, nv, _, err := l.AppendJSON(ctx, nil, []byte(`{"i":0}`))
+	r.NoError(err)
+	r.Equal("1", of)
+	r.Equal("2", nv)
+}
+
+func TestAppendJSONConcurrent(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	db := openWAL(t)
+	l, err := sqldb.New(ctx, db, sqldb.Options{
+		Clock:         now,
+		IsConflictErr: isSQLiteConflictErr,
+		MaxAttempts:   1000,
+	})
+	r.NoError(err)
+
+	const writers, appends = 4, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*appends)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < appends; j++ {
+				_, _, _, err := l.AppendJSON(ctx, nil, []byte(`{}`))
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		r.NoError(err)
+	}
+	r.Equal(fmt.Sprint(writers*appends), version(t, l))
+	r.Len(scan(t, l, "0", 0), writers*appends)
+}
+
+// openWAL opens a file database in WAL mode allowing
+// concurrent transactions on multiple connections
+func openWAL(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", "file:"+
+		filepath.Join(t.TempDir(), "eventlog.db")+
+		"?_journal_mode=WAL&_busy_timeout=5000",
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// isSQLiteConflictErr returns true for busy errors, which SQLite reports
+// when a transaction read a snapshot that became outdated
+// by a concurrent write
+func isSQLiteConflictErr(err error) bool {
+	var e sqlite3.Error
+	return errors.As(err, &e) && e.Code == sqlite3.ErrBusy
+}
+
+type sqlStateErr string
+
+func (e sqlStateErr) Error() string    { return "SQLSTATE " + string(e) }
+func (e sqlStateErr) SQLState() string { return string(e) }
+
+func TestIsConflictErr(t *testing.T) {
+	for _, code := range []string{"23505", "40001", "40P01"} {
+		require.True(t, sqldb.IsConflictErr(
+			fmt.Errorf("inserting event: %w", sqlStateErr(code)),
+		), code)
+	}
+	require.False(t, sqldb.IsConflictErr(sqlStateErr("23502")))
+	require.False(t, sqldb.IsConflictErr(errors.New("other")))
+}
+
+func TestSharedTxOtherDB(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l, _ := newLog(t)
+	_, other := newLog(t)
+
+	// Transactions of other databases are ignored
+	tx, err := other.BeginTx(ctx, nil)
+	r.NoError(err)
+	defer tx.Rollback()
+	_, _, _, err = l.AppendJSON(
+		sqldb.WithTx(ctx, other, tx), nil, []byte(`{"i":0}`),
+	)
+	r.NoError(err)
+	r.NoError(tx.Rollback())
+	r.Equal("1", version(t, l))
+}
+
+func TestAppendErrInvalid(t *testing.T) {
+	l, _ := newLog(t)
+	_, _, _, err := l.AppendJSON(context.Background(), nil, []byte(`42`))
+	require.ErrorIs(t, err, eventlog.ErrInvalidPayload)
+	require.Equal(t, "0", version(t, l))
+}
+
+type Event struct {
+	Offset  string
+	Time    time.Time
+	Payload string
+	Next    string
+}
+
+func now() time.Time {
+	return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newLog(t *testing.T) (*sqldb.EventLog, *sql.DB) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	l, err := sqldb.New(context.Background(), db, sqldb.Options{
+		Clock: now,
+	})
+	require.NoError(t, err)
+	return l, db
+}
+
+func version(t *testing.T, l *sqldb.EventLog) string {
+	v, err := l.Version(context.Background())
+	require.NoError(t, err)
+	return v
+}
+
+func scan(
+	t *testing.T,
+	l *sqldb.EventLog,
+	version string,
+	limit uint,
+) (e []Event) {
+	require.NoError(t, l.Scan(
+		context.Background(), version, limit,
+		func(offset string, tm time.Time, payload []byte, next string) error {
+			e = append(e, Event{offset, tm, string(payload), next})
+			return nil
+		},
+	))
+	return
+}
@@ -0,0 +1,220 @@
+package eventlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Log is an event log satisfying the generated EventLogger interface,
+// which the inmem, file and sqldb event logs are
+type Log interface {
+	IsOffsetOutOfBoundErr(error) bool
+	IsMismatchingVersionsErr(error) bool
+	Begin(context.Context) (string, error)
+	Scan(
+		ctx context.Context,
+		version string,
+		limit uint,
+		onEvent func(
+			offset string,
+			tm time.Time,
+			payload []byte,
+			next string,
+		) error,
+	) error
+	AppendJSON(
+		ctx context.Context,
+		streams []string,
+		payload []byte,
+	) (offset, newVersion string, tm time.Time, err error)
+	AppendCheckJSON(
+		ctx context.Context,
+		assumedVersion string,
+		streams []string,
+		payload []byte,
+	) (offset, newVersion string, tm time.Time, err error)
+	AppendCheckStreamsJSON(
+		ctx context.Context,
+		assumedVersion string,
+		streams []string,
+		payload []byte,
+	) (offset, newVersion string, tm time.Time, err error)
+}
+
+// TenantLog is the view of a tenant onto an event log shared by
+// multiple tenants satisfying the generated EventLogger interface.
+//
+// Events are appended enveloped with the tenant and associated with
+// the streams of the tenant, which are prefixed by the tenant, and
+// the stream of the tenant itself. Scans only read the events
+// of the tenant stripped of the envelope. Versions are the versions
+// of the shared event log.
+//
+// Since every event of a tenant is associated with the stream
+// of the tenant, appends of a tenant checking versions only conflict
+// with appends of the same tenant, but appends checking streams
+// conflict with any append of the same tenant as well.
+type TenantLog struct {
+	log    Log
+	tenant json.RawMessage
+	stream string
+}
+
+// NewTenantLog creates the view of the given tenant onto log.
+// tenant is the JSON encoded ID of the tenant.
+func NewTenantLog(log Log, tenant json.RawMessage) (*TenantLog, error) {
+	if log == nil {
+		panic("log is nil in NewTenantLog")
+	}
+	var b bytes.Buffer
+	if err := json.Compact(&b, tenant); err != nil {
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+	return &TenantLog{
+		log:    log,
+		tenant: b.Bytes(),
+		stream: "tenant:" + b.String(),
+	}, nil
+}
+
+// tenantEnvelope is an event enveloped with its tenant
+type tenantEnvelope struct {
+	Tenant json.RawMessage `json:"tenant"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// errLimitReached stops scanning the shared log
+var errLimitReached = errors.New("limit reached")
+
+// IsOffsetOutOfBoundErr returns true if the given error
+// is an offset-out-of-bound error
+func (l *TenantLog) IsOffsetOutOfBoundErr(err error) bool {
+	return l.log.IsOffsetOutOfBoundErr(err)
+}
+
+// IsMismatchingVersionsErr returns true if the given error
+// is a mismatching-versions error
+func (l *TenantLog) IsMismatchingVersionsErr(err error) bool {
+	return l.log.IsMismatchingVersionsErr(err)
+}
+
+// Begin returns the first offset version of the shared event log.
+func (l *TenantLog) Begin(ctx context.Context) (string, error) {
+	return l.log.Begin(ctx)
+}
+
+// Scan reads a limited number of events of the tenant at the given
+// offset version calling the onEvent callback for every received event.
+// Events of other tenants are skipped and don't count towards limit.
+func (l *TenantLog) Scan(
+	ctx context.Context,
+	version string,
+	limit uint,
+	onEvent func(
+		offset string,
+		tm time.Time,
+		payload []byte,
+		next string,
+	) error,
+) error {
+	var n uint
+	err := l.log.Scan(ctx, version, 0, func(
+		offset string,
+		tm time.Time,
+		payload []byte,
+		next string,
+	) error {
+		var e tenantEnvelope
+		if json.Unmarshal(payload, &e) != nil ||
+			!bytes.Equal(e.Tenant, l.tenant) {
+			// Not an event of the tenant
+			return nil
+		}
+		if err := onEvent(offset, tm, e.Event, next); err != nil {
+			return err
+		}
+		if n++; limit > 0 && n >= limit {
+			return errLimitReached
+		}
+		return nil
+	})
+	if err == errLimitReached {
+		return nil
+	}
+	return err
+}
+
+// AppendJSON appends one or multiple new events of the tenant
+// in JSON format onto the log associating them with the given streams
+// of the tenant.
+func (l *TenantLog) AppendJSON(
+	ctx context.Context,
+	streams []string,
+	payload []byte,
+) (offset, newVersion string, tm time.Time, err error) {
+	if payload, err = l.envelope(payload); err != nil {
+		return
+	}
+	return l.log.AppendJSON(ctx, l.streams(streams), payload)
+}
+
+// AppendCheckJSON appends one or multiple new events of the tenant
+// in JSON format onto the log associating them with the given streams
+// of the tenant if the tenant didn't receive any events after
+// the assumed version, otherwise ErrMismatchingVersions is returned.
+func (l *TenantLog) AppendCheckJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (offset, newVersion string, tm time.Time, err error) {
+	if payload, err = l.envelope(payload); err != nil {
+		return
+	}
+	return l.log.AppendCheckStreamsJSON(
+		ctx, assumedVersion, l.streams(streams), payload,
+	)
+}
+
+// AppendCheckStreamsJSON is equivalent to AppendCheckJSON
+// since all events of the tenant are associated with
+// the stream of the tenant.
+func (l *TenantLog) AppendCheckStreamsJSON(
+	ctx context.Context,
+	assumedVersion string,
+	streams []string,
+	payload []byte,
+) (offset, newVersion string, tm time.Time, err error) {
+	return l.AppendCheckJSON(ctx, assumedVersion, streams, payload)
+}
+
+// streams returns the given streams prefixed by the tenant
+// and the stream of the tenant
+func (l *TenantLog) streams(streams []string) []string {
+	s := make([]string, len(streams)+1)
+	for i, x := range streams {
+		s[i] = l.stream + "/" + x
+	}
+	s[len(streams)] = l.stream
+	return s
+}
+
+// envelope envelopes the events of the given payload with the tenant
+func (l *TenantLog) envelope(payload []byte) ([]byte, error) {
+	p, err := SplitJSON(payload)
+	if err != nil {
+		return nil, err
+	}
+	e := make([]tenantEnvelope, len(p))
+	for i, p := range p {
+		e[i] = tenantEnvelope{Tenant: l.tenant, Event: p}
+	}
+	if len(e) < 2 {
+		return json.Marshal(e[0])
+	}
+	return json.Marshal(e)
+}
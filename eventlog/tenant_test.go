@@ -0,0 +1,90 @@
+package eventlog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog"
+	"github.com/romshark/goesgen/eventlog/inmem"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantLog(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := inmem.New(inmem.Options{})
+	a, err := eventlog.NewTenantLog(l, []byte(`"a"`))
+	r.NoError(err)
+	b, err := eventlog.NewTenantLog(l, []byte(` "b" `))
+	r.NoError(err)
+
+	_, _, _, err = a.AppendJSON(ctx, []string{"s"}, []byte(`{"i":1}`))
+	r.NoError(err)
+	_, _, _, err = b.AppendJSON(ctx, []string{"s"}, []byte(`{"i":2}`))
+	r.NoError(err)
+	_, _, _, err = a.AppendJSON(ctx, nil, []byte(`[{"i":3},{"i":4}]`))
+	r.NoError(err)
+	r.Equal("4", l.Version())
+
+	r.Equal([]string{
+		`0 {"i":1} 1`, `2 {"i":3} 3`, `3 {"i":4} 4`,
+	}, scanTenant(t, a, "0", 0))
+	r.Equal([]string{`1 {"i":2} 2`}, scanTenant(t, b, "0", 0))
+
+	// Events of other tenants don't count towards the limit
+	r.Equal([]string{`2 {"i":3} 3`}, scanTenant(t, a, "1", 1))
+}
+
+func TestTenantLogAppendCheck(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := inmem.New(inmem.Options{})
+	a, err := eventlog.NewTenantLog(l, []byte(`"a"`))
+	r.NoError(err)
+	b, err := eventlog.NewTenantLog(l, []byte(`"b"`))
+	r.NoError(err)
+
+	_, _, _, err = a.AppendJSON(ctx, []string{"s"}, []byte(`{"i":1}`))
+	r.NoError(err)
+
+	// Appends of other tenants don't conflict
+	_, _, _, err = b.AppendCheckJSON(ctx, "0", []string{"s"}, []byte(`{}`))
+	r.NoError(err)
+	_, _, _, err = b.AppendCheckStreamsJSON(
+		ctx, "2", []string{"s"}, []byte(`{}`),
+	)
+	r.NoError(err)
+
+	// Appends of the same tenant conflict
+	_, _, _, err = a.AppendCheckJSON(ctx, "0", []string{"x"}, []byte(`{}`))
+	r.True(a.IsMismatchingVersionsErr(err))
+	_, _, _, err = a.AppendCheckStreamsJSON(
+		ctx, "0", []string{"x"}, []byte(`{}`),
+	)
+	r.True(a.IsMismatchingVersionsErr(err))
+	_, _, _, err = a.AppendCheckJSON(ctx, "3", []string{"x"}, []byte(`{}`))
+	r.NoError(err)
+}
+
+func TestNewTenantLogErrInvalid(t *testing.T) {
+	_, err := eventlog.NewTenantLog(inmem.New(inmem.Options{}), []byte(`{`))
+	require.Error(t, err)
+}
+
+func scanTenant(
+	t *testing.T,
+	l *eventlog.TenantLog,
+	version string,
+	limit uint,
+) (e []string) {
+	require.NoError(t, l.Scan(
+		context.Background(), version, limit,
+		func(offset string, _ time.Time, payload []byte, next string) error {
+			e = append(e, offset+" "+string(payload)+" "+next)
+			return nil
+		},
+	))
+	return
+}
@@ -23,9 +23,12 @@ func main() {
 	c := client.New(e)
 
 	// Initialize in-memory tickets service
+	ticketsStore := stickets.NewStore()
 	serviceTickets := generated.NewServiceTickets(
-		stickets.New(),
-		stickets.NewStore(),
+		stickets.New(
+			ticketsStore.ProjectionTicket(), ticketsStore.ProjectionUser(),
+		),
+		ticketsStore,
 		&service.EventlogAdapter{Client: c},
 		lErr,
 		generated.ServiceOptions{},
@@ -1,6 +1,7 @@
 // Code generated by github.com/romshark/goesgen - DO NOT EDIT.
 
-/* SCHEMA (YAML):events:
+/* SCHEMA (YAML):tenant: id.Tenant
+events:
   TicketCreated:
     id: id.Ticket
     title: TicketTitle
@@ -9,6 +10,8 @@
   TicketClosed:
     ticket: id.Ticket
     by: id.User
+  TicketAutoClosed:
+    ticket: id.Ticket
   TicketCommented:
     id: id.Comment
     ticket: id.Ticket
@@ -34,13 +37,50 @@
     id: id.User
     name: UserName
 
+streams:
+  Ticket:
+    key: id.Ticket
+    events:
+      TicketCreated: id
+      TicketClosed: ticket
+      TicketAutoClosed: ticket
+      TicketCommented: ticket
+      UserAssignedToTicket: ticket
+      UserUnassignedFromTicket: ticket
+      TicketDescriptionChanged: ticket
+      TicketTitleChanged: ticket
+  User:
+    key: id.User
+    events:
+      UserCreated: id
+
 projections:
   User:
+    key: id
+    indexes:
+      - name
+    properties:
+      id: id.User
+      name: UserName
     states:
       - New
     createOn: UserCreated
+    apply:
+      UserCreated:
+        set:
+          name: name
 
   Ticket:
+    key: id
+    indexes:
+      - author
+    properties:
+      id: id.Ticket
+      title: TicketTitle
+      description: TicketDescription
+      author: id.User
+      assignees: TicketAssignees
+      comments: TicketComments
     states:
       - New
       - InProgress
@@ -50,12 +90,15 @@ projections:
     transitions:
       UserAssignedToTicket:
         - New -> New
+        - New -> InProgress
         - InProgress -> InProgress
         - Stalled -> InProgress
       TicketClosed:
         - New -> Closed
         - InProgress -> Closed
         - Stalled -> Closed
+      TicketAutoClosed:
+        - Stalled -> Closed
       TicketCommented:
         - InProgress -> InProgress
         - New -> New
@@ -71,9 +114,33 @@ projections:
         - New -> New
         - InProgress -> InProgress
         - Stalled -> Stalled
+    apply:
+      TicketCreated:
+        set:
+          title: title
+          description: description
+          author: author
+      UserAssignedToTicket:
+        append:
+          assignees: user
+        state: InProgress
+      TicketClosed:
+        state: Closed
+      TicketAutoClosed:
+        state: Closed
+      TicketCommented:
+        append:
+          comments: id
+      TicketDescriptionChanged:
+        set:
+          description: newDescription
+      TicketTitleChanged:
+        set:
+          title: newTitle
 
 services:
   Users:
+    store: sql
     projections:
       - User
     methods:
@@ -87,6 +154,7 @@ services:
           - UserCreated
 
   Tickets:
+    transaction: store.Transaction
     projections:
       - Ticket
       - User
@@ -98,30 +166,57 @@ services:
       CreateTicket:
         in: service.tickets.io.CreateTicketIn
         out: service.tickets.io.CreateTicketOut
+        streams:
+          - Ticket
         emits:
           - TicketCreated
       AssignUserToTicket:
         in: service.tickets.io.AssignUserToTicketIn
+        streams:
+          - Ticket
         emits:
           - UserAssignedToTicket
       CloseTicket:
         in: service.tickets.io.CloseTicketIn
+        streams:
+          - Ticket
         emits:
           - TicketClosed
       CreateComment:
         in: service.tickets.io.CreateCommentIn
         out: service.tickets.io.CreateCommentOut
+        streams:
+          - Ticket
         emits:
           - TicketCommented
       UnassignUserFromTicket:
         in: service.tickets.io.UnassignUserFromTicketIn
+        streams:
+          - Ticket
         emits:
           - UserUnassignedFromTicket
       UpdateTicket:
         in: service.tickets.io.UpdateTicketIn
+        streams:
+          - Ticket
         emits:
           - TicketDescriptionChanged
           - TicketTitleChanged
+    timers:
+      AutoCloseStalledTicket:
+        projection: Ticket
+        state: Stalled
+        after: 168h
+        emits: TicketAutoClosed
+processes:
+  Welcome:
+    key: id.User
+    state: process.welcome.state.State
+    on:
+      UserCreated: id
+    calls:
+      - Tickets.CreateTicket
+
 */
 
 package generated
@@ -129,18 +224,28 @@ package generated
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/romshark/goesgen/eventlog"
+	"github.com/romshark/goesgen/eventlog/sqldb"
+	"github.com/romshark/goesgen/inmemstore"
+	"github.com/romshark/goesgen/runtime"
+
 	srctickets "tickets"
 	srcticketsid "tickets/id"
+	srcticketsprocesswelcomestate "tickets/process/welcome/state"
 	srcticketsserviceticketsio "tickets/service/tickets/io"
 	srcticketsserviceusersio "tickets/service/users/io"
+	srcticketsstore "tickets/store"
 )
 
 type Logger interface {
@@ -158,15 +263,75 @@ var defaultLogErr = &fallbackLog{os.Stderr}
 /* EVENT TYPES */
 
 // Event represents either of:
-//  EventTicketClosed
-//  EventTicketCommented
-//  EventTicketCreated
-//  EventTicketDescriptionChanged
-//  EventTicketTitleChanged
-//  EventUserAssignedToTicket
-//  EventUserCreated
-//  EventUserUnassignedFromTicket
-type Event = interface{}
+//
+//	EventTicketAutoClosed
+//	EventTicketClosed
+//	EventTicketCommented
+//	EventTicketCreated
+//	EventTicketDescriptionChanged
+//	EventTicketTitleChanged
+//	EventUserAssignedToTicket
+//	EventUserCreated
+//	EventUserUnassignedFromTicket
+//
+// Event is sealed, only the event types of the schema implement it.
+type Event interface {
+	// EventTypeName returns the name of the event type
+	EventTypeName() string
+
+	isEvent()
+}
+
+// EventVisitor handles every type of event.
+// Adding an event to the schema breaks the compilation of
+// all EventVisitor implementations until they handle it.
+type EventVisitor interface {
+	VisitEventTicketAutoClosed(EventTicketAutoClosed) error
+	VisitEventTicketClosed(EventTicketClosed) error
+	VisitEventTicketCommented(EventTicketCommented) error
+	VisitEventTicketCreated(EventTicketCreated) error
+	VisitEventTicketDescriptionChanged(EventTicketDescriptionChanged) error
+	VisitEventTicketTitleChanged(EventTicketTitleChanged) error
+	VisitEventUserAssignedToTicket(EventUserAssignedToTicket) error
+	VisitEventUserCreated(EventUserCreated) error
+	VisitEventUserUnassignedFromTicket(EventUserUnassignedFromTicket) error
+}
+
+// VisitEvent calls the method of v handling the type of e.
+// Returns an UnknownEventTypeErr if e is nil.
+func VisitEvent(e Event, v EventVisitor) error {
+	switch e := e.(type) {
+	case EventTicketAutoClosed:
+		return v.VisitEventTicketAutoClosed(e)
+	case EventTicketClosed:
+		return v.VisitEventTicketClosed(e)
+	case EventTicketCommented:
+		return v.VisitEventTicketCommented(e)
+	case EventTicketCreated:
+		return v.VisitEventTicketCreated(e)
+	case EventTicketDescriptionChanged:
+		return v.VisitEventTicketDescriptionChanged(e)
+	case EventTicketTitleChanged:
+		return v.VisitEventTicketTitleChanged(e)
+	case EventUserAssignedToTicket:
+		return v.VisitEventUserAssignedToTicket(e)
+	case EventUserCreated:
+		return v.VisitEventUserCreated(e)
+	case EventUserUnassignedFromTicket:
+		return v.VisitEventUserUnassignedFromTicket(e)
+	}
+	return CheckEventType(e)
+}
+
+// EventTicketAutoClosed defines event TicketAutoClosed
+type EventTicketAutoClosed struct {
+	Ticket srcticketsid.Ticket "json:\"ticket\""
+}
+
+// EventTypeName implements Event.EventTypeName
+func (EventTicketAutoClosed) EventTypeName() string { return "TicketAutoClosed" }
+
+func (EventTicketAutoClosed) isEvent() {}
 
 // EventTicketClosed defines event TicketClosed
 type EventTicketClosed struct {
@@ -175,6 +340,11 @@ type EventTicketClosed struct {
 	By srcticketsid.User "json:\"by\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventTicketClosed) EventTypeName() string { return "TicketClosed" }
+
+func (EventTicketClosed) isEvent() {}
+
 // EventTicketCommented defines event TicketCommented
 type EventTicketCommented struct {
 	Id srcticketsid.Comment "json:\"id\""
@@ -186,6 +356,11 @@ type EventTicketCommented struct {
 	By srcticketsid.User "json:\"by\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventTicketCommented) EventTypeName() string { return "TicketCommented" }
+
+func (EventTicketCommented) isEvent() {}
+
 // EventTicketCreated defines event TicketCreated
 type EventTicketCreated struct {
 	Id srcticketsid.Ticket "json:\"id\""
@@ -197,6 +372,11 @@ type EventTicketCreated struct {
 	Author srcticketsid.User "json:\"author\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventTicketCreated) EventTypeName() string { return "TicketCreated" }
+
+func (EventTicketCreated) isEvent() {}
+
 // EventTicketDescriptionChanged defines event TicketDescriptionChanged
 type EventTicketDescriptionChanged struct {
 	Ticket srcticketsid.Ticket "json:\"ticket\""
@@ -206,6 +386,11 @@ type EventTicketDescriptionChanged struct {
 	By srcticketsid.User "json:\"by\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventTicketDescriptionChanged) EventTypeName() string { return "TicketDescriptionChanged" }
+
+func (EventTicketDescriptionChanged) isEvent() {}
+
 // EventTicketTitleChanged defines event TicketTitleChanged
 type EventTicketTitleChanged struct {
 	Ticket srcticketsid.Ticket "json:\"ticket\""
@@ -215,6 +400,11 @@ type EventTicketTitleChanged struct {
 	By srcticketsid.User "json:\"by\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventTicketTitleChanged) EventTypeName() string { return "TicketTitleChanged" }
+
+func (EventTicketTitleChanged) isEvent() {}
+
 // EventUserAssignedToTicket defines event UserAssignedToTicket
 type EventUserAssignedToTicket struct {
 	User srcticketsid.User "json:\"user\""
@@ -224,6 +414,11 @@ type EventUserAssignedToTicket struct {
 	By srcticketsid.User "json:\"by\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventUserAssignedToTicket) EventTypeName() string { return "UserAssignedToTicket" }
+
+func (EventUserAssignedToTicket) isEvent() {}
+
 // EventUserCreated defines event UserCreated
 type EventUserCreated struct {
 	Id srcticketsid.User "json:\"id\""
@@ -231,6 +426,11 @@ type EventUserCreated struct {
 	Name srctickets.UserName "json:\"name\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventUserCreated) EventTypeName() string { return "UserCreated" }
+
+func (EventUserCreated) isEvent() {}
+
 // EventUserUnassignedFromTicket defines event UserUnassignedFromTicket
 type EventUserUnassignedFromTicket struct {
 	User srcticketsid.User "json:\"user\""
@@ -240,28 +440,18 @@ type EventUserUnassignedFromTicket struct {
 	By srcticketsid.User "json:\"by\""
 }
 
+// EventTypeName implements Event.EventTypeName
+func (EventUserUnassignedFromTicket) EventTypeName() string { return "UserUnassignedFromTicket" }
+
+func (EventUserUnassignedFromTicket) isEvent() {}
+
 // GetEventTypeName returns the given event's name.
 // Returns "" if the given object is not a valid event.
 func GetEventTypeName(e Event) string {
-	switch e.(type) {
-	case EventTicketClosed:
-		return "TicketClosed"
-	case EventTicketCommented:
-		return "TicketCommented"
-	case EventTicketCreated:
-		return "TicketCreated"
-	case EventTicketDescriptionChanged:
-		return "TicketDescriptionChanged"
-	case EventTicketTitleChanged:
-		return "TicketTitleChanged"
-	case EventUserAssignedToTicket:
-		return "UserAssignedToTicket"
-	case EventUserCreated:
-		return "UserCreated"
-	case EventUserUnassignedFromTicket:
-		return "UserUnassignedFromTicket"
+	if e == nil {
+		return ""
 	}
-	return ""
+	return e.EventTypeName()
 }
 
 // CheckEventType returns an error if the given object isn't a valid event,
@@ -324,6 +514,15 @@ func DecodeEventJSON(b []byte) (Event, error) {
 	}
 
 	switch v.TypeName {
+	case "TicketAutoClosed":
+		var e EventTicketAutoClosed
+		if err := json.Unmarshal(v.Payload, &e); err != nil {
+			return nil, DecodingEventErr(fmt.Sprintf(
+				"decoding TicketAutoClosed payload: %s",
+				err,
+			))
+		}
+		return e, nil
 	case "TicketClosed":
 		var e EventTicketClosed
 		if err := json.Unmarshal(v.Payload, &e); err != nil {
@@ -402,6 +601,44 @@ func DecodeEventJSON(b []byte) (Event, error) {
 	))
 }
 
+// DecodeEventTypeName decodes only the type name of an event
+// from UTF-8 text without decoding its payload.
+func DecodeEventTypeName(b []byte) (string, error) {
+	var v struct {
+		TypeName string "json:\"type\""
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", DecodingEventErr(fmt.Sprintf("decoding event type: %s", err))
+	}
+	return v.TypeName, nil
+}
+
+// IsEventTypeKnown returns true if the given event type name
+// is defined by the schema.
+func IsEventTypeKnown(typeName string) bool {
+	switch typeName {
+	case "TicketAutoClosed":
+		return true
+	case "TicketClosed":
+		return true
+	case "TicketCommented":
+		return true
+	case "TicketCreated":
+		return true
+	case "TicketDescriptionChanged":
+		return true
+	case "TicketTitleChanged":
+		return true
+	case "UserAssignedToTicket":
+		return true
+	case "UserCreated":
+		return true
+	case "UserUnassignedFromTicket":
+		return true
+	}
+	return false
+}
+
 type DecodingEventErr string
 
 func (e DecodingEventErr) Error() string { return string(e) }
@@ -410,6 +647,69 @@ type UnknownEventTypeErr string
 
 func (e UnknownEventTypeErr) Error() string { return string(e) }
 
+/* STREAMS */
+
+// StreamID identifies a stream of events within the event log
+type StreamID = string
+
+// StreamIDTicket returns the ID of stream Ticket identified by the given key.
+func StreamIDTicket(key srcticketsid.Ticket) StreamID {
+	return "Ticket/" + fmt.Sprint(key)
+}
+
+// StreamIDUser returns the ID of stream User identified by the given key.
+func StreamIDUser(key srcticketsid.User) StreamID {
+	return "User/" + fmt.Sprint(key)
+}
+
+// GetEventStreamID returns the ID of the stream the given event belongs to.
+// Returns "" if the given event doesn't belong to any stream.
+func GetEventStreamID(e Event) StreamID {
+	switch v := e.(type) {
+	case EventTicketAutoClosed:
+		return StreamIDTicket(v.Ticket)
+	case EventTicketClosed:
+		return StreamIDTicket(v.Ticket)
+	case EventTicketCommented:
+		return StreamIDTicket(v.Ticket)
+	case EventTicketCreated:
+		return StreamIDTicket(v.Id)
+	case EventTicketDescriptionChanged:
+		return StreamIDTicket(v.Ticket)
+	case EventTicketTitleChanged:
+		return StreamIDTicket(v.Ticket)
+	case EventUserAssignedToTicket:
+		return StreamIDTicket(v.Ticket)
+	case EventUserCreated:
+		return StreamIDUser(v.Id)
+	case EventUserUnassignedFromTicket:
+		return StreamIDTicket(v.Ticket)
+	}
+	return ""
+}
+
+// GetEventStreamIDs returns the distinct IDs of the streams
+// the given events belong to.
+func GetEventStreamIDs(e ...Event) []StreamID {
+	var s []StreamID
+	for _, e := range e {
+		id := GetEventStreamID(e)
+		if id == "" {
+			continue
+		}
+		for _, x := range s {
+			if x == id {
+				id = ""
+				break
+			}
+		}
+		if id != "" {
+			s = append(s, id)
+		}
+	}
+	return s
+}
+
 /* PROJECTIONS */
 
 type ProjectionTicketState string
@@ -423,6 +723,18 @@ const (
 
 type ProjectionTicket struct {
 	state ProjectionTicketState
+
+	Id srcticketsid.Ticket
+
+	Title srctickets.TicketTitle
+
+	Description srctickets.TicketDescription
+
+	Author srcticketsid.User
+
+	Assignees srctickets.TicketAssignees
+
+	Comments srctickets.TicketComments
 }
 
 func NewProjectionTicket() ProjectionTicket {
@@ -435,150 +747,1221 @@ func (p ProjectionTicket) State() ProjectionTicketState {
 	return p.state
 }
 
-type ProjectionUserState string
-
-const (
-	ProjectionUserStateNew ProjectionUserState = "New"
-)
-
-type ProjectionUser struct {
-	state ProjectionUserState
+// WithState returns a copy of p in the given state
+func (p ProjectionTicket) WithState(s ProjectionTicketState) ProjectionTicket {
+	p.state = s
+	return p
 }
 
-func NewProjectionUser() ProjectionUser {
-	return ProjectionUser{
-		state: ProjectionUserStateNew,
-	}
+// ProjectionTicketReader provides typed read access to
+// the instances of projection Ticket maintained by in-memory stores
+// within the transactions of the store.
+type ProjectionTicketReader interface {
+	// GetByID returns the instance identified by the given key.
+	// Returns false if there's no such instance.
+	GetByID(
+		ctx context.Context,
+		trx *inmemstore.Transaction,
+		key srcticketsid.Ticket,
+	) (ProjectionTicket, bool, error)
+
+	// ListByAuthor returns all instances with
+	// property author equal to the given value
+	// in the order of creation.
+	ListByAuthor(
+		ctx context.Context,
+		trx *inmemstore.Transaction,
+		author srcticketsid.User,
+	) ([]ProjectionTicket, error)
 }
 
-func (p ProjectionUser) State() ProjectionUserState {
-	return p.state
+// ProjectionTicketHandler computes the instances of projection Ticket
+// from the events applied to them.
+type ProjectionTicketHandler interface {
+
+	// OnTicketAutoClosed returns instance p with event TicketAutoClosed applied.
+	// The state of the returned instance must be
+	// a legal transition on TicketAutoClosed.
+	OnTicketAutoClosed(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventTicketAutoClosed,
+	) (ProjectionTicket, error)
+
+	// OnTicketClosed returns instance p with event TicketClosed applied.
+	// The state of the returned instance must be
+	// a legal transition on TicketClosed.
+	OnTicketClosed(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventTicketClosed,
+	) (ProjectionTicket, error)
+
+	// OnTicketCommented returns instance p with event TicketCommented applied.
+	// The state of the returned instance must be
+	// a legal transition on TicketCommented.
+	OnTicketCommented(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventTicketCommented,
+	) (ProjectionTicket, error)
+
+	// OnTicketCreated returns instance p with event TicketCreated applied.
+	// p is a new instance with only property id set.
+	// The state of the returned instance must be
+	// a legal transition on TicketCreated.
+	OnTicketCreated(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventTicketCreated,
+	) (ProjectionTicket, error)
+
+	// OnTicketDescriptionChanged returns instance p with event TicketDescriptionChanged applied.
+	// The state of the returned instance must be
+	// a legal transition on TicketDescriptionChanged.
+	OnTicketDescriptionChanged(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventTicketDescriptionChanged,
+	) (ProjectionTicket, error)
+
+	// OnTicketTitleChanged returns instance p with event TicketTitleChanged applied.
+	// The state of the returned instance must be
+	// a legal transition on TicketTitleChanged.
+	OnTicketTitleChanged(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventTicketTitleChanged,
+	) (ProjectionTicket, error)
+
+	// OnUserAssignedToTicket returns instance p with event UserAssignedToTicket applied.
+	// The state of the returned instance must be
+	// a legal transition on UserAssignedToTicket.
+	OnUserAssignedToTicket(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventUserAssignedToTicket,
+	) (ProjectionTicket, error)
+
+	// OnUserUnassignedFromTicket returns instance p with event UserUnassignedFromTicket applied.
+	// The state of the returned instance must be
+	// a legal transition on UserUnassignedFromTicket.
+	OnUserUnassignedFromTicket(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionTicket,
+		e EventUserUnassignedFromTicket,
+	) (ProjectionTicket, error)
 }
 
-/* SERVICES */
+// ProjectionTicketMappings applies events to projection Ticket
+// as declared by the mappings of the schema.
+// Embed it in a ProjectionTicketHandler to override individual mappings
+// and to handle events without mappings.
+type ProjectionTicketMappings struct{}
 
-type ServiceOptions struct {
-	// SyncAfterPush will synchronize a service against the event log
-	// after a successful push of events.
-	//
-	// SyncAfterPush is enabled by default.
-	SyncAfterPush Option
+// OnTicketAutoClosed implements ProjectionTicketHandler.OnTicketAutoClosed
+func (ProjectionTicketMappings) OnTicketAutoClosed(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionTicket,
+	e EventTicketAutoClosed,
+) (ProjectionTicket, error) {
+	p.state = ProjectionTicketStateClosed
+	return p, nil
 }
 
-type Option int
+// OnTicketClosed implements ProjectionTicketHandler.OnTicketClosed
+func (ProjectionTicketMappings) OnTicketClosed(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionTicket,
+	e EventTicketClosed,
+) (ProjectionTicket, error) {
+	p.state = ProjectionTicketStateClosed
+	return p, nil
+}
 
-const (
-	Unspecified Option = 0
-	Disabled    Option = -1
-	Enabled     Option = 1
-)
+// OnTicketCommented implements ProjectionTicketHandler.OnTicketCommented
+func (ProjectionTicketMappings) OnTicketCommented(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionTicket,
+	e EventTicketCommented,
+) (ProjectionTicket, error) {
+	// Appending to full slices never mutates previous instance versions
+	p.Comments = append(p.Comments[:len(p.Comments):len(p.Comments)], e.Id)
+	return p, nil
+}
 
-// SetDefaults sets default values to unspecified options
-func (o *ServiceOptions) SetDefaults() {
-	if o.SyncAfterPush == Unspecified {
-		o.SyncAfterPush = Enabled
-	}
+// OnTicketCreated implements ProjectionTicketHandler.OnTicketCreated
+func (ProjectionTicketMappings) OnTicketCreated(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionTicket,
+	e EventTicketCreated,
+) (ProjectionTicket, error) {
+	p.Title = e.Title
+	p.Description = e.Description
+	p.Author = e.Author
+	return p, nil
 }
 
-type EventlogVersion = string
+// OnTicketDescriptionChanged implements ProjectionTicketHandler.OnTicketDescriptionChanged
+func (ProjectionTicketMappings) OnTicketDescriptionChanged(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionTicket,
+	e EventTicketDescriptionChanged,
+) (ProjectionTicket, error) {
+	p.Description = e.NewDescription
+	return p, nil
+}
 
-// EventLogger represents an abstract event logger
-type EventLogger interface {
-	// IsOffsetOutOfBoundErr returns true if the given error
-	// is an offset-out-of-bound error
-	IsOffsetOutOfBoundErr(error) bool
+// OnTicketTitleChanged implements ProjectionTicketHandler.OnTicketTitleChanged
+func (ProjectionTicketMappings) OnTicketTitleChanged(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionTicket,
+	e EventTicketTitleChanged,
+) (ProjectionTicket, error) {
+	p.Title = e.NewTitle
+	return p, nil
+}
 
-	// Begin returns the first offset version of the eventlog.
-	//
-	// WARNING: Begin is expected to be thread-safe.
-	Begin(context.Context) (string, error)
+// OnUserAssignedToTicket implements ProjectionTicketHandler.OnUserAssignedToTicket
+func (ProjectionTicketMappings) OnUserAssignedToTicket(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionTicket,
+	e EventUserAssignedToTicket,
+) (ProjectionTicket, error) {
+	// Appending to full slices never mutates previous instance versions
+	p.Assignees = append(p.Assignees[:len(p.Assignees):len(p.Assignees)], e.User)
+	p.state = ProjectionTicketStateInProgress
+	return p, nil
+}
 
-	// Scan reads a limited number of events at the given offset version
-	// calling the onEvent callback for every received event.
-	//
-	// WARNING: Scan is expected to be thread-safe.
-	Scan(
-		ctx context.Context,
-		version EventlogVersion,
-		limit uint,
-		onEvent func(
-			offset EventlogVersion,
-			tm time.Time,
-			payload []byte,
-			next EventlogVersion,
-		) error,
-	) error
+// InmemProjectionTicket is an in-memory ProjectionTicketReader
+// maintained by its Apply methods mirroring the methods
+// of the store handler.
+//
+// The Apply methods expect an *inmemstore.Transaction
+// and record the undo of their changes in it.
+//
+// WARNING: InmemProjectionTicket isn't thread-safe and expects
+// the store handler to synchronize access to it.
+type InmemProjectionTicket struct {
+	handler     ProjectionTicketHandler
+	created     uint64
+	instances   map[srcticketsid.Ticket]inmemProjectionTicketInstance
+	indexAuthor map[srcticketsid.User]map[srcticketsid.Ticket]struct{}
+}
 
-	// AppendJSON appends one or multiple new events
-	// in JSON format onto the log.
-	//
-	// WARNING: AppendJSON is expected to be thread-safe.
-	AppendJSON(
-		ctx context.Context,
-		payload []byte,
-	) (
-		offset EventlogVersion,
-		newVersion EventlogVersion,
-		tm time.Time,
-		err error,
-	)
+type inmemProjectionTicketInstance struct {
+	// created defines the order of creation
+	created    uint64
+	projection ProjectionTicket
+}
 
-	// TryAppendJSON keeps executing transaction until either cancelled,
-	// succeeded (assumed and actual event log versions match)
-	// or failed due to an error.
-	//
-	// WARNING: TryAppendJSON is expected to be thread-safe.
-	TryAppendJSON(
-		ctx context.Context,
-		assumedVersion EventlogVersion,
-		transaction func() (events []byte, err error),
-		sync func() (EventlogVersion, error),
-	) (
-		offset EventlogVersion,
-		newVersion EventlogVersion,
-		tm time.Time,
-		err error,
-	)
+// NewInmemProjectionTicket creates a new empty in-memory projection
+func NewInmemProjectionTicket(handler ProjectionTicketHandler) *InmemProjectionTicket {
+	if handler == nil {
+		panic("handler is nil in NewInmemProjectionTicket")
+	}
+	return &InmemProjectionTicket{
+		handler:     handler,
+		instances:   map[srcticketsid.Ticket]inmemProjectionTicketInstance{},
+		indexAuthor: map[srcticketsid.User]map[srcticketsid.Ticket]struct{}{},
+	}
 }
 
-// StoreTransactionReadWriter represents an abstract
-// read-write (exclusive locking) store transaction handler
-type StoreTransactionReadWriter interface {
-	Commit()
-	Rollback()
+// GetByID implements ProjectionTicketReader.GetByID
+func (p *InmemProjectionTicket) GetByID(
+	ctx context.Context,
+	trx *inmemstore.Transaction,
+	key srcticketsid.Ticket,
+) (ProjectionTicket, bool, error) {
+	inmemstore.Reader(trx)
+	i, ok := p.instances[key]
+	return i.projection, ok, nil
 }
 
-// StoreTransactionReader represents an abstract
-// read only (shared locking) store transaction handler
-type StoreTransactionReader interface {
-	Complete()
+// ListByAuthor implements ProjectionTicketReader.ListByAuthor
+func (p *InmemProjectionTicket) ListByAuthor(
+	ctx context.Context,
+	trx *inmemstore.Transaction,
+	author srcticketsid.User,
+) ([]ProjectionTicket, error) {
+	inmemstore.Reader(trx)
+	return p.list(p.indexAuthor[author]), nil
 }
 
-// TransactionWriter represents an arbitrary abstract transaction object
-// that's supposed to be used for write-only mutations.
-// TransactionWriter must not be committed or rolled back!
-type TransactionWriter = interface{}
+// list returns the instances identified by keys in the order of creation
+func (p *InmemProjectionTicket) list(
+	keys map[srcticketsid.Ticket]struct{},
+) []ProjectionTicket {
+	l := make([]inmemProjectionTicketInstance, 0, len(keys))
+	for k := range keys {
+		l = append(l, p.instances[k])
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].created < l[j].created
+	})
+	r := make([]ProjectionTicket, len(l))
+	for i, x := range l {
+		r[i] = x.projection
+	}
+	return r
+}
 
-// TransactionReader represents an arbitrary abstract transaction object
-// that's supposed to be used for read-only queries.
-// TransactionReader must not be committed or rolled back!
-type TransactionReader = interface{}
+// put stores instance x identified by key
+// and records the undo of the change in trx
+func (p *InmemProjectionTicket) put(
+	trx *inmemstore.Transaction,
+	key srcticketsid.Ticket,
+	x ProjectionTicket,
+) {
+	i, ok := p.instances[key]
+	created := p.created
+	trx.OnRollback(func() {
+		p.created = created
+		if ok {
+			p.set(key, i)
+		} else {
+			p.unset(key)
+		}
+	})
+	n := inmemProjectionTicketInstance{created: i.created, projection: x}
+	if !ok {
+		p.created++
+		n.created = p.created
+	}
+	p.set(key, n)
+}
 
-// ServiceTickets projects the following entities:
-//  Ticket//  User
-// therefore, Tickets subscribes to the following events:
-//  TicketTitleChanged
-//  TicketClosed
-//  TicketCommented
-//  UserAssignedToTicket
-//  UserUnassignedFromTicket
-//  TicketDescriptionChanged
-type ServiceTickets struct {
-	eventlog EventLogger
-	logErr   Logger
-	methods  ServiceTicketsMethodCaller
-	store    ServiceTicketsStoreHandler
-	options  ServiceOptions
+// set stores instance i identified by key updating the indexes
+func (p *InmemProjectionTicket) set(key srcticketsid.Ticket, i inmemProjectionTicketInstance) {
+	p.unset(key)
+	p.instances[key] = i
+	if p.indexAuthor[i.projection.Author] == nil {
+		p.indexAuthor[i.projection.Author] = map[srcticketsid.Ticket]struct{}{}
+	}
+	p.indexAuthor[i.projection.Author][key] = struct{}{}
+}
+
+// unset removes the instance identified by key from the indexes
+// and the instances
+func (p *InmemProjectionTicket) unset(key srcticketsid.Ticket) {
+	i, ok := p.instances[key]
+	if !ok {
+		return
+	}
+	delete(p.indexAuthor[i.projection.Author], key)
+	if len(p.indexAuthor[i.projection.Author]) < 1 {
+		delete(p.indexAuthor, i.projection.Author)
+	}
+	delete(p.instances, key)
+}
+
+// ApplyEventTicketAutoClosed applies event TicketAutoClosed to the projection
+func (p *InmemProjectionTicket) ApplyEventTicketAutoClosed(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketAutoClosed,
+) error {
+	i, ok := p.instances[e.Ticket]
+	if !ok {
+		return fmt.Errorf(
+			"applying TicketAutoClosed: Ticket %v not found",
+			e.Ticket,
+		)
+	}
+	x := i.projection
+	n, err := p.handler.OnTicketAutoClosed(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Ticket {
+		return fmt.Errorf(
+			"applying TicketAutoClosed: Ticket key changed (%v -> %v)",
+			e.Ticket, n.Id,
+		)
+	}
+	switch [2]ProjectionTicketState{x.state, n.state} {
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateStalled,
+		ProjectionTicketStateClosed,
+	}:
+	default:
+		return fmt.Errorf(
+			"applying TicketAutoClosed: illegal transition of Ticket %v: %s -> %s",
+			e.Ticket, x.state, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Ticket, n)
+	return nil
+}
+
+// ApplyEventTicketClosed applies event TicketClosed to the projection
+func (p *InmemProjectionTicket) ApplyEventTicketClosed(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketClosed,
+) error {
+	i, ok := p.instances[e.Ticket]
+	if !ok {
+		return fmt.Errorf(
+			"applying TicketClosed: Ticket %v not found",
+			e.Ticket,
+		)
+	}
+	x := i.projection
+	n, err := p.handler.OnTicketClosed(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Ticket {
+		return fmt.Errorf(
+			"applying TicketClosed: Ticket key changed (%v -> %v)",
+			e.Ticket, n.Id,
+		)
+	}
+	switch [2]ProjectionTicketState{x.state, n.state} {
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateNew,
+		ProjectionTicketStateClosed,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateInProgress,
+		ProjectionTicketStateClosed,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateStalled,
+		ProjectionTicketStateClosed,
+	}:
+	default:
+		return fmt.Errorf(
+			"applying TicketClosed: illegal transition of Ticket %v: %s -> %s",
+			e.Ticket, x.state, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Ticket, n)
+	return nil
+}
+
+// ApplyEventTicketCommented applies event TicketCommented to the projection
+func (p *InmemProjectionTicket) ApplyEventTicketCommented(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketCommented,
+) error {
+	i, ok := p.instances[e.Ticket]
+	if !ok {
+		return fmt.Errorf(
+			"applying TicketCommented: Ticket %v not found",
+			e.Ticket,
+		)
+	}
+	x := i.projection
+	n, err := p.handler.OnTicketCommented(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Ticket {
+		return fmt.Errorf(
+			"applying TicketCommented: Ticket key changed (%v -> %v)",
+			e.Ticket, n.Id,
+		)
+	}
+	switch [2]ProjectionTicketState{x.state, n.state} {
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateInProgress,
+		ProjectionTicketStateInProgress,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateNew,
+		ProjectionTicketStateNew,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateStalled,
+		ProjectionTicketStateStalled,
+	}:
+	default:
+		return fmt.Errorf(
+			"applying TicketCommented: illegal transition of Ticket %v: %s -> %s",
+			e.Ticket, x.state, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Ticket, n)
+	return nil
+}
+
+// ApplyEventTicketCreated applies event TicketCreated to the projection
+func (p *InmemProjectionTicket) ApplyEventTicketCreated(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketCreated,
+) error {
+	if _, ok := p.instances[e.Id]; ok {
+		return fmt.Errorf(
+			"applying TicketCreated: Ticket %v already exists",
+			e.Id,
+		)
+	}
+	x := NewProjectionTicket()
+	x.Id = e.Id
+	n, err := p.handler.OnTicketCreated(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Id {
+		return fmt.Errorf(
+			"applying TicketCreated: Ticket key changed (%v -> %v)",
+			e.Id, n.Id,
+		)
+	}
+	if n.state != x.state {
+		return fmt.Errorf(
+			"applying TicketCreated: illegal initial state of Ticket %v: %s",
+			e.Id, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Id, n)
+	return nil
+}
+
+// ApplyEventTicketDescriptionChanged applies event TicketDescriptionChanged to the projection
+func (p *InmemProjectionTicket) ApplyEventTicketDescriptionChanged(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketDescriptionChanged,
+) error {
+	i, ok := p.instances[e.Ticket]
+	if !ok {
+		return fmt.Errorf(
+			"applying TicketDescriptionChanged: Ticket %v not found",
+			e.Ticket,
+		)
+	}
+	x := i.projection
+	n, err := p.handler.OnTicketDescriptionChanged(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Ticket {
+		return fmt.Errorf(
+			"applying TicketDescriptionChanged: Ticket key changed (%v -> %v)",
+			e.Ticket, n.Id,
+		)
+	}
+	switch [2]ProjectionTicketState{x.state, n.state} {
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateNew,
+		ProjectionTicketStateNew,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateInProgress,
+		ProjectionTicketStateInProgress,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateStalled,
+		ProjectionTicketStateStalled,
+	}:
+	default:
+		return fmt.Errorf(
+			"applying TicketDescriptionChanged: illegal transition of Ticket %v: %s -> %s",
+			e.Ticket, x.state, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Ticket, n)
+	return nil
+}
+
+// ApplyEventTicketTitleChanged applies event TicketTitleChanged to the projection
+func (p *InmemProjectionTicket) ApplyEventTicketTitleChanged(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketTitleChanged,
+) error {
+	i, ok := p.instances[e.Ticket]
+	if !ok {
+		return fmt.Errorf(
+			"applying TicketTitleChanged: Ticket %v not found",
+			e.Ticket,
+		)
+	}
+	x := i.projection
+	n, err := p.handler.OnTicketTitleChanged(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Ticket {
+		return fmt.Errorf(
+			"applying TicketTitleChanged: Ticket key changed (%v -> %v)",
+			e.Ticket, n.Id,
+		)
+	}
+	switch [2]ProjectionTicketState{x.state, n.state} {
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateNew,
+		ProjectionTicketStateNew,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateInProgress,
+		ProjectionTicketStateInProgress,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateStalled,
+		ProjectionTicketStateStalled,
+	}:
+	default:
+		return fmt.Errorf(
+			"applying TicketTitleChanged: illegal transition of Ticket %v: %s -> %s",
+			e.Ticket, x.state, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Ticket, n)
+	return nil
+}
+
+// ApplyEventUserAssignedToTicket applies event UserAssignedToTicket to the projection
+func (p *InmemProjectionTicket) ApplyEventUserAssignedToTicket(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventUserAssignedToTicket,
+) error {
+	i, ok := p.instances[e.Ticket]
+	if !ok {
+		return fmt.Errorf(
+			"applying UserAssignedToTicket: Ticket %v not found",
+			e.Ticket,
+		)
+	}
+	x := i.projection
+	n, err := p.handler.OnUserAssignedToTicket(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Ticket {
+		return fmt.Errorf(
+			"applying UserAssignedToTicket: Ticket key changed (%v -> %v)",
+			e.Ticket, n.Id,
+		)
+	}
+	switch [2]ProjectionTicketState{x.state, n.state} {
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateNew,
+		ProjectionTicketStateNew,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateNew,
+		ProjectionTicketStateInProgress,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateInProgress,
+		ProjectionTicketStateInProgress,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateStalled,
+		ProjectionTicketStateInProgress,
+	}:
+	default:
+		return fmt.Errorf(
+			"applying UserAssignedToTicket: illegal transition of Ticket %v: %s -> %s",
+			e.Ticket, x.state, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Ticket, n)
+	return nil
+}
+
+// ApplyEventUserUnassignedFromTicket applies event UserUnassignedFromTicket to the projection
+func (p *InmemProjectionTicket) ApplyEventUserUnassignedFromTicket(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventUserUnassignedFromTicket,
+) error {
+	i, ok := p.instances[e.Ticket]
+	if !ok {
+		return fmt.Errorf(
+			"applying UserUnassignedFromTicket: Ticket %v not found",
+			e.Ticket,
+		)
+	}
+	x := i.projection
+	n, err := p.handler.OnUserUnassignedFromTicket(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Ticket {
+		return fmt.Errorf(
+			"applying UserUnassignedFromTicket: Ticket key changed (%v -> %v)",
+			e.Ticket, n.Id,
+		)
+	}
+	switch [2]ProjectionTicketState{x.state, n.state} {
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateInProgress,
+		ProjectionTicketStateInProgress,
+	}:
+	case [2]ProjectionTicketState{
+		ProjectionTicketStateInProgress,
+		ProjectionTicketStateStalled,
+	}:
+	default:
+		return fmt.Errorf(
+			"applying UserUnassignedFromTicket: illegal transition of Ticket %v: %s -> %s",
+			e.Ticket, x.state, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Ticket, n)
+	return nil
+}
+
+type ProjectionUserState string
+
+const (
+	ProjectionUserStateNew ProjectionUserState = "New"
+)
+
+type ProjectionUser struct {
+	state ProjectionUserState
+
+	Id srcticketsid.User
+
+	Name srctickets.UserName
+}
+
+func NewProjectionUser() ProjectionUser {
+	return ProjectionUser{
+		state: ProjectionUserStateNew,
+	}
+}
+
+func (p ProjectionUser) State() ProjectionUserState {
+	return p.state
+}
+
+// WithState returns a copy of p in the given state
+func (p ProjectionUser) WithState(s ProjectionUserState) ProjectionUser {
+	p.state = s
+	return p
+}
+
+// ProjectionUserReader provides typed read access to
+// the instances of projection User maintained by in-memory stores
+// within the transactions of the store.
+type ProjectionUserReader interface {
+	// GetByID returns the instance identified by the given key.
+	// Returns false if there's no such instance.
+	GetByID(
+		ctx context.Context,
+		trx *inmemstore.Transaction,
+		key srcticketsid.User,
+	) (ProjectionUser, bool, error)
+
+	// ListByName returns all instances with
+	// property name equal to the given value
+	// in the order of creation.
+	ListByName(
+		ctx context.Context,
+		trx *inmemstore.Transaction,
+		name srctickets.UserName,
+	) ([]ProjectionUser, error)
+}
+
+// ProjectionUserHandler computes the instances of projection User
+// from the events applied to them.
+type ProjectionUserHandler interface {
+
+	// OnUserCreated returns instance p with event UserCreated applied.
+	// p is a new instance with only property id set.
+	// The state of the returned instance must be
+	// a legal transition on UserCreated.
+	OnUserCreated(
+		ctx context.Context,
+		tm time.Time,
+		p ProjectionUser,
+		e EventUserCreated,
+	) (ProjectionUser, error)
+}
+
+// ProjectionUserMappings applies events to projection User
+// as declared by the mappings of the schema.
+// Embed it in a ProjectionUserHandler to override individual mappings
+// and to handle events without mappings.
+type ProjectionUserMappings struct{}
+
+// OnUserCreated implements ProjectionUserHandler.OnUserCreated
+func (ProjectionUserMappings) OnUserCreated(
+	ctx context.Context,
+	tm time.Time,
+	p ProjectionUser,
+	e EventUserCreated,
+) (ProjectionUser, error) {
+	p.Name = e.Name
+	return p, nil
+}
+
+// InmemProjectionUser is an in-memory ProjectionUserReader
+// maintained by its Apply methods mirroring the methods
+// of the store handler.
+//
+// The Apply methods expect an *inmemstore.Transaction
+// and record the undo of their changes in it.
+//
+// WARNING: InmemProjectionUser isn't thread-safe and expects
+// the store handler to synchronize access to it.
+type InmemProjectionUser struct {
+	handler   ProjectionUserHandler
+	created   uint64
+	instances map[srcticketsid.User]inmemProjectionUserInstance
+	indexName map[srctickets.UserName]map[srcticketsid.User]struct{}
+}
+
+type inmemProjectionUserInstance struct {
+	// created defines the order of creation
+	created    uint64
+	projection ProjectionUser
+}
+
+// NewInmemProjectionUser creates a new empty in-memory projection
+func NewInmemProjectionUser(handler ProjectionUserHandler) *InmemProjectionUser {
+	if handler == nil {
+		panic("handler is nil in NewInmemProjectionUser")
+	}
+	return &InmemProjectionUser{
+		handler:   handler,
+		instances: map[srcticketsid.User]inmemProjectionUserInstance{},
+		indexName: map[srctickets.UserName]map[srcticketsid.User]struct{}{},
+	}
+}
+
+// GetByID implements ProjectionUserReader.GetByID
+func (p *InmemProjectionUser) GetByID(
+	ctx context.Context,
+	trx *inmemstore.Transaction,
+	key srcticketsid.User,
+) (ProjectionUser, bool, error) {
+	inmemstore.Reader(trx)
+	i, ok := p.instances[key]
+	return i.projection, ok, nil
+}
+
+// ListByName implements ProjectionUserReader.ListByName
+func (p *InmemProjectionUser) ListByName(
+	ctx context.Context,
+	trx *inmemstore.Transaction,
+	name srctickets.UserName,
+) ([]ProjectionUser, error) {
+	inmemstore.Reader(trx)
+	return p.list(p.indexName[name]), nil
+}
+
+// list returns the instances identified by keys in the order of creation
+func (p *InmemProjectionUser) list(
+	keys map[srcticketsid.User]struct{},
+) []ProjectionUser {
+	l := make([]inmemProjectionUserInstance, 0, len(keys))
+	for k := range keys {
+		l = append(l, p.instances[k])
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].created < l[j].created
+	})
+	r := make([]ProjectionUser, len(l))
+	for i, x := range l {
+		r[i] = x.projection
+	}
+	return r
+}
+
+// put stores instance x identified by key
+// and records the undo of the change in trx
+func (p *InmemProjectionUser) put(
+	trx *inmemstore.Transaction,
+	key srcticketsid.User,
+	x ProjectionUser,
+) {
+	i, ok := p.instances[key]
+	created := p.created
+	trx.OnRollback(func() {
+		p.created = created
+		if ok {
+			p.set(key, i)
+		} else {
+			p.unset(key)
+		}
+	})
+	n := inmemProjectionUserInstance{created: i.created, projection: x}
+	if !ok {
+		p.created++
+		n.created = p.created
+	}
+	p.set(key, n)
+}
+
+// set stores instance i identified by key updating the indexes
+func (p *InmemProjectionUser) set(key srcticketsid.User, i inmemProjectionUserInstance) {
+	p.unset(key)
+	p.instances[key] = i
+	if p.indexName[i.projection.Name] == nil {
+		p.indexName[i.projection.Name] = map[srcticketsid.User]struct{}{}
+	}
+	p.indexName[i.projection.Name][key] = struct{}{}
+}
+
+// unset removes the instance identified by key from the indexes
+// and the instances
+func (p *InmemProjectionUser) unset(key srcticketsid.User) {
+	i, ok := p.instances[key]
+	if !ok {
+		return
+	}
+	delete(p.indexName[i.projection.Name], key)
+	if len(p.indexName[i.projection.Name]) < 1 {
+		delete(p.indexName, i.projection.Name)
+	}
+	delete(p.instances, key)
+}
+
+// ApplyEventUserCreated applies event UserCreated to the projection
+func (p *InmemProjectionUser) ApplyEventUserCreated(
+	ctx context.Context,
+	trx TransactionWriter,
+	v EventlogVersion,
+	tm time.Time,
+	e EventUserCreated,
+) error {
+	if _, ok := p.instances[e.Id]; ok {
+		return fmt.Errorf(
+			"applying UserCreated: User %v already exists",
+			e.Id,
+		)
+	}
+	x := NewProjectionUser()
+	x.Id = e.Id
+	n, err := p.handler.OnUserCreated(ctx, tm, x, e)
+	if err != nil {
+		return err
+	}
+	if n.Id != e.Id {
+		return fmt.Errorf(
+			"applying UserCreated: User key changed (%v -> %v)",
+			e.Id, n.Id,
+		)
+	}
+	if n.state != x.state {
+		return fmt.Errorf(
+			"applying UserCreated: illegal initial state of User %v: %s",
+			e.Id, n.state,
+		)
+	}
+	p.put(inmemstore.Writer(trx), e.Id, n)
+	return nil
+}
+
+/* SERVICES */
+
+// ServiceOptions defines the options of a service,
+// see runtime.ServiceOptions
+type ServiceOptions = runtime.ServiceOptions
+
+type UnknownEventPolicy = runtime.UnknownEventPolicy
+
+const (
+	UnknownEventIgnore = runtime.UnknownEventIgnore
+	UnknownEventFail   = runtime.UnknownEventFail
+)
+
+type FailurePolicy = runtime.FailurePolicy
+
+const (
+	FailurePolicyStop = runtime.FailurePolicyStop
+	FailurePolicySkip = runtime.FailurePolicySkip
+)
+
+// DeadLetter is an event that failed to be decoded or applied
+type DeadLetter = runtime.DeadLetter
+
+// DeadLetterSink receives events that failed to be decoded or applied
+type DeadLetterSink = runtime.DeadLetterSink
+
+// BackoffStrategy returns the delay before the given retry attempt
+type BackoffStrategy = runtime.BackoffStrategy
+
+// ConstantBackoff returns a backoff strategy always waiting for d.
+func ConstantBackoff(d time.Duration) BackoffStrategy {
+	return runtime.ConstantBackoff(d)
+}
+
+// ExponentialBackoff returns a backoff strategy doubling the delay
+// with every attempt starting at min and never exceeding max.
+func ExponentialBackoff(min, max time.Duration) BackoffStrategy {
+	return runtime.ExponentialBackoff(min, max)
+}
+
+const DefaultMaxAttempts = runtime.DefaultMaxAttempts
+
+var DefaultBackoff = runtime.DefaultBackoff
+
+type Option = runtime.Option
+
+const (
+	Unspecified = runtime.Unspecified
+	Disabled    = runtime.Disabled
+	Enabled     = runtime.Enabled
+)
+
+// ConflictErr is returned by transaction methods when the event log
+// was concurrently modified on every attempt.
+type ConflictErr = runtime.ConflictErr
+
+// ProjectionName is the name of a projection
+type ProjectionName = runtime.ProjectionName
+
+const (
+	ProjectionNameTicket ProjectionName = "Ticket"
+	ProjectionNameUser   ProjectionName = "User"
+)
+
+// Projections is a set of projections
+type Projections = runtime.Projections
+
+// LaggingErr is returned when the projections of a service
+// are at different versions
+type LaggingErr = runtime.LaggingErr
+
+// RebuildOptions defines the options of a projection rebuild
+type RebuildOptions = runtime.RebuildOptions
+
+// RebuildProgress describes the progress of a projection rebuild
+type RebuildProgress = runtime.RebuildProgress
+
+// Timer is a pending timer of a projection instance,
+// see runtime.Timer
+type Timer = runtime.Timer
+
+type EventlogVersion = string
+
+// EventLogger represents an abstract event logger
+type EventLogger interface {
+	// IsOffsetOutOfBoundErr returns true if the given error
+	// is an offset-out-of-bound error
+	IsOffsetOutOfBoundErr(error) bool
+
+	// Begin returns the first offset version of the eventlog.
+	//
+	// WARNING: Begin is expected to be thread-safe.
+	Begin(context.Context) (string, error)
+
+	// Scan reads a limited number of events at the given offset version
+	// calling the onEvent callback for every received event.
+	//
+	// WARNING: Scan is expected to be thread-safe.
+	Scan(
+		ctx context.Context,
+		version EventlogVersion,
+		limit uint,
+		onEvent func(
+			offset EventlogVersion,
+			tm time.Time,
+			payload []byte,
+			next EventlogVersion,
+		) error,
+	) error
+
+	// AppendJSON appends one or multiple new events
+	// in JSON format onto the log associating them with the given streams.
+	//
+	// WARNING: AppendJSON is expected to be thread-safe.
+	AppendJSON(
+		ctx context.Context,
+		streams []StreamID,
+		payload []byte,
+	) (
+		offset EventlogVersion,
+		newVersion EventlogVersion,
+		tm time.Time,
+		err error,
+	)
+
+	// IsMismatchingVersionsErr returns true if the given error
+	// is a mismatching-versions error returned by AppendCheckJSON
+	IsMismatchingVersionsErr(error) bool
+
+	// AppendCheckJSON appends one or multiple new events
+	// in JSON format onto the log associating them with the given streams
+	// if the assumed version matches the actual version of the log,
+	// otherwise the append is rejected with an error satisfying
+	// IsMismatchingVersionsErr.
+	// AppendCheckJSON must not retry, retries are driven by the service.
+	//
+	// WARNING: AppendCheckJSON is expected to be thread-safe.
+	AppendCheckJSON(
+		ctx context.Context,
+		assumedVersion EventlogVersion,
+		streams []StreamID,
+		payload []byte,
+	) (
+		offset EventlogVersion,
+		newVersion EventlogVersion,
+		tm time.Time,
+		err error,
+	)
+
+	// AppendCheckStreamsJSON appends one or multiple new events
+	// in JSON format onto the log associating them with the given streams
+	// if none of the given streams received any events after
+	// the assumed version, otherwise the append is rejected with an error
+	// satisfying IsMismatchingVersionsErr.
+	// Events appended to other streams don't cause a rejection.
+	// AppendCheckStreamsJSON must not retry,
+	// retries are driven by the service.
+	//
+	// WARNING: AppendCheckStreamsJSON is expected to be thread-safe.
+	AppendCheckStreamsJSON(
+		ctx context.Context,
+		assumedVersion EventlogVersion,
+		streams []StreamID,
+		payload []byte,
+	) (
+		offset EventlogVersion,
+		newVersion EventlogVersion,
+		tm time.Time,
+		err error,
+	)
+}
+
+// StoreTransactionReadWriter represents an abstract
+// read-write (exclusive locking) store transaction handler
+type StoreTransactionReadWriter interface {
+	Commit()
+	Rollback()
+}
+
+// StoreTransactionReader represents an abstract
+// read only (shared locking) store transaction handler
+type StoreTransactionReader interface {
+	Complete()
+}
+
+// StoreTransactionErrorer can optionally be implemented by
+// store transactions that can fail to begin or to commit.
+// Err is checked after beginning and after committing a transaction,
+// a failed transaction is reported as the error of the service method.
+// Failed transactions must tolerate being committed, rolled back
+// or completed.
+type StoreTransactionErrorer interface {
+	// Err returns the error the transaction failed with, if any
+	Err() error
+}
+
+// storeTransactionErr returns the error trx failed with
+// if trx implements StoreTransactionErrorer
+func storeTransactionErr(trx interface{}) error {
+	if e, ok := trx.(StoreTransactionErrorer); ok {
+		return e.Err()
+	}
+	return nil
+}
+
+// commitStoreTransaction commits trx and returns the error
+// the commit failed with if trx implements StoreTransactionErrorer
+func commitStoreTransaction(trx StoreTransactionReadWriter) error {
+	trx.Commit()
+	return storeTransactionErr(trx)
+}
+
+// StoreTransactionContextBinder can optionally be implemented by
+// a StoreTransactionReadWriter to bind the transaction to the context
+// passed to the EventLogger. This allows an EventLogger backed by
+// the same database to append events within the store transaction
+// making projection updates and appends atomic.
+type StoreTransactionContextBinder interface {
+	BindContext(context.Context) context.Context
+}
+
+// StoreTransactionViewer can optionally be implemented by
+// a StoreTransactionReadWriter to provide the command and timer methods
+// with a read-only view of the transaction preventing them
+// from mutating the projection while deciding.
+// The transaction types of services declaring a transaction type
+// in the schema must implement ReadOnlyView returning the transaction
+// type of the service instead, otherwise the generated code
+// doesn't compile. SQLTransaction returns an SQLReadOnlyView.
+type StoreTransactionViewer interface {
+	ReadOnlyView() TransactionReader
+}
+
+// StoreTransactionSavepointer can optionally be implemented by
+// a StoreTransactionReadWriter to roll back failed attempts
+// to apply an event, which ServiceOptions.ApplyRetries and
+// FailurePolicySkip require. The constructors of services with typed
+// transactions panic if the transaction type doesn't implement it
+// while the options require it.
+type StoreTransactionSavepointer = runtime.Savepointer
+
+// TransactionWriter represents an arbitrary abstract transaction object
+// that's supposed to be used for write-only mutations.
+// TransactionWriter must not be committed or rolled back!
+// Services declaring a transaction type in the schema and services
+// with an SQL store receive transactions of that type instead.
+type TransactionWriter = interface{}
+
+// TransactionReader represents an arbitrary abstract transaction object
+// that's supposed to be used for read-only queries.
+// TransactionReader must not be committed or rolled back!
+// Services declaring a transaction type in the schema and services
+// with an SQL store receive transactions of that type instead.
+type TransactionReader = interface{}
+
+// ServiceTickets projects the following entities:
+//
+//	Ticket//  User
+//
+// therefore, Tickets subscribes to the following events:
+//
+//	TicketClosed
+//	TicketAutoClosed
+//	UserAssignedToTicket
+//	UserUnassignedFromTicket
+//	TicketDescriptionChanged
+//	TicketTitleChanged
+//	TicketCommented
+type ServiceTickets struct {
+	eventlog EventLogger
+	logErr   Logger
+	methods  ServiceTicketsMethodCaller
+	store    ServiceTicketsStoreHandler
+	engine   *runtime.Service[srcticketsstore.Transaction, Event]
+
+	// tenant is the tenant the instance is hosted for, if any
+	tenant *TenantID
 }
 
 // ServiceTicketsStoreHandler represents a store handler implementation
@@ -588,1375 +1971,4421 @@ type ServiceTicketsStoreHandler interface {
 	// read-write transaction handler.
 	// The returned transaction is passed to implementation methods
 	// and will eventually be either committed or rolled back respectively.
-	NewTransactionReadWriter() StoreTransactionReadWriter
+	NewTransactionReadWriter() srcticketsstore.Transaction
+
+	// NewTransactionReader creates a new read-only transaction handler.
+	// The returned transaction is passed to implementation methods
+	// and will eventually be completed.
+	NewTransactionReader() srcticketsstore.Transaction
+
+	// ProjectionVersion returns the current version
+	// of the given projection.
+	// Returns an empty string if the projection wasn't initialized yet.
+	// In case an empty string is returned the service will backfill
+	// the projection from the begin offset version of the eventlog.
+	ProjectionVersion(
+		context.Context,
+		srcticketsstore.Transaction,
+		ProjectionName,
+	) (EventlogVersion, error)
+
+	// UpdateProjectionVersion explicitly sets the
+	// version of the given projection
+	UpdateProjectionVersion(
+		context.Context,
+		srcticketsstore.Transaction,
+		ProjectionName,
+		EventlogVersion,
+	) error
+
+	// ProjectionTicketState returns the current state of the
+	// Ticket projection instance identified by the given key.
+	// Returns an empty state if the instance doesn't exist.
+	ProjectionTicketState(
+		context.Context,
+		srcticketsstore.Transaction,
+		srcticketsid.Ticket,
+	) (ProjectionTicketState, error)
+
+	// ScheduleTimer persists the given timer replacing any pending timer
+	// of the same name and stream.
+	ScheduleTimer(context.Context, srcticketsstore.Transaction, Timer) error
+
+	// CancelTimer removes the pending timer of the given name and stream.
+	// No error is returned if there is no such timer.
+	CancelTimer(
+		ctx context.Context,
+		trx srcticketsstore.Transaction,
+		name string,
+		stream StreamID,
+	) error
+
+	// DueTimers returns all pending timers with a deadline
+	// before or at the given time ordered by deadline.
+	DueTimers(
+		context.Context,
+		srcticketsstore.Transaction,
+		time.Time,
+	) ([]Timer, error)
+
+	// ApplyEventTicketAutoClosedToTicket applies event TicketAutoClosed
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventTicketAutoClosedToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventTicketAutoClosed,
+	) error
+
+	// ApplyEventTicketClosedToTicket applies event TicketClosed
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventTicketClosedToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventTicketClosed,
+	) error
+
+	// ApplyEventTicketCommentedToTicket applies event TicketCommented
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventTicketCommentedToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventTicketCommented,
+	) error
+
+	// ApplyEventTicketCreatedToTicket applies event TicketCreated
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventTicketCreatedToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventTicketCreated,
+	) error
+
+	// ApplyEventTicketDescriptionChangedToTicket applies event TicketDescriptionChanged
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventTicketDescriptionChangedToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventTicketDescriptionChanged,
+	) error
+
+	// ApplyEventTicketTitleChangedToTicket applies event TicketTitleChanged
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventTicketTitleChangedToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventTicketTitleChanged,
+	) error
+
+	// ApplyEventUserAssignedToTicketToTicket applies event UserAssignedToTicket
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventUserAssignedToTicketToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventUserAssignedToTicket,
+	) error
+
+	// ApplyEventUserCreatedToUser applies event UserCreated
+	// to projection User.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventUserCreatedToUser(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventUserCreated,
+	) error
+
+	// ApplyEventUserUnassignedFromTicketToTicket applies event UserUnassignedFromTicket
+	// to projection Ticket.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventUserUnassignedFromTicketToTicket(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+		time.Time,
+		EventUserUnassignedFromTicket,
+	) error
+}
+
+// ServiceTicketsSnapshotter can optionally be implemented by
+// the store handler of the service Tickets to save and load
+// snapshots of the projection, which allows the service to avoid
+// replaying the event log from the beginning.
+type ServiceTicketsSnapshotter interface {
+	// SaveSnapshot persists a snapshot of the projection
+	// at the given projection version.
+	SaveSnapshot(
+		context.Context,
+		srcticketsstore.Transaction,
+		EventlogVersion,
+	) error
+
+	// LoadSnapshot restores the projections from the latest snapshot
+	// and returns the projection version the snapshot was taken at.
+	// Returns an empty string if there's no snapshot yet.
+	// Snapshots are only loaded if none of the projections
+	// is initialized and only saved if all projections
+	// are at the same version.
+	// The returned version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	LoadSnapshot(
+		context.Context,
+		srcticketsstore.Transaction,
+	) (EventlogVersion, error)
+}
+
+// ServiceTicketsRebuilder can optionally be implemented by
+// the store handler of the service Tickets
+// to allow rebuilding the projection using Rebuild.
+type ServiceTicketsRebuilder interface {
+	// NewShadowStore creates a new store handler with a reset projection
+	// that the event log is replayed into during the rebuild.
+	NewShadowStore(context.Context) (ServiceTicketsStoreHandler, error)
+
+	// SwapStore replaces the projection of the store with the projection
+	// of the given rebuilt shadow store previously created by
+	// NewShadowStore including its projection version.
+	// The given transaction will eventually be either committed or
+	// rolled back, which must atomically apply or discard the swap.
+	SwapStore(
+		context.Context,
+		srcticketsstore.Transaction,
+		ServiceTicketsStoreHandler,
+	) error
+}
+
+// ServiceTicketsMethodCaller represents an implementation
+// of the service Tickets
+type ServiceTicketsMethodCaller interface {
+
+	// AssignUserToTicket represents method Tickets.AssignUserToTicket
+	//
+	// The events emitted through the command are appended
+	// to the event log unless an error is returned.
+	// Emitting events the method isn't declared to emit
+	// is prevented at compile time.
+	AssignUserToTicket(
+		context.Context,
+		*ServiceTicketsAssignUserToTicketCommand,
+		srcticketsserviceticketsio.AssignUserToTicketIn,
+	) (
+		// No output
+		err error,
+	)
+
+	// CloseTicket represents method Tickets.CloseTicket
+	//
+	// The events emitted through the command are appended
+	// to the event log unless an error is returned.
+	// Emitting events the method isn't declared to emit
+	// is prevented at compile time.
+	CloseTicket(
+		context.Context,
+		*ServiceTicketsCloseTicketCommand,
+		srcticketsserviceticketsio.CloseTicketIn,
+	) (
+		// No output
+		err error,
+	)
+
+	// CreateComment represents method Tickets.CreateComment
+	//
+	// The events emitted through the command are appended
+	// to the event log unless an error is returned.
+	// Emitting events the method isn't declared to emit
+	// is prevented at compile time.
+	CreateComment(
+		context.Context,
+		*ServiceTicketsCreateCommentCommand,
+		srcticketsserviceticketsio.CreateCommentIn,
+	) (
+		output srcticketsserviceticketsio.CreateCommentOut,
+		err error,
+	)
+
+	// CreateTicket represents method Tickets.CreateTicket
+	//
+	// The events emitted through the command are appended
+	// to the event log unless an error is returned.
+	// Emitting events the method isn't declared to emit
+	// is prevented at compile time.
+	CreateTicket(
+		context.Context,
+		*ServiceTicketsCreateTicketCommand,
+		srcticketsserviceticketsio.CreateTicketIn,
+	) (
+		output srcticketsserviceticketsio.CreateTicketOut,
+		err error,
+	)
+
+	// GetTicketByID represents method Tickets.GetTicketByID
+	//
+	// WARNING: this method is read-only and must not mutate neither
+	// the state of the projection nor the projection version!
+	// The provided transaction must not be committed or rolled back
+	// and shall only be used for queries.
+	GetTicketByID(
+		context.Context,
+		srcticketsstore.Transaction,
+		srcticketsid.Ticket,
+	) (
+		output srcticketsserviceticketsio.GetTicketByIDOut,
+		err error,
+	)
+
+	// UnassignUserFromTicket represents method Tickets.UnassignUserFromTicket
+	//
+	// The events emitted through the command are appended
+	// to the event log unless an error is returned.
+	// Emitting events the method isn't declared to emit
+	// is prevented at compile time.
+	UnassignUserFromTicket(
+		context.Context,
+		*ServiceTicketsUnassignUserFromTicketCommand,
+		srcticketsserviceticketsio.UnassignUserFromTicketIn,
+	) (
+		// No output
+		err error,
+	)
+
+	// UpdateTicket represents method Tickets.UpdateTicket
+	//
+	// The events emitted through the command are appended
+	// to the event log unless an error is returned.
+	// Emitting events the method isn't declared to emit
+	// is prevented at compile time.
+	UpdateTicket(
+		context.Context,
+		*ServiceTicketsUpdateTicketCommand,
+		srcticketsserviceticketsio.UpdateTicketIn,
+	) (
+		// No output
+		err error,
+	)
+
+	// TimerAutoCloseStalledTicket creates the TicketAutoClosed event appended when
+	// the Ticket projection instance identified by key
+	// stayed in state Stalled for 168h0m0s.
+	//
+	// WARNING: this method is read-only and must not mutate neither
+	// the state of the projection nor the projection version!
+	TimerAutoCloseStalledTicket(
+		ctx context.Context,
+		trx srcticketsstore.Transaction,
+		key srcticketsid.Ticket,
+		deadline time.Time,
+	) (EventTicketAutoClosed, error)
+}
+
+// ServiceTicketsCommand provides the command methods
+// of service Tickets with the transaction for queries.
+type ServiceTicketsCommand struct {
+	reader srcticketsstore.Transaction
+}
+
+// Reader returns the transaction to be used for queries.
+// The transaction is a read-only view of the store transaction.
+func (c *ServiceTicketsCommand) Reader() srcticketsstore.Transaction { return c.reader }
+
+// ServiceTicketsAssignUserToTicketCommand is passed to method Tickets.AssignUserToTicket
+// collecting the events it emits, which are limited to the events
+// the method is declared to emit.
+type ServiceTicketsAssignUserToTicketCommand struct {
+	ServiceTicketsCommand
+	events []Event
+}
+
+// EmitUserAssignedToTicket emits event UserAssignedToTicket
+func (c *ServiceTicketsAssignUserToTicketCommand) EmitUserAssignedToTicket(e EventUserAssignedToTicket) {
+	c.events = append(c.events, e)
+}
+
+// Events returns the emitted events in the order of emission
+func (c *ServiceTicketsAssignUserToTicketCommand) Events() []Event { return c.events }
+
+// ServiceTicketsCloseTicketCommand is passed to method Tickets.CloseTicket
+// collecting the events it emits, which are limited to the events
+// the method is declared to emit.
+type ServiceTicketsCloseTicketCommand struct {
+	ServiceTicketsCommand
+	events []Event
+}
+
+// EmitTicketClosed emits event TicketClosed
+func (c *ServiceTicketsCloseTicketCommand) EmitTicketClosed(e EventTicketClosed) {
+	c.events = append(c.events, e)
+}
+
+// Events returns the emitted events in the order of emission
+func (c *ServiceTicketsCloseTicketCommand) Events() []Event { return c.events }
+
+// ServiceTicketsCreateCommentCommand is passed to method Tickets.CreateComment
+// collecting the events it emits, which are limited to the events
+// the method is declared to emit.
+type ServiceTicketsCreateCommentCommand struct {
+	ServiceTicketsCommand
+	events []Event
+}
+
+// EmitTicketCommented emits event TicketCommented
+func (c *ServiceTicketsCreateCommentCommand) EmitTicketCommented(e EventTicketCommented) {
+	c.events = append(c.events, e)
+}
+
+// Events returns the emitted events in the order of emission
+func (c *ServiceTicketsCreateCommentCommand) Events() []Event { return c.events }
+
+// ServiceTicketsCreateTicketCommand is passed to method Tickets.CreateTicket
+// collecting the events it emits, which are limited to the events
+// the method is declared to emit.
+type ServiceTicketsCreateTicketCommand struct {
+	ServiceTicketsCommand
+	events []Event
+}
+
+// EmitTicketCreated emits event TicketCreated
+func (c *ServiceTicketsCreateTicketCommand) EmitTicketCreated(e EventTicketCreated) {
+	c.events = append(c.events, e)
+}
+
+// Events returns the emitted events in the order of emission
+func (c *ServiceTicketsCreateTicketCommand) Events() []Event { return c.events }
+
+// ServiceTicketsUnassignUserFromTicketCommand is passed to method Tickets.UnassignUserFromTicket
+// collecting the events it emits, which are limited to the events
+// the method is declared to emit.
+type ServiceTicketsUnassignUserFromTicketCommand struct {
+	ServiceTicketsCommand
+	events []Event
+}
+
+// EmitUserUnassignedFromTicket emits event UserUnassignedFromTicket
+func (c *ServiceTicketsUnassignUserFromTicketCommand) EmitUserUnassignedFromTicket(e EventUserUnassignedFromTicket) {
+	c.events = append(c.events, e)
+}
+
+// Events returns the emitted events in the order of emission
+func (c *ServiceTicketsUnassignUserFromTicketCommand) Events() []Event { return c.events }
+
+// ServiceTicketsUpdateTicketCommand is passed to method Tickets.UpdateTicket
+// collecting the events it emits, which are limited to the events
+// the method is declared to emit.
+type ServiceTicketsUpdateTicketCommand struct {
+	ServiceTicketsCommand
+	events []Event
+}
+
+// EmitTicketDescriptionChanged emits event TicketDescriptionChanged
+func (c *ServiceTicketsUpdateTicketCommand) EmitTicketDescriptionChanged(e EventTicketDescriptionChanged) {
+	c.events = append(c.events, e)
+}
+
+// EmitTicketTitleChanged emits event TicketTitleChanged
+func (c *ServiceTicketsUpdateTicketCommand) EmitTicketTitleChanged(e EventTicketTitleChanged) {
+	c.events = append(c.events, e)
+}
+
+// Events returns the emitted events in the order of emission
+func (c *ServiceTicketsUpdateTicketCommand) Events() []Event { return c.events }
+
+// readOnlyView returns the read-only view of trx.
+// The transaction type of the service must implement ReadOnlyView
+// returning srcticketsstore.Transaction.
+func (s *ServiceTickets) readOnlyView(trx srcticketsstore.Transaction) srcticketsstore.Transaction {
+	return trx.ReadOnlyView()
+}
+
+// withTenant binds the tenant the instance is hosted for, if any,
+// to ctx making it available to the store handler
+// and the method caller through TenantFromContext
+func (s *ServiceTickets) withTenant(ctx context.Context) context.Context {
+	if s.tenant == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyTenant{}, *s.tenant)
+}
+
+// NewServiceTickets creates a new instance of the Tickets service.
+func NewServiceTickets(
+	methodCaller ServiceTicketsMethodCaller,
+	storeHandler ServiceTicketsStoreHandler,
+	eventLogger EventLogger,
+	errorLogger Logger,
+	options ServiceOptions,
+) *ServiceTickets {
+	if methodCaller == nil {
+		panic("methodCaller is nil in NewServiceTickets")
+	}
+	if storeHandler == nil {
+		panic("storeHandler is nil in NewServiceTickets")
+	}
+	if eventLogger == nil {
+		panic("eventLogger is nil in NewServiceTickets")
+	}
+	if options.FailurePolicy == FailurePolicySkip &&
+		options.DeadLetterSink == nil {
+		panic("options.DeadLetterSink is nil in NewServiceTickets")
+	}
+	if options.RequiresSavepointer() &&
+		!runtime.ImplementsSavepointer[srcticketsstore.Transaction]() {
+		panic("options require transactions implementing " +
+			"StoreTransactionSavepointer in NewServiceTickets")
+	}
+	if errorLogger == nil {
+		errorLogger = defaultLogErr
+	}
+	options.SetDefaults()
+	s := newServiceTickets(
+		methodCaller, storeHandler, eventLogger, errorLogger, options,
+	)
+	if snapshotter, ok := storeHandler.(ServiceTicketsSnapshotter); ok {
+		s.engine.Snapshotter = snapshotter
+	}
+	return s
+}
+
+// newServiceTickets binds a new instance of the Tickets service
+// to the runtime service engine
+func newServiceTickets(
+	methodCaller ServiceTicketsMethodCaller,
+	storeHandler ServiceTicketsStoreHandler,
+	eventLogger EventLogger,
+	errorLogger Logger,
+	options ServiceOptions,
+) *ServiceTickets {
+	s := &ServiceTickets{
+		methods:  methodCaller,
+		store:    storeHandler,
+		eventlog: eventLogger,
+		logErr:   errorLogger,
+	}
+	s.engine = &runtime.Service[srcticketsstore.Transaction, Event]{
+		Name: "Tickets",
+		Projections: Projections{
+			ProjectionNameTicket,
+			ProjectionNameUser,
+		},
+		EventLog:     eventLogger,
+		Store:        storeHandler,
+		Options:      options,
+		IsSubscribed: s.isSubscribed,
+		DecodeEvent:  DecodeEventJSON,
+		ApplyEvent:   s.applyEvent,
+	}
+	return s
+}
+
+// ProjectionVersion returns the current projection version.
+// Returns a LaggingErr if the projections are at different versions.
+func (s *ServiceTickets) ProjectionVersion(ctx context.Context) (
+	EventlogVersion,
+	error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err := storeTransactionErr(txn); err != nil {
+		return "", err
+	}
+
+	return s.engine.ProjectionVersion(ctx, txn)
+}
+
+// ProjectionVersions returns the current version of each projection
+// of service Tickets. The version of projections that weren't
+// initialized yet is empty.
+func (s *ServiceTickets) ProjectionVersions(ctx context.Context) (
+	map[ProjectionName]EventlogVersion,
+	error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err := storeTransactionErr(txn); err != nil {
+		return nil, err
+	}
+
+	return s.engine.ProjectionVersions(ctx, txn)
+}
+
+// LaggingProjections returns the projections of service Tickets
+// lagging behind the projection furthest ahead, which are backfilled
+// during the next synchronization.
+// Returns nil if all projections are at the same version.
+func (s *ServiceTickets) LaggingProjections(ctx context.Context) (
+	Projections,
+	error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err := storeTransactionErr(txn); err != nil {
+		return nil, err
+	}
+
+	return s.engine.Lagging(ctx, txn)
+}
+
+// Sync synchronizes service Tickets against the eventlog.
+// If the projection wasn't initialized yet and the store handler
+// implements ServiceTicketsSnapshotter then the latest snapshot is loaded
+// before scanning.
+// Sync will scan events until it reaches the tip of the event log and
+// always return the latest version of the event log it managed to reach,
+// unless the returned error is not equal context.Canceled or
+// context.DeadlineExceeded and didn't pass isErrAcceptable (if not nil).
+func (s *ServiceTickets) Sync(
+	ctx context.Context,
+	isErrAcceptable func(error) bool,
+) (
+	latestVersion EventlogVersion,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReadWriter()
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) ||
+			(isErrAcceptable != nil && isErrAcceptable(err)) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	return s.engine.Sync(ctx, txn)
+}
+
+// isSubscribed peeks the type of the given encoded event
+// and returns true if the service is subscribed to it.
+// Returns an UnknownEventTypeErr for unknown event types
+// if the UnknownEvents option is UnknownEventFail.
+func (s *ServiceTickets) isSubscribed(payload []byte) (bool, error) {
+	typeName, err := DecodeEventTypeName(payload)
+	if err != nil {
+		return false, err
+	}
+	switch typeName {
+	case "TicketAutoClosed":
+		return true, nil
+	case "TicketClosed":
+		return true, nil
+	case "TicketCommented":
+		return true, nil
+	case "TicketCreated":
+		return true, nil
+	case "TicketDescriptionChanged":
+		return true, nil
+	case "TicketTitleChanged":
+		return true, nil
+	case "UserAssignedToTicket":
+		return true, nil
+	case "UserCreated":
+		return true, nil
+	case "UserUnassignedFromTicket":
+		return true, nil
+	}
+	if s.engine.Options.UnknownEvents == UnknownEventFail &&
+		!IsEventTypeKnown(typeName) {
+		return false, UnknownEventTypeErr(fmt.Sprintf(
+			"unknown event type %s", typeName,
+		))
+	}
+	return false, nil
+}
+
+// applyEvent applies the given event to the given projections.
+// Events the service isn't subscribed to are ignored.
+func (s *ServiceTickets) applyEvent(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev Event,
+) error {
+	switch v := ev.(type) {
+	case EventTicketAutoClosed:
+		return s.applyTimedTicketAutoClosed(ctx, trx, projections, version, tm, v)
+	case EventTicketClosed:
+		return s.applyTimedTicketClosed(ctx, trx, projections, version, tm, v)
+	case EventTicketCommented:
+		return s.applyTimedTicketCommented(ctx, trx, projections, version, tm, v)
+	case EventTicketCreated:
+		return s.applyTimedTicketCreated(ctx, trx, projections, version, tm, v)
+	case EventTicketDescriptionChanged:
+		return s.applyTimedTicketDescriptionChanged(ctx, trx, projections, version, tm, v)
+	case EventTicketTitleChanged:
+		return s.applyTimedTicketTitleChanged(ctx, trx, projections, version, tm, v)
+	case EventUserAssignedToTicket:
+		return s.applyTimedUserAssignedToTicket(ctx, trx, projections, version, tm, v)
+	case EventUserCreated:
+		return s.applyUserCreated(ctx, trx, projections, version, tm, v)
+	case EventUserUnassignedFromTicket:
+		return s.applyTimedUserUnassignedFromTicket(ctx, trx, projections, version, tm, v)
+	}
+	return nil
+}
+
+// applyTicketAutoClosed applies event TicketAutoClosed
+// to the given projections
+func (s *ServiceTickets) applyTicketAutoClosed(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketAutoClosed,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventTicketAutoClosedToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTicketClosed applies event TicketClosed
+// to the given projections
+func (s *ServiceTickets) applyTicketClosed(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketClosed,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventTicketClosedToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTicketCommented applies event TicketCommented
+// to the given projections
+func (s *ServiceTickets) applyTicketCommented(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketCommented,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventTicketCommentedToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTicketCreated applies event TicketCreated
+// to the given projections
+func (s *ServiceTickets) applyTicketCreated(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketCreated,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventTicketCreatedToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTicketDescriptionChanged applies event TicketDescriptionChanged
+// to the given projections
+func (s *ServiceTickets) applyTicketDescriptionChanged(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketDescriptionChanged,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventTicketDescriptionChangedToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTicketTitleChanged applies event TicketTitleChanged
+// to the given projections
+func (s *ServiceTickets) applyTicketTitleChanged(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketTitleChanged,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventTicketTitleChangedToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyUserAssignedToTicket applies event UserAssignedToTicket
+// to the given projections
+func (s *ServiceTickets) applyUserAssignedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventUserAssignedToTicket,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventUserAssignedToTicketToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyUserCreated applies event UserCreated
+// to the given projections
+func (s *ServiceTickets) applyUserCreated(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventUserCreated,
+) error {
+	if projections.Has(ProjectionNameUser) {
+		if err := s.store.ApplyEventUserCreatedToUser(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyUserUnassignedFromTicket applies event UserUnassignedFromTicket
+// to the given projections
+func (s *ServiceTickets) applyUserUnassignedFromTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventUserUnassignedFromTicket,
+) error {
+	if projections.Has(ProjectionNameTicket) {
+		if err := s.store.ApplyEventUserUnassignedFromTicketToTicket(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTimedTicketAutoClosed applies event TicketAutoClosed
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedTicketAutoClosed(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketAutoClosed,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Ticket,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyTicketAutoClosed(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Ticket
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTimedTicketClosed applies event TicketClosed
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedTicketClosed(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketClosed,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Ticket,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyTicketClosed(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Ticket
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTimedTicketCommented applies event TicketCommented
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedTicketCommented(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketCommented,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Ticket,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyTicketCommented(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Ticket
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTimedTicketCreated applies event TicketCreated
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedTicketCreated(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketCreated,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyTicketCreated(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Id
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTimedTicketDescriptionChanged applies event TicketDescriptionChanged
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedTicketDescriptionChanged(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketDescriptionChanged,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Ticket,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyTicketDescriptionChanged(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Ticket
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTimedTicketTitleChanged applies event TicketTitleChanged
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedTicketTitleChanged(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventTicketTitleChanged,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Ticket,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyTicketTitleChanged(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Ticket
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTimedUserAssignedToTicket applies event UserAssignedToTicket
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedUserAssignedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventUserAssignedToTicket,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Ticket,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyUserAssignedToTicket(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Ticket
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTimedUserUnassignedFromTicket applies event UserUnassignedFromTicket
+// to the given projections scheduling timers for projection instances
+// entering the timer state and cancelling the timers of instances
+// leaving it.
+func (s *ServiceTickets) applyTimedUserUnassignedFromTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventUserUnassignedFromTicket,
+) error {
+	beforeAutoCloseStalledTicket, err := s.store.ProjectionTicketState(
+		ctx, trx, ev.Ticket,
+	)
+	if err != nil {
+		return fmt.Errorf("reading state before applying: %w", err)
+	}
+
+	if err := s.applyUserUnassignedFromTicket(
+		ctx, trx, projections, version, tm, ev,
+	); err != nil {
+		return err
+	}
+	{ // Timer AutoCloseStalledTicket
+		key := ev.Ticket
+		after, err := s.store.ProjectionTicketState(
+			ctx, trx, key,
+		)
+		if err != nil {
+			return fmt.Errorf("reading state after applying: %w", err)
+		}
+		const state = ProjectionTicketStateStalled
+		switch {
+		case beforeAutoCloseStalledTicket != state && after == state:
+			k, err := json.Marshal(key)
+			if err != nil {
+				return fmt.Errorf("encoding timer key: %w", err)
+			}
+			if err := s.store.ScheduleTimer(ctx, trx, Timer{
+				Name:     "AutoCloseStalledTicket",
+				Stream:   StreamIDTicket(key),
+				Key:      k,
+				Deadline: tm.Add(604800000000000), // 168h0m0s
+			}); err != nil {
+				return fmt.Errorf("scheduling timer: %w", err)
+			}
+		case beforeAutoCloseStalledTicket == state && after != state:
+			if err := s.store.CancelTimer(
+				ctx, trx, "AutoCloseStalledTicket", StreamIDTicket(key),
+			); err != nil {
+				return fmt.Errorf("cancelling timer: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// FireTimers appends the events of all timers of service Tickets
+// that are due according to the Clock option
+// and returns the number of fired timers.
+// Timers of projection instances that left the timer state
+// are cancelled during synchronization and never fire.
+// A timer is left pending and fired during the next call
+// if its stream changed concurrently.
+func (s *ServiceTickets) FireTimers(ctx context.Context) (
+	fired uint,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReadWriter()
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	return s.engine.FireTimers(ctx, txn, s.store, s.eventlog, func(
+		ctx context.Context,
+		t Timer,
+	) ([]byte, error) {
+		var e Event
+		switch t.Name {
+		case "AutoCloseStalledTicket":
+			var key srcticketsid.Ticket
+			if err := json.Unmarshal(t.Key, &key); err != nil {
+				return nil, fmt.Errorf("decoding timer key: %w", err)
+			}
+			var err error
+			if e, err = s.methods.TimerAutoCloseStalledTicket(
+				ctx, s.readOnlyView(txn), key, t.Deadline,
+			); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown timer %s", t.Name)
+		}
+		return EncodeEventJSON(e)
+	})
+}
+
+// Reprocess applies a dead-lettered event to the projection of
+// service Tickets again, which is useful once the cause of
+// the failure is fixed. The projection version remains unchanged.
+func (s *ServiceTickets) Reprocess(
+	ctx context.Context,
+	deadLetter DeadLetter,
+) (err error) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReadWriter()
+	defer func() {
+		if err == nil {
+			err = commitStoreTransaction(txn)
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+
+	return s.engine.Reprocess(ctx, txn, deadLetter)
+}
+
+// Rebuild rebuilds the projection of service Tickets by replaying
+// the event log into a shadow store created by the store handler
+// and atomically swapping the current projection with the rebuilt one
+// once it has caught up. Requires the store handler to implement
+// ServiceTicketsRebuilder. The service remains available during the rebuild.
+// Returns the projection version reached after the swap.
+func (s *ServiceTickets) Rebuild(
+	ctx context.Context,
+	options RebuildOptions,
+) (EventlogVersion, error) {
+	ctx = s.withTenant(ctx)
+	rebuilder, ok := s.store.(ServiceTicketsRebuilder)
+	if !ok {
+		return "", fmt.Errorf(
+			"store handler of service Tickets " +
+				"doesn't implement ServiceTicketsRebuilder",
+		)
+	}
+
+	shadowStore, err := rebuilder.NewShadowStore(ctx)
+	if err != nil {
+		return "", fmt.Errorf("creating shadow store: %w", err)
+	}
+
+	shadow := newServiceTickets(
+		s.methods, shadowStore, s.eventlog, s.logErr, s.engine.Options,
+	)
+	return s.engine.Rebuild(
+		ctx,
+		shadow.engine,
+		options,
+		func(fn func(srcticketsstore.Transaction) error) error {
+			return transactServiceTickets(shadowStore, fn)
+		},
+		func(fn func(srcticketsstore.Transaction) error) error {
+			return transactServiceTickets(s.store, fn)
+		},
+		func(ctx context.Context, trx srcticketsstore.Transaction) error {
+			return rebuilder.SwapStore(ctx, trx, shadowStore)
+		},
+	)
+}
+
+// transactServiceTickets invokes fn within a new read-write transaction
+// of the given store handler committing the transaction if fn succeeds
+// and rolling it back otherwise
+func transactServiceTickets(
+	store ServiceTicketsStoreHandler,
+	fn func(srcticketsstore.Transaction) error,
+) (err error) {
+	txn := store.NewTransactionReadWriter()
+	defer func() {
+		if err == nil {
+			err = commitStoreTransaction(txn)
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	return fn(txn)
+}
+
+func (s *ServiceTickets) AssignUserToTicket(
+	ctx context.Context,
+	input srcticketsserviceticketsio.AssignUserToTicketIn,
+) (
+	// No output
+	events []Event,
+	eventsPushTime time.Time,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+
+	var eventsJSON []byte
+	var eventsStreams []StreamID
+
+	defer func() {
+		if err != nil {
+			// No output to reset
+			events = nil
+			eventsJSON = nil
+			eventsStreams = nil
+			eventsPushTime = time.Time{}
+		}
+	}()
+	txn := s.store.NewTransactionReadWriter()
+	// Committed before the outputs are reset to reset them
+	// if the commit fails
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	exec := func() (ok bool) {
+		cmd := &ServiceTicketsAssignUserToTicketCommand{
+			ServiceTicketsCommand: ServiceTicketsCommand{reader: s.readOnlyView(txn)},
+		}
+		err = s.methods.AssignUserToTicket(ctx, cmd, input)
+		if err != nil {
+			return false
+		}
+		events = cmd.events
+		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
+			return false
+		}
+		eventsStreams = GetEventStreamIDs(events...)
+		return true
+	}
+
+	err = s.engine.Transact(ctx, txn, "Tickets.AssignUserToTicket", func(
+		version EventlogVersion,
+	) (conflict bool, _ error) {
+		if !exec() {
+			return false, err
+		}
+		// Only the streams affected by the events need to be unchanged
+		_, _, eventsPushTime, err = s.eventlog.AppendCheckStreamsJSON(
+			ctx, version, eventsStreams, eventsJSON,
+		)
+		return s.eventlog.IsMismatchingVersionsErr(err), err
+	})
+
+	if err != nil {
+		return
+	}
+	if s.engine.Options.SyncAfterPush == Enabled && len(events) > 0 {
+		_, err = s.engine.Sync(ctx, txn)
+	}
+
+	return
+}
+
+func (s *ServiceTickets) CloseTicket(
+	ctx context.Context,
+	input srcticketsserviceticketsio.CloseTicketIn,
+) (
+	// No output
+	events []Event,
+	eventsPushTime time.Time,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+
+	var eventsJSON []byte
+	var eventsStreams []StreamID
+
+	defer func() {
+		if err != nil {
+			// No output to reset
+			events = nil
+			eventsJSON = nil
+			eventsStreams = nil
+			eventsPushTime = time.Time{}
+		}
+	}()
+	txn := s.store.NewTransactionReadWriter()
+	// Committed before the outputs are reset to reset them
+	// if the commit fails
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	exec := func() (ok bool) {
+		cmd := &ServiceTicketsCloseTicketCommand{
+			ServiceTicketsCommand: ServiceTicketsCommand{reader: s.readOnlyView(txn)},
+		}
+		err = s.methods.CloseTicket(ctx, cmd, input)
+		if err != nil {
+			return false
+		}
+		events = cmd.events
+		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
+			return false
+		}
+		eventsStreams = GetEventStreamIDs(events...)
+		return true
+	}
+
+	err = s.engine.Transact(ctx, txn, "Tickets.CloseTicket", func(
+		version EventlogVersion,
+	) (conflict bool, _ error) {
+		if !exec() {
+			return false, err
+		}
+		// Only the streams affected by the events need to be unchanged
+		_, _, eventsPushTime, err = s.eventlog.AppendCheckStreamsJSON(
+			ctx, version, eventsStreams, eventsJSON,
+		)
+		return s.eventlog.IsMismatchingVersionsErr(err), err
+	})
+
+	if err != nil {
+		return
+	}
+	if s.engine.Options.SyncAfterPush == Enabled && len(events) > 0 {
+		_, err = s.engine.Sync(ctx, txn)
+	}
+
+	return
+}
+
+func (s *ServiceTickets) CreateComment(
+	ctx context.Context,
+	input srcticketsserviceticketsio.CreateCommentIn,
+) (
+	output srcticketsserviceticketsio.CreateCommentOut,
+	events []Event,
+	eventsPushTime time.Time,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	var outZero srcticketsserviceticketsio.CreateCommentOut
+	var eventsJSON []byte
+	var eventsStreams []StreamID
+
+	defer func() {
+		if err != nil {
+			output = outZero
+			events = nil
+			eventsJSON = nil
+			eventsStreams = nil
+			eventsPushTime = time.Time{}
+		}
+	}()
+	txn := s.store.NewTransactionReadWriter()
+	// Committed before the outputs are reset to reset them
+	// if the commit fails
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	exec := func() (ok bool) {
+		cmd := &ServiceTicketsCreateCommentCommand{
+			ServiceTicketsCommand: ServiceTicketsCommand{reader: s.readOnlyView(txn)},
+		}
+		output, err = s.methods.CreateComment(ctx, cmd, input)
+		if err != nil {
+			return false
+		}
+		events = cmd.events
+		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
+			return false
+		}
+		eventsStreams = GetEventStreamIDs(events...)
+		return true
+	}
+
+	err = s.engine.Transact(ctx, txn, "Tickets.CreateComment", func(
+		version EventlogVersion,
+	) (conflict bool, _ error) {
+		if !exec() {
+			return false, err
+		}
+		// Only the streams affected by the events need to be unchanged
+		_, _, eventsPushTime, err = s.eventlog.AppendCheckStreamsJSON(
+			ctx, version, eventsStreams, eventsJSON,
+		)
+		return s.eventlog.IsMismatchingVersionsErr(err), err
+	})
+
+	if err != nil {
+		return
+	}
+	if s.engine.Options.SyncAfterPush == Enabled && len(events) > 0 {
+		_, err = s.engine.Sync(ctx, txn)
+	}
+
+	return
+}
+
+func (s *ServiceTickets) CreateTicket(
+	ctx context.Context,
+	input srcticketsserviceticketsio.CreateTicketIn,
+) (
+	output srcticketsserviceticketsio.CreateTicketOut,
+	events []Event,
+	eventsPushTime time.Time,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	var outZero srcticketsserviceticketsio.CreateTicketOut
+	var eventsJSON []byte
+	var eventsStreams []StreamID
+
+	defer func() {
+		if err != nil {
+			output = outZero
+			events = nil
+			eventsJSON = nil
+			eventsStreams = nil
+			eventsPushTime = time.Time{}
+		}
+	}()
+	txn := s.store.NewTransactionReadWriter()
+	// Committed before the outputs are reset to reset them
+	// if the commit fails
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	exec := func() (ok bool) {
+		cmd := &ServiceTicketsCreateTicketCommand{
+			ServiceTicketsCommand: ServiceTicketsCommand{reader: s.readOnlyView(txn)},
+		}
+		output, err = s.methods.CreateTicket(ctx, cmd, input)
+		if err != nil {
+			return false
+		}
+		events = cmd.events
+		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
+			return false
+		}
+		eventsStreams = GetEventStreamIDs(events...)
+		return true
+	}
+
+	err = s.engine.Transact(ctx, txn, "Tickets.CreateTicket", func(
+		version EventlogVersion,
+	) (conflict bool, _ error) {
+		if !exec() {
+			return false, err
+		}
+		// Only the streams affected by the events need to be unchanged
+		_, _, eventsPushTime, err = s.eventlog.AppendCheckStreamsJSON(
+			ctx, version, eventsStreams, eventsJSON,
+		)
+		return s.eventlog.IsMismatchingVersionsErr(err), err
+	})
+
+	if err != nil {
+		return
+	}
+	if s.engine.Options.SyncAfterPush == Enabled && len(events) > 0 {
+		_, err = s.engine.Sync(ctx, txn)
+	}
+
+	return
+}
+
+func (s *ServiceTickets) GetTicketByID(
+	ctx context.Context,
+	input srcticketsid.Ticket,
+) (
+	output srcticketsserviceticketsio.GetTicketByIDOut,
+	// No events
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	var outZero srcticketsserviceticketsio.GetTicketByIDOut
+
+	defer func() {
+		if err != nil {
+			output = outZero
+			// No events to reset
+		}
+	}()
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+
+	exec := func() (ok bool) {
+		output, err = s.methods.GetTicketByID(ctx, txn, input)
+		if err != nil {
+			return false
+		}
+
+		return true
+	}
+
+	exec()
+
+	return
+}
+
+func (s *ServiceTickets) UnassignUserFromTicket(
+	ctx context.Context,
+	input srcticketsserviceticketsio.UnassignUserFromTicketIn,
+) (
+	// No output
+	events []Event,
+	eventsPushTime time.Time,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+
+	var eventsJSON []byte
+	var eventsStreams []StreamID
+
+	defer func() {
+		if err != nil {
+			// No output to reset
+			events = nil
+			eventsJSON = nil
+			eventsStreams = nil
+			eventsPushTime = time.Time{}
+		}
+	}()
+	txn := s.store.NewTransactionReadWriter()
+	// Committed before the outputs are reset to reset them
+	// if the commit fails
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	exec := func() (ok bool) {
+		cmd := &ServiceTicketsUnassignUserFromTicketCommand{
+			ServiceTicketsCommand: ServiceTicketsCommand{reader: s.readOnlyView(txn)},
+		}
+		err = s.methods.UnassignUserFromTicket(ctx, cmd, input)
+		if err != nil {
+			return false
+		}
+		events = cmd.events
+		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
+			return false
+		}
+		eventsStreams = GetEventStreamIDs(events...)
+		return true
+	}
+
+	err = s.engine.Transact(ctx, txn, "Tickets.UnassignUserFromTicket", func(
+		version EventlogVersion,
+	) (conflict bool, _ error) {
+		if !exec() {
+			return false, err
+		}
+		// Only the streams affected by the events need to be unchanged
+		_, _, eventsPushTime, err = s.eventlog.AppendCheckStreamsJSON(
+			ctx, version, eventsStreams, eventsJSON,
+		)
+		return s.eventlog.IsMismatchingVersionsErr(err), err
+	})
+
+	if err != nil {
+		return
+	}
+	if s.engine.Options.SyncAfterPush == Enabled && len(events) > 0 {
+		_, err = s.engine.Sync(ctx, txn)
+	}
+
+	return
+}
+
+func (s *ServiceTickets) UpdateTicket(
+	ctx context.Context,
+	input srcticketsserviceticketsio.UpdateTicketIn,
+) (
+	// No output
+	events []Event,
+	eventsPushTime time.Time,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+
+	var eventsJSON []byte
+	var eventsStreams []StreamID
+
+	defer func() {
+		if err != nil {
+			// No output to reset
+			events = nil
+			eventsJSON = nil
+			eventsStreams = nil
+			eventsPushTime = time.Time{}
+		}
+	}()
+	txn := s.store.NewTransactionReadWriter()
+	// Committed before the outputs are reset to reset them
+	// if the commit fails
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	exec := func() (ok bool) {
+		cmd := &ServiceTicketsUpdateTicketCommand{
+			ServiceTicketsCommand: ServiceTicketsCommand{reader: s.readOnlyView(txn)},
+		}
+		err = s.methods.UpdateTicket(ctx, cmd, input)
+		if err != nil {
+			return false
+		}
+		events = cmd.events
+		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
+			return false
+		}
+		eventsStreams = GetEventStreamIDs(events...)
+		return true
+	}
+
+	err = s.engine.Transact(ctx, txn, "Tickets.UpdateTicket", func(
+		version EventlogVersion,
+	) (conflict bool, _ error) {
+		if !exec() {
+			return false, err
+		}
+		// Only the streams affected by the events need to be unchanged
+		_, _, eventsPushTime, err = s.eventlog.AppendCheckStreamsJSON(
+			ctx, version, eventsStreams, eventsJSON,
+		)
+		return s.eventlog.IsMismatchingVersionsErr(err), err
+	})
+
+	if err != nil {
+		return
+	}
+	if s.engine.Options.SyncAfterPush == Enabled && len(events) > 0 {
+		_, err = s.engine.Sync(ctx, txn)
+	}
+
+	return
+}
+
+// ServiceUsers projects the following entities:
+//
+//	User
+//
+// therefore, Users subscribes to the following events:
+type ServiceUsers struct {
+	eventlog EventLogger
+	logErr   Logger
+	methods  ServiceUsersMethodCaller
+	store    ServiceUsersStoreHandler
+	engine   *runtime.Service[*SQLTransaction, Event]
+
+	// tenant is the tenant the instance is hosted for, if any
+	tenant *TenantID
+}
+
+// ServiceUsersStoreHandler represents a store handler implementation
+// of the service Users
+type ServiceUsersStoreHandler interface {
+	// NewTransactionReadWriter creates a new exclusive
+	// read-write transaction handler.
+	// The returned transaction is passed to implementation methods
+	// and will eventually be either committed or rolled back respectively.
+	NewTransactionReadWriter() *SQLTransaction
+
+	// NewTransactionReader creates a new read-only transaction handler.
+	// The returned transaction is passed to implementation methods
+	// and will eventually be completed.
+	NewTransactionReader() *SQLTransaction
+
+	// ProjectionVersion returns the current version
+	// of the given projection.
+	// Returns an empty string if the projection wasn't initialized yet.
+	// In case an empty string is returned the service will backfill
+	// the projection from the begin offset version of the eventlog.
+	ProjectionVersion(
+		context.Context,
+		*SQLTransaction,
+		ProjectionName,
+	) (EventlogVersion, error)
+
+	// UpdateProjectionVersion explicitly sets the
+	// version of the given projection
+	UpdateProjectionVersion(
+		context.Context,
+		*SQLTransaction,
+		ProjectionName,
+		EventlogVersion,
+	) error
+
+	// ApplyEventUserCreatedToUser applies event UserCreated
+	// to projection User.
+	// The given projection version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	ApplyEventUserCreatedToUser(
+		context.Context,
+		*SQLTransaction,
+		EventlogVersion,
+		time.Time,
+		EventUserCreated,
+	) error
+}
+
+// ServiceUsersSnapshotter can optionally be implemented by
+// the store handler of the service Users to save and load
+// snapshots of the projection, which allows the service to avoid
+// replaying the event log from the beginning.
+type ServiceUsersSnapshotter interface {
+	// SaveSnapshot persists a snapshot of the projection
+	// at the given projection version.
+	SaveSnapshot(
+		context.Context,
+		*SQLTransaction,
+		EventlogVersion,
+	) error
+
+	// LoadSnapshot restores the projections from the latest snapshot
+	// and returns the projection version the snapshot was taken at.
+	// Returns an empty string if there's no snapshot yet.
+	// Snapshots are only loaded if none of the projections
+	// is initialized and only saved if all projections
+	// are at the same version.
+	// The returned version doesn't need to be applied,
+	// it will be applied in a separate call to UpdateProjectionVersion.
+	LoadSnapshot(
+		context.Context,
+		*SQLTransaction,
+	) (EventlogVersion, error)
+}
+
+// ServiceUsersRebuilder can optionally be implemented by
+// the store handler of the service Users
+// to allow rebuilding the projection using Rebuild.
+type ServiceUsersRebuilder interface {
+	// NewShadowStore creates a new store handler with a reset projection
+	// that the event log is replayed into during the rebuild.
+	NewShadowStore(context.Context) (ServiceUsersStoreHandler, error)
+
+	// SwapStore replaces the projection of the store with the projection
+	// of the given rebuilt shadow store previously created by
+	// NewShadowStore including its projection version.
+	// The given transaction will eventually be either committed or
+	// rolled back, which must atomically apply or discard the swap.
+	SwapStore(
+		context.Context,
+		*SQLTransaction,
+		ServiceUsersStoreHandler,
+	) error
+}
+
+// ServiceUsersMethodCaller represents an implementation
+// of the service Users
+type ServiceUsersMethodCaller interface {
+
+	// CreateUser represents method Users.CreateUser
+	//
+	// The events emitted through the command are appended
+	// to the event log unless an error is returned.
+	// Emitting events the method isn't declared to emit
+	// is prevented at compile time.
+	CreateUser(
+		context.Context,
+		*ServiceUsersCreateUserCommand,
+		srcticketsserviceusersio.CreateUserIn,
+	) (
+		output srcticketsserviceusersio.CreateUserOut,
+		err error,
+	)
+
+	// GetUserByID represents method Users.GetUserByID
+	//
+	// WARNING: this method is read-only and must not mutate neither
+	// the state of the projection nor the projection version!
+	// The provided transaction must not be committed or rolled back
+	// and shall only be used for queries.
+	GetUserByID(
+		context.Context,
+		SQLReadOnlyView,
+		srcticketsid.User,
+	) (
+		output srcticketsserviceusersio.GetUserByIDOut,
+		err error,
+	)
+}
+
+// ServiceUsersCommand provides the command methods
+// of service Users with the transaction for queries.
+type ServiceUsersCommand struct {
+	reader SQLReadOnlyView
+}
+
+// Reader returns the transaction to be used for queries.
+// The transaction is a read-only view of the store transaction.
+func (c *ServiceUsersCommand) Reader() SQLReadOnlyView { return c.reader }
+
+// ServiceUsersCreateUserCommand is passed to method Users.CreateUser
+// collecting the events it emits, which are limited to the events
+// the method is declared to emit.
+type ServiceUsersCreateUserCommand struct {
+	ServiceUsersCommand
+	events []Event
+}
+
+// EmitUserCreated emits event UserCreated
+func (c *ServiceUsersCreateUserCommand) EmitUserCreated(e EventUserCreated) {
+	c.events = append(c.events, e)
+}
+
+// Events returns the emitted events in the order of emission
+func (c *ServiceUsersCreateUserCommand) Events() []Event { return c.events }
+
+// readOnlyView returns the read-only view of trx.
+// The transaction type of the service must implement ReadOnlyView
+// returning SQLReadOnlyView.
+func (s *ServiceUsers) readOnlyView(trx *SQLTransaction) SQLReadOnlyView {
+	return trx.ReadOnlyView()
+}
+
+// withTenant binds the tenant the instance is hosted for, if any,
+// to ctx making it available to the store handler
+// and the method caller through TenantFromContext
+func (s *ServiceUsers) withTenant(ctx context.Context) context.Context {
+	if s.tenant == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyTenant{}, *s.tenant)
+}
+
+// NewServiceUsers creates a new instance of the Users service.
+func NewServiceUsers(
+	methodCaller ServiceUsersMethodCaller,
+	storeHandler ServiceUsersStoreHandler,
+	eventLogger EventLogger,
+	errorLogger Logger,
+	options ServiceOptions,
+) *ServiceUsers {
+	if methodCaller == nil {
+		panic("methodCaller is nil in NewServiceUsers")
+	}
+	if storeHandler == nil {
+		panic("storeHandler is nil in NewServiceUsers")
+	}
+	if eventLogger == nil {
+		panic("eventLogger is nil in NewServiceUsers")
+	}
+	if options.FailurePolicy == FailurePolicySkip &&
+		options.DeadLetterSink == nil {
+		panic("options.DeadLetterSink is nil in NewServiceUsers")
+	}
+	if options.RequiresSavepointer() &&
+		!runtime.ImplementsSavepointer[*SQLTransaction]() {
+		panic("options require transactions implementing " +
+			"StoreTransactionSavepointer in NewServiceUsers")
+	}
+	if errorLogger == nil {
+		errorLogger = defaultLogErr
+	}
+	options.SetDefaults()
+	s := newServiceUsers(
+		methodCaller, storeHandler, eventLogger, errorLogger, options,
+	)
+	if snapshotter, ok := storeHandler.(ServiceUsersSnapshotter); ok {
+		s.engine.Snapshotter = snapshotter
+	}
+	return s
+}
+
+// newServiceUsers binds a new instance of the Users service
+// to the runtime service engine
+func newServiceUsers(
+	methodCaller ServiceUsersMethodCaller,
+	storeHandler ServiceUsersStoreHandler,
+	eventLogger EventLogger,
+	errorLogger Logger,
+	options ServiceOptions,
+) *ServiceUsers {
+	s := &ServiceUsers{
+		methods:  methodCaller,
+		store:    storeHandler,
+		eventlog: eventLogger,
+		logErr:   errorLogger,
+	}
+	s.engine = &runtime.Service[*SQLTransaction, Event]{
+		Name: "Users",
+		Projections: Projections{
+			ProjectionNameUser,
+		},
+		EventLog:     eventLogger,
+		Store:        storeHandler,
+		Options:      options,
+		IsSubscribed: s.isSubscribed,
+		DecodeEvent:  DecodeEventJSON,
+		ApplyEvent:   s.applyEvent,
+	}
+	return s
+}
+
+// ProjectionVersion returns the current projection version.
+// Returns a LaggingErr if the projections are at different versions.
+func (s *ServiceUsers) ProjectionVersion(ctx context.Context) (
+	EventlogVersion,
+	error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err := storeTransactionErr(txn); err != nil {
+		return "", err
+	}
+
+	return s.engine.ProjectionVersion(ctx, txn)
+}
+
+// ProjectionVersions returns the current version of each projection
+// of service Users. The version of projections that weren't
+// initialized yet is empty.
+func (s *ServiceUsers) ProjectionVersions(ctx context.Context) (
+	map[ProjectionName]EventlogVersion,
+	error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err := storeTransactionErr(txn); err != nil {
+		return nil, err
+	}
+
+	return s.engine.ProjectionVersions(ctx, txn)
+}
+
+// LaggingProjections returns the projections of service Users
+// lagging behind the projection furthest ahead, which are backfilled
+// during the next synchronization.
+// Returns nil if all projections are at the same version.
+func (s *ServiceUsers) LaggingProjections(ctx context.Context) (
+	Projections,
+	error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err := storeTransactionErr(txn); err != nil {
+		return nil, err
+	}
+
+	return s.engine.Lagging(ctx, txn)
+}
+
+// Sync synchronizes service Users against the eventlog.
+// If the projection wasn't initialized yet and the store handler
+// implements ServiceUsersSnapshotter then the latest snapshot is loaded
+// before scanning.
+// Sync will scan events until it reaches the tip of the event log and
+// always return the latest version of the event log it managed to reach,
+// unless the returned error is not equal context.Canceled or
+// context.DeadlineExceeded and didn't pass isErrAcceptable (if not nil).
+func (s *ServiceUsers) Sync(
+	ctx context.Context,
+	isErrAcceptable func(error) bool,
+) (
+	latestVersion EventlogVersion,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReadWriter()
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) ||
+			(isErrAcceptable != nil && isErrAcceptable(err)) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
+
+	return s.engine.Sync(ctx, txn)
+}
+
+// isSubscribed peeks the type of the given encoded event
+// and returns true if the service is subscribed to it.
+// Returns an UnknownEventTypeErr for unknown event types
+// if the UnknownEvents option is UnknownEventFail.
+func (s *ServiceUsers) isSubscribed(payload []byte) (bool, error) {
+	typeName, err := DecodeEventTypeName(payload)
+	if err != nil {
+		return false, err
+	}
+	switch typeName {
+	case "UserCreated":
+		return true, nil
+	}
+	if s.engine.Options.UnknownEvents == UnknownEventFail &&
+		!IsEventTypeKnown(typeName) {
+		return false, UnknownEventTypeErr(fmt.Sprintf(
+			"unknown event type %s", typeName,
+		))
+	}
+	return false, nil
+}
 
-	// NewTransactionReader creates a new read-only transaction handler.
-	// The returned transaction is passed to implementation methods
-	// and will eventually be completed.
-	NewTransactionReader() StoreTransactionReader
+// applyEvent applies the given event to the given projections.
+// Events the service isn't subscribed to are ignored.
+func (s *ServiceUsers) applyEvent(
+	ctx context.Context,
+	trx *SQLTransaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev Event,
+) error {
+	switch v := ev.(type) {
+	case EventUserCreated:
+		return s.applyUserCreated(ctx, trx, projections, version, tm, v)
+	}
+	return nil
+}
 
-	// ProjectionVersion returns the current projection version.
-	// Returns an empty string if the projection wasn't initialized yet.
-	// In case an empty string is returned the service will fallback
-	// to the begin offset version of the eventlog.
-	ProjectionVersion(
-		context.Context,
-		TransactionReader,
-	) (EventlogVersion, error)
+// applyUserCreated applies event UserCreated
+// to the given projections
+func (s *ServiceUsers) applyUserCreated(
+	ctx context.Context,
+	trx *SQLTransaction,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev EventUserCreated,
+) error {
+	if projections.Has(ProjectionNameUser) {
+		if err := s.store.ApplyEventUserCreatedToUser(
+			ctx, trx, version, tm, ev,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// UpdateProjectionVersion explicitly sets the
-	// projection version of the store
-	UpdateProjectionVersion(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-	) error
+// Reprocess applies a dead-lettered event to the projection of
+// service Users again, which is useful once the cause of
+// the failure is fixed. The projection version remains unchanged.
+func (s *ServiceUsers) Reprocess(
+	ctx context.Context,
+	deadLetter DeadLetter,
+) (err error) {
+	ctx = s.withTenant(ctx)
+	txn := s.store.NewTransactionReadWriter()
+	defer func() {
+		if err == nil {
+			err = commitStoreTransaction(txn)
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
 
-	// ApplyEventTicketClosed applies event TicketClosed to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventTicketClosed(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventTicketClosed,
-	) error
+	return s.engine.Reprocess(ctx, txn, deadLetter)
+}
 
-	// ApplyEventTicketCommented applies event TicketCommented to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventTicketCommented(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventTicketCommented,
-	) error
+// Rebuild rebuilds the projection of service Users by replaying
+// the event log into a shadow store created by the store handler
+// and atomically swapping the current projection with the rebuilt one
+// once it has caught up. Requires the store handler to implement
+// ServiceUsersRebuilder. The service remains available during the rebuild.
+// Returns the projection version reached after the swap.
+func (s *ServiceUsers) Rebuild(
+	ctx context.Context,
+	options RebuildOptions,
+) (EventlogVersion, error) {
+	ctx = s.withTenant(ctx)
+	rebuilder, ok := s.store.(ServiceUsersRebuilder)
+	if !ok {
+		return "", fmt.Errorf(
+			"store handler of service Users " +
+				"doesn't implement ServiceUsersRebuilder",
+		)
+	}
 
-	// ApplyEventTicketCreated applies event TicketCreated to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventTicketCreated(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventTicketCreated,
-	) error
+	shadowStore, err := rebuilder.NewShadowStore(ctx)
+	if err != nil {
+		return "", fmt.Errorf("creating shadow store: %w", err)
+	}
 
-	// ApplyEventTicketDescriptionChanged applies event TicketDescriptionChanged to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventTicketDescriptionChanged(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventTicketDescriptionChanged,
-	) error
+	shadow := newServiceUsers(
+		s.methods, shadowStore, s.eventlog, s.logErr, s.engine.Options,
+	)
+	return s.engine.Rebuild(
+		ctx,
+		shadow.engine,
+		options,
+		func(fn func(*SQLTransaction) error) error {
+			return transactServiceUsers(shadowStore, fn)
+		},
+		func(fn func(*SQLTransaction) error) error {
+			return transactServiceUsers(s.store, fn)
+		},
+		func(ctx context.Context, trx *SQLTransaction) error {
+			return rebuilder.SwapStore(ctx, trx, shadowStore)
+		},
+	)
+}
 
-	// ApplyEventTicketTitleChanged applies event TicketTitleChanged to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventTicketTitleChanged(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventTicketTitleChanged,
-	) error
+// transactServiceUsers invokes fn within a new read-write transaction
+// of the given store handler committing the transaction if fn succeeds
+// and rolling it back otherwise
+func transactServiceUsers(
+	store ServiceUsersStoreHandler,
+	fn func(*SQLTransaction) error,
+) (err error) {
+	txn := store.NewTransactionReadWriter()
+	defer func() {
+		if err == nil {
+			err = commitStoreTransaction(txn)
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	return fn(txn)
+}
 
-	// ApplyEventUserAssignedToTicket applies event UserAssignedToTicket to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventUserAssignedToTicket(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventUserAssignedToTicket,
-	) error
+func (s *ServiceUsers) CreateUser(
+	ctx context.Context,
+	input srcticketsserviceusersio.CreateUserIn,
+) (
+	output srcticketsserviceusersio.CreateUserOut,
+	events []Event,
+	eventsPushTime time.Time,
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	var outZero srcticketsserviceusersio.CreateUserOut
+	var eventsJSON []byte
+	var eventsStreams []StreamID
 
-	// ApplyEventUserCreated applies event UserCreated to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventUserCreated(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventUserCreated,
-	) error
+	defer func() {
+		if err != nil {
+			output = outZero
+			events = nil
+			eventsJSON = nil
+			eventsStreams = nil
+			eventsPushTime = time.Time{}
+		}
+	}()
+	txn := s.store.NewTransactionReadWriter()
+	// Committed before the outputs are reset to reset them
+	// if the commit fails
+	defer func() {
+		if err == nil ||
+			errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			if errCommit := commitStoreTransaction(txn); errCommit != nil {
+				err = errCommit
+			}
+		} else {
+			txn.Rollback()
+		}
+	}()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
+	if b, ok := interface{}(txn).(StoreTransactionContextBinder); ok {
+		ctx = b.BindContext(ctx)
+	}
 
-	// ApplyEventUserUnassignedFromTicket applies event UserUnassignedFromTicket to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventUserUnassignedFromTicket(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventUserUnassignedFromTicket,
-	) error
+	exec := func() (ok bool) {
+		cmd := &ServiceUsersCreateUserCommand{
+			ServiceUsersCommand: ServiceUsersCommand{reader: s.readOnlyView(txn)},
+		}
+		output, err = s.methods.CreateUser(ctx, cmd, input)
+		if err != nil {
+			return false
+		}
+		events = cmd.events
+		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
+			return false
+		}
+		eventsStreams = GetEventStreamIDs(events...)
+		return true
+	}
+
+	err = s.engine.Transact(ctx, txn, "Users.CreateUser", func(
+		version EventlogVersion,
+	) (conflict bool, _ error) {
+		if !exec() {
+			return false, err
+		}
+		_, _, eventsPushTime, err = s.eventlog.AppendCheckJSON(
+			ctx, version, eventsStreams, eventsJSON,
+		)
+		return s.eventlog.IsMismatchingVersionsErr(err), err
+	})
+
+	if err != nil {
+		return
+	}
+	if s.engine.Options.SyncAfterPush == Enabled && len(events) > 0 {
+		_, err = s.engine.Sync(ctx, txn)
+	}
+
+	return
 }
 
-// ServiceTicketsMethodCaller represents an implementation
-// of the service Tickets
-type ServiceTicketsMethodCaller interface {
+func (s *ServiceUsers) GetUserByID(
+	ctx context.Context,
+	input srcticketsid.User,
+) (
+	output srcticketsserviceusersio.GetUserByIDOut,
+	// No events
+	err error,
+) {
+	ctx = s.withTenant(ctx)
+	var outZero srcticketsserviceusersio.GetUserByIDOut
 
-	// AssignUserToTicket represents method Tickets.AssignUserToTicket
-	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	AssignUserToTicket(
-		context.Context,
-		TransactionReader,
-		srcticketsserviceticketsio.AssignUserToTicketIn,
-	) (
-		// No output
-		events []Event,
-		err error,
-	)
+	defer func() {
+		if err != nil {
+			output = outZero
+			// No events to reset
+		}
+	}()
+	txn := s.store.NewTransactionReader()
+	defer txn.Complete()
+	if err = storeTransactionErr(txn); err != nil {
+		return
+	}
 
-	// CloseTicket represents method Tickets.CloseTicket
-	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	CloseTicket(
-		context.Context,
-		TransactionReader,
-		srcticketsserviceticketsio.CloseTicketIn,
-	) (
-		// No output
-		events []Event,
-		err error,
-	)
+	exec := func() (ok bool) {
+		output, err = s.methods.GetUserByID(ctx, txn.ReadOnlyView(), input)
+		if err != nil {
+			return false
+		}
 
-	// CreateComment represents method Tickets.CreateComment
-	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	CreateComment(
-		context.Context,
-		TransactionReader,
-		srcticketsserviceticketsio.CreateCommentIn,
-	) (
-		output srcticketsserviceticketsio.CreateCommentOut,
-		events []Event,
-		err error,
-	)
+		return true
+	}
 
-	// CreateTicket represents method Tickets.CreateTicket
-	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	CreateTicket(
-		context.Context,
-		TransactionReader,
-		srcticketsserviceticketsio.CreateTicketIn,
-	) (
-		output srcticketsserviceticketsio.CreateTicketOut,
-		events []Event,
-		err error,
-	)
+	exec()
+
+	return
+}
+
+/* TENANTS */
+
+// TenantID identifies a tenant
+type TenantID = srcticketsid.Tenant
+
+// ctxKeyTenant is the context key of the tenant
+type ctxKeyTenant struct{}
+
+// TenantFromContext returns the tenant the service instance
+// invoking a store handler or a method caller is hosted for.
+// Returns false if the service instance isn't hosted per tenant.
+func TenantFromContext(ctx context.Context) (TenantID, bool) {
+	t, ok := ctx.Value(ctxKeyTenant{}).(TenantID)
+	return t, ok
+}
+
+// TenantEventLog returns the view of the given tenant onto
+// an event log shared by multiple tenants.
+// Events of the tenant are appended to and scanned from
+// the streams of the tenant isolating them from other tenants,
+// see eventlog.TenantLog.
+func TenantEventLog(l EventLogger, tenant TenantID) (EventLogger, error) {
+	b, err := json.Marshal(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("encoding tenant: %w", err)
+	}
+	return eventlog.NewTenantLog(l, b)
+}
 
-	// GetTicketByID represents method Tickets.GetTicketByID
-	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	GetTicketByID(
-		context.Context,
-		TransactionReader,
-		srcticketsid.Ticket,
-	) (
-		output srcticketsserviceticketsio.GetTicketByIDOut,
-		// No events
-		err error,
-	)
+// tenantDeadLetterSink records the dead letters of the service instance
+// of a tenant in the sink shared by all tenants setting their tenant
+type tenantDeadLetterSink struct {
+	tenant TenantID
+	sink   DeadLetterSink
+}
 
-	// UnassignUserFromTicket represents method Tickets.UnassignUserFromTicket
-	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	UnassignUserFromTicket(
-		context.Context,
-		TransactionReader,
-		srcticketsserviceticketsio.UnassignUserFromTicketIn,
-	) (
-		// No output
-		events []Event,
-		err error,
-	)
+// RecordDeadLetter implements DeadLetterSink.RecordDeadLetter
+func (s tenantDeadLetterSink) RecordDeadLetter(
+	ctx context.Context,
+	d DeadLetter,
+) error {
+	d.Tenant = s.tenant
+	return s.sink.RecordDeadLetter(ctx, d)
+}
 
-	// UpdateTicket represents method Tickets.UpdateTicket
+// ServiceTicketsTenantProvider provides the method caller
+// and the store handler of service Tickets for each tenant.
+type ServiceTicketsTenantProvider interface {
+	// Tenant returns the method caller and the store handler
+	// of the given tenant. The same store handler may be returned
+	// for multiple tenants since it receives the tenant of every call
+	// through TenantFromContext, in which case it must isolate
+	// the projections of the tenants.
 	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	UpdateTicket(
-		context.Context,
-		TransactionReader,
-		srcticketsserviceticketsio.UpdateTicketIn,
-	) (
-		// No output
-		events []Event,
-		err error,
+	// WARNING: Tenant is expected to be thread-safe.
+	Tenant(context.Context, TenantID) (
+		ServiceTicketsMethodCaller,
+		ServiceTicketsStoreHandler,
+		error,
 	)
 }
 
-// NewServiceTickets creates a new instance of the Tickets service.
-func NewServiceTickets(
-	methodCaller ServiceTicketsMethodCaller,
-	storeHandler ServiceTicketsStoreHandler,
+// ServiceTicketsTenantProviderFunc is a function implementing
+// ServiceTicketsTenantProvider
+type ServiceTicketsTenantProviderFunc func(context.Context, TenantID) (
+	ServiceTicketsMethodCaller,
+	ServiceTicketsStoreHandler,
+	error,
+)
+
+// Tenant implements ServiceTicketsTenantProvider.Tenant
+func (f ServiceTicketsTenantProviderFunc) Tenant(
+	ctx context.Context,
+	tenant TenantID,
+) (ServiceTicketsMethodCaller, ServiceTicketsStoreHandler, error) {
+	return f(ctx, tenant)
+}
+
+// ServiceTicketsTenants hosts an isolated instance of
+// service Tickets for each tenant on top of an event log
+// shared by all tenants, each with its own method caller and store handler.
+type ServiceTicketsTenants struct {
+	provider ServiceTicketsTenantProvider
+	eventlog EventLogger
+	logErr   Logger
+	options  ServiceOptions
+	tenants  runtime.Tenants[TenantID, *ServiceTickets]
+}
+
+// NewServiceTicketsTenants creates a new multi-tenant host
+// of the Tickets service on top of the event log
+// shared by all tenants.
+func NewServiceTicketsTenants(
+	provider ServiceTicketsTenantProvider,
 	eventLogger EventLogger,
 	errorLogger Logger,
 	options ServiceOptions,
-) *ServiceTickets {
-	if methodCaller == nil {
-		panic("methodCaller is nil in NewServiceTickets")
-	}
-	if storeHandler == nil {
-		panic("storeHandler is nil in NewServiceTickets")
+) *ServiceTicketsTenants {
+	if provider == nil {
+		panic("provider is nil in NewServiceTicketsTenants")
 	}
 	if eventLogger == nil {
-		panic("eventLogger is nil in NewServiceTickets")
+		panic("eventLogger is nil in NewServiceTicketsTenants")
 	}
-	if errorLogger == nil {
-		errorLogger = defaultLogErr
-	}
-	options.SetDefaults()
-	return &ServiceTickets{
-		methods:  methodCaller,
-		store:    storeHandler,
+	return &ServiceTicketsTenants{
+		provider: provider,
 		eventlog: eventLogger,
 		logErr:   errorLogger,
 		options:  options,
 	}
 }
 
-// ProjectionVersion returns the current projection version
-func (s *ServiceTickets) ProjectionVersion(ctx context.Context) (
-	EventlogVersion,
-	error,
-) {
-	txn := s.store.NewTransactionReader()
-	defer txn.Complete()
+// Tenant returns the instance of service Tickets of the given tenant
+// creating it on first use. The returned instance only appends to and
+// scans the streams of the tenant and passes the tenant to the store
+// handler and the method caller, see TenantFromContext.
+// Dead letters of the instance are recorded with the tenant
+// in the DeadLetterSink of the options.
+func (t *ServiceTicketsTenants) Tenant(
+	ctx context.Context,
+	tenant TenantID,
+) (*ServiceTickets, error) {
+	return t.tenants.Get(ctx, tenant, func(
+		ctx context.Context,
+	) (*ServiceTickets, error) {
+		l, err := TenantEventLog(t.eventlog, tenant)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"getting event logger of tenant %v: %w", tenant, err,
+			)
+		}
+		m, st, err := t.provider.Tenant(ctx, tenant)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"getting store handler of tenant %v: %w", tenant, err,
+			)
+		}
+		o := t.options
+		if o.DeadLetterSink != nil {
+			o.DeadLetterSink = tenantDeadLetterSink{
+				tenant: tenant,
+				sink:   o.DeadLetterSink,
+			}
+		}
+		s := NewServiceTickets(m, st, l, t.logErr, o)
+		s.tenant = &tenant
+		return s, nil
+	})
+}
 
-	return s.projectionVersion(ctx, txn)
+// Remove removes the instance of service Tickets of the given tenant,
+// which is created anew on next use, including an instance that's
+// still being created. Returns false if there's no instance
+// of the given tenant. The method caller and the store handler
+// of the tenant aren't closed, the provider is responsible
+// for releasing them.
+func (t *ServiceTicketsTenants) Remove(tenant TenantID) bool {
+	return t.tenants.Remove(tenant)
 }
 
-func (s *ServiceTickets) projectionVersion(
-	ctx context.Context,
-	txn TransactionReader,
-) (
-	EventlogVersion,
+// ServiceUsersTenantProvider provides the method caller
+// and the store handler of service Users for each tenant.
+type ServiceUsersTenantProvider interface {
+	// Tenant returns the method caller and the store handler
+	// of the given tenant. The same store handler may be returned
+	// for multiple tenants since it receives the tenant of every call
+	// through TenantFromContext, in which case it must isolate
+	// the projections of the tenants.
+	//
+	// WARNING: Tenant is expected to be thread-safe.
+	Tenant(context.Context, TenantID) (
+		ServiceUsersMethodCaller,
+		ServiceUsersStoreHandler,
+		error,
+	)
+}
+
+// ServiceUsersTenantProviderFunc is a function implementing
+// ServiceUsersTenantProvider
+type ServiceUsersTenantProviderFunc func(context.Context, TenantID) (
+	ServiceUsersMethodCaller,
+	ServiceUsersStoreHandler,
 	error,
-) {
-	v, err := s.store.ProjectionVersion(ctx, txn)
-	if err != nil {
-		return "", fmt.Errorf("reading projection version: %w", err)
+)
+
+// Tenant implements ServiceUsersTenantProvider.Tenant
+func (f ServiceUsersTenantProviderFunc) Tenant(
+	ctx context.Context,
+	tenant TenantID,
+) (ServiceUsersMethodCaller, ServiceUsersStoreHandler, error) {
+	return f(ctx, tenant)
+}
+
+// ServiceUsersTenants hosts an isolated instance of
+// service Users for each tenant on top of an event log
+// shared by all tenants, each with its own method caller and store handler.
+type ServiceUsersTenants struct {
+	provider ServiceUsersTenantProvider
+	eventlog EventLogger
+	logErr   Logger
+	options  ServiceOptions
+	tenants  runtime.Tenants[TenantID, *ServiceUsers]
+}
+
+// NewServiceUsersTenants creates a new multi-tenant host
+// of the Users service on top of the event log
+// shared by all tenants.
+func NewServiceUsersTenants(
+	provider ServiceUsersTenantProvider,
+	eventLogger EventLogger,
+	errorLogger Logger,
+	options ServiceOptions,
+) *ServiceUsersTenants {
+	if provider == nil {
+		panic("provider is nil in NewServiceUsersTenants")
+	}
+	if eventLogger == nil {
+		panic("eventLogger is nil in NewServiceUsersTenants")
 	}
-	if v != "" {
-		return v, nil
+	return &ServiceUsersTenants{
+		provider: provider,
+		eventlog: eventLogger,
+		logErr:   errorLogger,
+		options:  options,
 	}
+}
 
-	// Fallback to the beginning of the eventlog
-	v, err = s.eventlog.Begin(ctx)
-	if err != nil {
-		return "", fmt.Errorf("reading begin offset version: %w", err)
+// Tenant returns the instance of service Users of the given tenant
+// creating it on first use. The returned instance only appends to and
+// scans the streams of the tenant and passes the tenant to the store
+// handler and the method caller, see TenantFromContext.
+// Dead letters of the instance are recorded with the tenant
+// in the DeadLetterSink of the options.
+func (t *ServiceUsersTenants) Tenant(
+	ctx context.Context,
+	tenant TenantID,
+) (*ServiceUsers, error) {
+	return t.tenants.Get(ctx, tenant, func(
+		ctx context.Context,
+	) (*ServiceUsers, error) {
+		l, err := TenantEventLog(t.eventlog, tenant)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"getting event logger of tenant %v: %w", tenant, err,
+			)
+		}
+		m, st, err := t.provider.Tenant(ctx, tenant)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"getting store handler of tenant %v: %w", tenant, err,
+			)
+		}
+		o := t.options
+		if o.DeadLetterSink != nil {
+			o.DeadLetterSink = tenantDeadLetterSink{
+				tenant: tenant,
+				sink:   o.DeadLetterSink,
+			}
+		}
+		s := NewServiceUsers(m, st, l, t.logErr, o)
+		s.tenant = &tenant
+		return s, nil
+	})
+}
+
+// Remove removes the instance of service Users of the given tenant,
+// which is created anew on next use, including an instance that's
+// still being created. Returns false if there's no instance
+// of the given tenant. The method caller and the store handler
+// of the tenant aren't closed, the provider is responsible
+// for releasing them.
+func (t *ServiceUsersTenants) Remove(tenant TenantID) bool {
+	return t.tenants.Remove(tenant)
+}
+
+/* IN-MEMORY STORES */
+
+// inmemTimerID identifies a timer of an in-memory store
+type inmemTimerID struct {
+	Name   string
+	Stream StreamID
+}
+
+// InmemServiceTicketsStore is a thread-safe in-memory
+// ServiceTicketsStoreHandler maintaining
+// the projections of service Tickets in memory.
+// Write transactions are rolled back using the undo log
+// of the inmemstore.Transaction recorded by the changes.
+type InmemServiceTicketsStore struct {
+	store inmemstore.Store
+	state inmemServiceTicketsState
+}
+
+type inmemServiceTicketsState struct {
+	projectionVersions map[ProjectionName]EventlogVersion
+	projectionTicket   *InmemProjectionTicket
+	projectionUser     *InmemProjectionUser
+	timers             map[inmemTimerID]Timer
+}
+
+// NewInmemServiceTicketsStore creates a new empty in-memory store
+// applying events to the projections using the given handlers.
+func NewInmemServiceTicketsStore(
+	handlerTicket ProjectionTicketHandler,
+	handlerUser ProjectionUserHandler,
+) *InmemServiceTicketsStore {
+	if handlerTicket == nil {
+		panic("handlerTicket is nil in NewInmemServiceTicketsStore")
+	}
+	if handlerUser == nil {
+		panic("handlerUser is nil in NewInmemServiceTicketsStore")
+	}
+	return &InmemServiceTicketsStore{
+		state: inmemServiceTicketsState{
+			projectionVersions: map[ProjectionName]EventlogVersion{},
+			projectionTicket:   NewInmemProjectionTicket(handlerTicket),
+			projectionUser:     NewInmemProjectionUser(handlerUser),
+			timers:             map[inmemTimerID]Timer{},
+		},
 	}
-	return v, nil
 }
 
-// Sync synchronizes service Tickets against the eventlog.
-// Sync will scan events until it reaches the tip of the event log and
-// always return the latest version of the event log it managed to reach,
-// unless the returned error is not equal context.Canceled or
-// context.DeadlineExceeded and didn't pass isErrAcceptable (if not nil).
-func (s *ServiceTickets) Sync(
+// ProjectionTicket returns the reader of projection Ticket.
+// The reader must only be used within the transactions of the store.
+func (s *InmemServiceTicketsStore) ProjectionTicket() ProjectionTicketReader {
+	return s.state.projectionTicket
+}
+
+// ProjectionUser returns the reader of projection User.
+// The reader must only be used within the transactions of the store.
+func (s *InmemServiceTicketsStore) ProjectionUser() ProjectionUserReader {
+	return s.state.projectionUser
+}
+
+// NewTransactionReadWriter implements
+// ServiceTicketsStoreHandler.NewTransactionReadWriter
+func (s *InmemServiceTicketsStore) NewTransactionReadWriter() srcticketsstore.Transaction {
+	return s.store.NewTransactionReadWriter()
+}
+
+// NewTransactionReader implements
+// ServiceTicketsStoreHandler.NewTransactionReader
+func (s *InmemServiceTicketsStore) NewTransactionReader() srcticketsstore.Transaction {
+	return s.store.NewTransactionReader()
+}
+
+// ProjectionVersion implements
+// ServiceTicketsStoreHandler.ProjectionVersion
+func (s *InmemServiceTicketsStore) ProjectionVersion(
+	_ context.Context,
+	_ srcticketsstore.Transaction,
+	projection ProjectionName,
+) (EventlogVersion, error) {
+	return s.state.projectionVersions[projection], nil
+}
+
+// UpdateProjectionVersion implements
+// ServiceTicketsStoreHandler.UpdateProjectionVersion
+func (s *InmemServiceTicketsStore) UpdateProjectionVersion(
 	ctx context.Context,
-	isErrAcceptable func(error) bool,
-) (
-	latestVersion EventlogVersion,
-	err error,
-) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) ||
-			(isErrAcceptable != nil && isErrAcceptable(err)) {
-			txn.Commit()
+	trx srcticketsstore.Transaction,
+	projection ProjectionName,
+	v EventlogVersion,
+) error {
+	p, ok := s.state.projectionVersions[projection]
+	inmemstore.Writer(trx).OnRollback(func() {
+		if ok {
+			s.state.projectionVersions[projection] = p
 		} else {
-			txn.Rollback()
+			delete(s.state.projectionVersions, projection)
 		}
-	}()
+	})
+	s.state.projectionVersions[projection] = v
+	return nil
+}
 
-	return s.sync(ctx, txn)
+// NewShadowStore implements ServiceTicketsRebuilder.NewShadowStore
+func (s *InmemServiceTicketsStore) NewShadowStore(
+	context.Context,
+) (ServiceTicketsStoreHandler, error) {
+	return NewInmemServiceTicketsStore(
+		s.state.projectionTicket.handler,
+		s.state.projectionUser.handler,
+	), nil
 }
 
-func (s *ServiceTickets) sync(
+// SwapStore implements ServiceTicketsRebuilder.SwapStore.
+// The swap is reverted when trx is rolled back.
+// The shadow store must not be used after the swap.
+func (s *InmemServiceTicketsStore) SwapStore(
 	ctx context.Context,
-	trx TransactionWriter,
-) (
-	latestVersion EventlogVersion,
-	err error,
-) {
-	initialVersion, err := s.projectionVersion(ctx, trx)
-	if err != nil {
+	trx srcticketsstore.Transaction,
+	shadow ServiceTicketsStoreHandler,
+) error {
+	sh, ok := shadow.(*InmemServiceTicketsStore)
+	if !ok {
+		return fmt.Errorf("unexpected shadow store type: %T", shadow)
+	}
+	w := inmemstore.Writer(trx)
+	r := sh.store.NewTransactionReader()
+	defer r.Complete()
+
+	// The projections are copied in place keeping their readers valid
+	projectionVersions := s.state.projectionVersions
+	projectionTicket := *s.state.projectionTicket
+	projectionUser := *s.state.projectionUser
+	timers := s.state.timers
+	w.OnRollback(func() {
+		s.state.projectionVersions = projectionVersions
+		*s.state.projectionTicket = projectionTicket
+		*s.state.projectionUser = projectionUser
+		s.state.timers = timers
+	})
+	s.state.projectionVersions = sh.state.projectionVersions
+	*s.state.projectionTicket = *sh.state.projectionTicket
+	*s.state.projectionUser = *sh.state.projectionUser
+	s.state.timers = sh.state.timers
+	return nil
+}
+
+// ProjectionTicketState implements
+// ServiceTicketsStoreHandler.ProjectionTicketState
+func (s *InmemServiceTicketsStore) ProjectionTicketState(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	key srcticketsid.Ticket,
+) (ProjectionTicketState, error) {
+	p, ok, err := s.state.projectionTicket.GetByID(
+		ctx, inmemstore.Reader(trx), key,
+	)
+	if err != nil || !ok {
 		return "", err
 	}
+	return p.State(), nil
+}
 
-	var appliedVersion EventlogVersion
-	defer func() {
-		if appliedVersion == latestVersion {
-			return
-		}
-		err = s.store.UpdateProjectionVersion(ctx, trx, latestVersion)
-		if err != nil {
-			latestVersion = appliedVersion
-		}
-	}()
+// ScheduleTimer implements ServiceTicketsStoreHandler.ScheduleTimer
+func (s *InmemServiceTicketsStore) ScheduleTimer(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	t Timer,
+) error {
+	s.setTimer(inmemstore.Writer(trx), inmemTimerID{t.Name, t.Stream}, &t)
+	return nil
+}
 
-	if err := s.eventlog.Scan(
-		ctx,
-		initialVersion,
-		0, // No limit
-		func(
-			offset EventlogVersion,
-			tm time.Time,
-			payload []byte,
-			next EventlogVersion,
-		) error {
-			ev, err := DecodeEventJSON(payload)
-			if err != nil {
-				return err
-			}
-			switch v := ev.(type) {
-			case EventTicketClosed:
-				if err := s.store.ApplyEventTicketClosed(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			case EventTicketCommented:
-				if err := s.store.ApplyEventTicketCommented(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			case EventTicketCreated:
-				if err := s.store.ApplyEventTicketCreated(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			case EventTicketDescriptionChanged:
-				if err := s.store.ApplyEventTicketDescriptionChanged(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			case EventTicketTitleChanged:
-				if err := s.store.ApplyEventTicketTitleChanged(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			case EventUserAssignedToTicket:
-				if err := s.store.ApplyEventUserAssignedToTicket(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			case EventUserCreated:
-				if err := s.store.ApplyEventUserCreated(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			case EventUserUnassignedFromTicket:
-				if err := s.store.ApplyEventUserUnassignedFromTicket(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			}
-			latestVersion = next
-			return nil
-		},
-	); err != nil {
-		if s.eventlog.IsOffsetOutOfBoundErr(err) {
-			return latestVersion, nil
+// CancelTimer implements ServiceTicketsStoreHandler.CancelTimer
+func (s *InmemServiceTicketsStore) CancelTimer(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	name string,
+	stream StreamID,
+) error {
+	s.setTimer(inmemstore.Writer(trx), inmemTimerID{name, stream}, nil)
+	return nil
+}
+
+// setTimer sets the timer identified by id to t, removes it if t is nil
+// and records the undo of the change in trx
+func (s *InmemServiceTicketsStore) setTimer(
+	trx *inmemstore.Transaction,
+	id inmemTimerID,
+	t *Timer,
+) {
+	if p, ok := s.state.timers[id]; ok {
+		trx.OnRollback(func() { s.state.timers[id] = p })
+	} else {
+		trx.OnRollback(func() { delete(s.state.timers, id) })
+	}
+	if t == nil {
+		delete(s.state.timers, id)
+		return
+	}
+	s.state.timers[id] = *t
+}
+
+// DueTimers implements ServiceTicketsStoreHandler.DueTimers
+func (s *InmemServiceTicketsStore) DueTimers(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	now time.Time,
+) ([]Timer, error) {
+	var l []Timer
+	for _, t := range s.state.timers {
+		if !t.Deadline.After(now) {
+			l = append(l, t)
 		}
-		return "", err
 	}
-	return latestVersion, nil
+	sort.Slice(l, func(i, j int) bool {
+		if !l[i].Deadline.Equal(l[j].Deadline) {
+			return l[i].Deadline.Before(l[j].Deadline)
+		}
+		if l[i].Stream != l[j].Stream {
+			return l[i].Stream < l[j].Stream
+		}
+		return l[i].Name < l[j].Name
+	})
+	return l, nil
+}
+
+// ApplyEventTicketAutoClosedToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventTicketAutoClosedToTicket
+func (s *InmemServiceTicketsStore) ApplyEventTicketAutoClosedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketAutoClosed,
+) error {
+	return s.state.projectionTicket.ApplyEventTicketAutoClosed(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventTicketClosedToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventTicketClosedToTicket
+func (s *InmemServiceTicketsStore) ApplyEventTicketClosedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketClosed,
+) error {
+	return s.state.projectionTicket.ApplyEventTicketClosed(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventTicketCommentedToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventTicketCommentedToTicket
+func (s *InmemServiceTicketsStore) ApplyEventTicketCommentedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketCommented,
+) error {
+	return s.state.projectionTicket.ApplyEventTicketCommented(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventTicketCreatedToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventTicketCreatedToTicket
+func (s *InmemServiceTicketsStore) ApplyEventTicketCreatedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketCreated,
+) error {
+	return s.state.projectionTicket.ApplyEventTicketCreated(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventTicketDescriptionChangedToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventTicketDescriptionChangedToTicket
+func (s *InmemServiceTicketsStore) ApplyEventTicketDescriptionChangedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketDescriptionChanged,
+) error {
+	return s.state.projectionTicket.ApplyEventTicketDescriptionChanged(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventTicketTitleChangedToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventTicketTitleChangedToTicket
+func (s *InmemServiceTicketsStore) ApplyEventTicketTitleChangedToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventTicketTitleChanged,
+) error {
+	return s.state.projectionTicket.ApplyEventTicketTitleChanged(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventUserAssignedToTicketToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventUserAssignedToTicketToTicket
+func (s *InmemServiceTicketsStore) ApplyEventUserAssignedToTicketToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventUserAssignedToTicket,
+) error {
+	return s.state.projectionTicket.ApplyEventUserAssignedToTicket(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventUserCreatedToUser implements
+// ServiceTicketsStoreHandler.ApplyEventUserCreatedToUser
+func (s *InmemServiceTicketsStore) ApplyEventUserCreatedToUser(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventUserCreated,
+) error {
+	return s.state.projectionUser.ApplyEventUserCreated(
+		ctx, trx, v, tm, e,
+	)
+}
+
+// ApplyEventUserUnassignedFromTicketToTicket implements
+// ServiceTicketsStoreHandler.ApplyEventUserUnassignedFromTicketToTicket
+func (s *InmemServiceTicketsStore) ApplyEventUserUnassignedFromTicketToTicket(
+	ctx context.Context,
+	trx srcticketsstore.Transaction,
+	v EventlogVersion,
+	tm time.Time,
+	e EventUserUnassignedFromTicket,
+) error {
+	return s.state.projectionTicket.ApplyEventUserUnassignedFromTicket(
+		ctx, trx, v, tm, e,
+	)
+}
+
+/* SQL STORES */
+
+// SQLTransaction is the database/sql based store transaction
+// of the generated SQL store handlers, which is either
+// a read-write transaction satisfying StoreTransactionReadWriter
+// or a read-only transaction satisfying StoreTransactionReader.
+// Transactions that failed to begin or to commit report the error
+// through Err, which makes the service method fail.
+type SQLTransaction struct {
+	Tx       *sql.Tx
+	db       *sql.DB
+	readOnly bool
+	err      error
+	logErr   Logger
+}
+
+// SQLReadOnlyView is the read-only view of an SQLTransaction passed
+// to the command, timer and read-only methods of services with
+// an SQL store. It only allows queries and can't be committed,
+// rolled back or completed.
+type SQLReadOnlyView struct{ tx *sql.Tx }
+
+// QueryContext executes a query within the transaction,
+// see sql.Tx.QueryContext
+func (v SQLReadOnlyView) QueryContext(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) (*sql.Rows, error) {
+	return v.tx.QueryContext(ctx, query, args...)
 }
 
-func (s *ServiceTickets) AssignUserToTicket(
+// QueryRowContext executes a query within the transaction
+// returning at most one row, see sql.Tx.QueryRowContext
+func (v SQLReadOnlyView) QueryRowContext(
 	ctx context.Context,
-	input srcticketsserviceticketsio.AssignUserToTicketIn,
-) (
-	// No output
-	events []Event,
-	eventsPushTime time.Time,
-	err error,
-) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+	query string,
+	args ...interface{},
+) *sql.Row {
+	return v.tx.QueryRowContext(ctx, query, args...)
+}
 
-	var eventsJSON []byte
+// ReadOnlyView implements StoreTransactionViewer.ReadOnlyView
+// returning a read-only view sharing the transaction.
+func (t *SQLTransaction) ReadOnlyView() SQLReadOnlyView {
+	return SQLReadOnlyView{tx: t.Tx}
+}
 
-	defer func() {
-		if err != nil {
-			// No output to reset
-			events = nil
-			eventsJSON = nil
-			eventsPushTime = time.Time{}
-		}
-	}()
+// ReadOnly returns true for read-only transactions
+func (t *SQLTransaction) ReadOnly() bool { return t.readOnly }
 
-	exec := func() (ok bool) {
-		events, err = s.methods.AssignUserToTicket(ctx, txn, input)
-		if err != nil {
-			return false
-		}
-		for i, e := range events {
-			if err = CheckEventType(e); err != nil {
-				err = fmt.Errorf("checking returned event (%d): %w", i, err)
-				return false
-			}
-			switch e.(type) {
-			case EventUserAssignedToTicket:
-			default:
-				panic(fmt.Errorf(
-					"method Tickets.AssignUserToTicket is not allowed to emit event %s",
-					reflect.TypeOf(e),
-				))
-			}
-		}
-		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
-			return false
-		}
-		return true
-	}
+// Err implements StoreTransactionErrorer.Err returning the error
+// the transaction failed to begin or to commit with, if any
+func (t *SQLTransaction) Err() error { return t.err }
 
-	var currentVersion EventlogVersion
-	currentVersion, err = s.projectionVersion(ctx, txn)
-	if err != nil {
+// Commit implements StoreTransactionReadWriter.Commit
+func (t *SQLTransaction) Commit() {
+	if t.err != nil {
 		return
 	}
+	if err := t.Tx.Commit(); err != nil {
+		t.err = fmt.Errorf("committing transaction: %w", err)
+	}
+}
 
-	_, _, eventsPushTime, err = s.eventlog.TryAppendJSON(
-		ctx,
-		currentVersion,
-		func() ([]byte, error) {
-			if !exec() {
-				return nil, err
-			}
-			return eventsJSON, nil
-		},
-		func() (EventlogVersion, error) { return s.sync(ctx, txn) },
-	)
-
-	if err != nil {
+// Rollback implements StoreTransactionReadWriter.Rollback
+func (t *SQLTransaction) Rollback() {
+	if t.Tx == nil {
+		// Failed to begin
 		return
 	}
-	if s.options.SyncAfterPush == Enabled && len(events) > 0 {
-		_, err = s.sync(ctx, txn)
+	if err := t.Tx.Rollback(); err != nil {
+		t.logErr.Printf("rolling back transaction: %s", err)
 	}
+}
 
-	return
+// BindContext implements StoreTransactionContextBinder.BindContext
+// binding the transaction to the context passed to the EventLogger.
+// An sqldb event log sharing the database of the store appends within
+// the transaction, which makes projection updates and appends atomic.
+func (t *SQLTransaction) BindContext(ctx context.Context) context.Context {
+	return sqldb.WithTx(ctx, t.db, t.Tx)
 }
 
-func (s *ServiceTickets) CloseTicket(
-	ctx context.Context,
-	input srcticketsserviceticketsio.CloseTicketIn,
-) (
-	// No output
-	events []Event,
-	eventsPushTime time.Time,
-	err error,
-) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+// Savepoint implements StoreTransactionSavepointer.Savepoint
+func (t *SQLTransaction) Savepoint(ctx context.Context) error {
+	_, err := t.Tx.ExecContext(ctx, `SAVEPOINT goesgen_apply`)
+	return err
+}
 
-	var eventsJSON []byte
+// RollbackToSavepoint implements
+// StoreTransactionSavepointer.RollbackToSavepoint
+func (t *SQLTransaction) RollbackToSavepoint(ctx context.Context) error {
+	_, err := t.Tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT goesgen_apply`)
+	return err
+}
 
-	defer func() {
-		if err != nil {
-			// No output to reset
-			events = nil
-			eventsJSON = nil
-			eventsPushTime = time.Time{}
-		}
-	}()
+// ReleaseSavepoint implements StoreTransactionSavepointer.ReleaseSavepoint
+func (t *SQLTransaction) ReleaseSavepoint(ctx context.Context) error {
+	_, err := t.Tx.ExecContext(ctx, `RELEASE SAVEPOINT goesgen_apply`)
+	return err
+}
 
-	exec := func() (ok bool) {
-		events, err = s.methods.CloseTicket(ctx, txn, input)
-		if err != nil {
-			return false
-		}
-		for i, e := range events {
-			if err = CheckEventType(e); err != nil {
-				err = fmt.Errorf("checking returned event (%d): %w", i, err)
-				return false
-			}
-			switch e.(type) {
-			case EventTicketClosed:
-			default:
-				panic(fmt.Errorf(
-					"method Tickets.CloseTicket is not allowed to emit event %s",
-					reflect.TypeOf(e),
-				))
-			}
-		}
-		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
-			return false
-		}
-		return true
+// Complete implements StoreTransactionReader.Complete
+func (t *SQLTransaction) Complete() {
+	if t.Tx == nil {
+		// Failed to begin
+		return
+	}
+	if err := t.Tx.Commit(); err != nil {
+		t.logErr.Printf("completing read-only transaction: %s", err)
 	}
+}
+
+// SQLServiceUsersStoreDDL lists the statements creating
+// the tables of service Users unless they already exist:
+//
+//	users_projection_versions stores the projection versions
+//	users_user stores projection User
+var SQLServiceUsersStoreDDL = []string{
+	`CREATE TABLE IF NOT EXISTS users_projection_versions (
+		projection TEXT PRIMARY KEY,
+		v TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS users_user (
+		state TEXT NOT NULL,
+		id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		PRIMARY KEY (id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS users_user_name
+		ON users_user (name)`,
+}
+
+// SQLServiceUsersStore implements the transactions and the projection versions
+// of ServiceUsersStoreHandler on top of database/sql
+// leaving only the Apply methods to the embedding store handler,
+// which executes them within the Tx of the given transactions.
+// The queries use $n placeholders and ON CONFLICT upserts, which
+// SQLite and PostgreSQL support while MySQL and drivers expecting
+// ? placeholders aren't supported.
+type SQLServiceUsersStore struct {
+	db     *sql.DB
+	logErr Logger
+}
 
-	var currentVersion EventlogVersion
-	currentVersion, err = s.projectionVersion(ctx, txn)
+// NewSQLServiceUsersStore creates the tables of service Users
+// unless they already exist and returns a new store.
+func NewSQLServiceUsersStore(
+	ctx context.Context,
+	db *sql.DB,
+	errorLogger Logger,
+) (*SQLServiceUsersStore, error) {
+	if db == nil {
+		panic("db is nil in NewSQLServiceUsersStore")
+	}
+	if errorLogger == nil {
+		errorLogger = defaultLogErr
+	}
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return
+		return nil, fmt.Errorf("beginning transaction: %w", err)
 	}
+	defer tx.Rollback()
+	for _, q := range SQLServiceUsersStoreDDL {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return nil, fmt.Errorf("creating tables: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing: %w", err)
+	}
+	return &SQLServiceUsersStore{db: db, logErr: errorLogger}, nil
+}
 
-	_, _, eventsPushTime, err = s.eventlog.TryAppendJSON(
-		ctx,
-		currentVersion,
-		func() ([]byte, error) {
-			if !exec() {
-				return nil, err
-			}
-			return eventsJSON, nil
-		},
-		func() (EventlogVersion, error) { return s.sync(ctx, txn) },
-	)
+// DB returns the database of the store
+func (s *SQLServiceUsersStore) DB() *sql.DB { return s.db }
 
+// NewTransactionReadWriter implements
+// ServiceUsersStoreHandler.NewTransactionReadWriter.
+// A transaction that failed to begin is reported by its Err method.
+func (s *SQLServiceUsersStore) NewTransactionReadWriter() *SQLTransaction {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
 	if err != nil {
-		return
+		err = fmt.Errorf("beginning read-write transaction: %w", err)
+	}
+	return &SQLTransaction{Tx: tx, db: s.db, err: err, logErr: s.logErr}
+}
+
+// NewTransactionReader implements
+// ServiceUsersStoreHandler.NewTransactionReader.
+// A transaction that failed to begin is reported by its Err method.
+func (s *SQLServiceUsersStore) NewTransactionReader() *SQLTransaction {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		ReadOnly: true,
+	})
+	if err != nil {
+		err = fmt.Errorf("beginning read-only transaction: %w", err)
 	}
-	if s.options.SyncAfterPush == Enabled && len(events) > 0 {
-		_, err = s.sync(ctx, txn)
+	return &SQLTransaction{
+		Tx:       tx,
+		db:       s.db,
+		readOnly: true,
+		err:      err,
+		logErr:   s.logErr,
 	}
+}
 
+// ProjectionVersion implements ServiceUsersStoreHandler.ProjectionVersion
+func (s *SQLServiceUsersStore) ProjectionVersion(
+	ctx context.Context,
+	trx *SQLTransaction,
+	projection ProjectionName,
+) (v EventlogVersion, err error) {
+	err = trx.Tx.QueryRowContext(ctx,
+		`SELECT v FROM users_projection_versions
+		WHERE projection = $1`, projection,
+	).Scan(&v)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
 	return
 }
 
-func (s *ServiceTickets) CreateComment(
+// UpdateProjectionVersion implements
+// ServiceUsersStoreHandler.UpdateProjectionVersion
+func (s *SQLServiceUsersStore) UpdateProjectionVersion(
 	ctx context.Context,
-	input srcticketsserviceticketsio.CreateCommentIn,
-) (
-	output srcticketsserviceticketsio.CreateCommentOut,
-	events []Event,
-	eventsPushTime time.Time,
-	err error,
-) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+	trx *SQLTransaction,
+	projection ProjectionName,
+	v EventlogVersion,
+) error {
+	_, err := trx.Tx.ExecContext(ctx,
+		`INSERT INTO users_projection_versions (projection, v)
+		VALUES ($1, $2)
+		ON CONFLICT (projection) DO UPDATE SET v = excluded.v`,
+		projection, v,
+	)
+	return err
+}
 
-	var outZero srcticketsserviceticketsio.CreateCommentOut
-	var eventsJSON []byte
+/* RELAY */
 
-	defer func() {
-		if err != nil {
-			output = outZero
-			events = nil
-			eventsJSON = nil
-			eventsPushTime = time.Time{}
-		}
-	}()
+// CursorStore persists the positions of event log consumers,
+// see runtime.CursorStore
+type CursorStore = runtime.CursorStore
 
-	exec := func() (ok bool) {
-		output, events, err = s.methods.CreateComment(ctx, txn, input)
-		if err != nil {
-			return false
-		}
-		for i, e := range events {
-			if err = CheckEventType(e); err != nil {
-				err = fmt.Errorf("checking returned event (%d): %w", i, err)
-				return false
-			}
-			switch e.(type) {
-			case EventTicketCommented:
-			default:
-				panic(fmt.Errorf(
-					"method Tickets.CreateComment is not allowed to emit event %s",
-					reflect.TypeOf(e),
-				))
-			}
-		}
-		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
-			return false
-		}
-		return true
-	}
+// InmemCursorStore is a thread-safe in-memory CursorStore
+type InmemCursorStore struct {
+	lock    sync.Mutex
+	cursors map[string]EventlogVersion
+}
 
-	var currentVersion EventlogVersion
-	currentVersion, err = s.projectionVersion(ctx, txn)
-	if err != nil {
-		return
-	}
+// NewInmemCursorStore creates a new empty in-memory cursor store
+func NewInmemCursorStore() *InmemCursorStore {
+	return &InmemCursorStore{cursors: map[string]EventlogVersion{}}
+}
 
-	_, _, eventsPushTime, err = s.eventlog.TryAppendJSON(
-		ctx,
-		currentVersion,
-		func() ([]byte, error) {
-			if !exec() {
-				return nil, err
-			}
-			return eventsJSON, nil
-		},
-		func() (EventlogVersion, error) { return s.sync(ctx, txn) },
-	)
+// LoadCursor implements CursorStore.LoadCursor
+func (s *InmemCursorStore) LoadCursor(
+	ctx context.Context,
+	consumer string,
+) (EventlogVersion, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.cursors[consumer], nil
+}
 
-	if err != nil {
-		return
-	}
-	if s.options.SyncAfterPush == Enabled && len(events) > 0 {
-		_, err = s.sync(ctx, txn)
+// SaveCursor implements CursorStore.SaveCursor
+func (s *InmemCursorStore) SaveCursor(
+	ctx context.Context,
+	consumer string,
+	version EventlogVersion,
+) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cursors[consumer] = version
+	return nil
+}
+
+// EventMeta describes an event read from the event log
+type EventMeta struct {
+	// Offset is the offset version of the event
+	Offset EventlogVersion
+
+	// Next is the version following the event
+	Next EventlogVersion
+
+	// Time is the time the event was appended at
+	Time time.Time
+
+	// Stream is the ID of the stream the event belongs to.
+	// Stream is empty if the event doesn't belong to any stream.
+	Stream StreamID
+}
+
+// Publication is an event handed to a Publisher
+type Publication struct {
+	EventMeta
+
+	// Event is the decoded event
+	Event Event
+
+	// Payload is the raw JSON encoded event
+	Payload []byte
+}
+
+// Publisher publishes events to an external system
+// such as a message broker or a webhook.
+type Publisher interface {
+	// Publish publishes the given event.
+	// Events are published in the order of the event log.
+	// The same event may be published more than once if the relay
+	// fails to store its cursor after publishing it.
+	Publish(context.Context, Publication) error
+}
+
+// ChannelPublisher is a Publisher sending publications to a channel,
+// which is useful as a stand-in for external systems in tests.
+type ChannelPublisher chan Publication
+
+// Publish implements Publisher.Publish
+func (p ChannelPublisher) Publish(ctx context.Context, e Publication) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case p <- e:
+		return nil
 	}
+}
 
-	return
+type RelayOptions struct {
+	// MaxAttempts limits the number of times publishing an event
+	// is attempted before Sync gives up.
+	//
+	// MaxAttempts is DefaultMaxAttempts by default.
+	MaxAttempts uint
+
+	// Backoff determines how long to wait before the next attempt
+	// after publishing an event failed.
+	//
+	// Backoff is DefaultBackoff by default.
+	Backoff BackoffStrategy
+
+	// Jitter randomizes the backoff delay.
+	//
+	// Jitter is enabled by default.
+	Jitter Option
+
+	// UnknownEvents defines how Sync handles events of types
+	// that aren't defined by the schema.
+	// Events of unknown types are never published.
+	//
+	// UnknownEvents is UnknownEventIgnore by default.
+	UnknownEvents UnknownEventPolicy
 }
 
-func (s *ServiceTickets) CreateTicket(
-	ctx context.Context,
-	input srcticketsserviceticketsio.CreateTicketIn,
-) (
-	output srcticketsserviceticketsio.CreateTicketOut,
-	events []Event,
-	eventsPushTime time.Time,
-	err error,
-) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+// SetDefaults sets default values to unspecified options
+func (o *RelayOptions) SetDefaults() {
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+	if o.Backoff == nil {
+		o.Backoff = DefaultBackoff
+	}
+	if o.Jitter == Unspecified {
+		o.Jitter = Enabled
+	}
+}
 
-	var outZero srcticketsserviceticketsio.CreateTicketOut
-	var eventsJSON []byte
+// Relay tails the event log from a stored cursor publishing every event
+// with at-least-once delivery. Events are published one at a time in the
+// order of the event log, which preserves the order within every stream.
+type Relay struct {
+	name      string
+	eventlog  EventLogger
+	cursors   CursorStore
+	publisher Publisher
+	options   RelayOptions
+	lock      sync.Mutex
+}
 
-	defer func() {
-		if err != nil {
-			output = outZero
-			events = nil
-			eventsJSON = nil
-			eventsPushTime = time.Time{}
-		}
-	}()
+// NewRelay creates a new relay identified by name in the cursor store.
+func NewRelay(
+	name string,
+	eventLogger EventLogger,
+	cursorStore CursorStore,
+	publisher Publisher,
+	options RelayOptions,
+) *Relay {
+	if eventLogger == nil {
+		panic("eventLogger is nil in NewRelay")
+	}
+	if cursorStore == nil {
+		panic("cursorStore is nil in NewRelay")
+	}
+	if publisher == nil {
+		panic("publisher is nil in NewRelay")
+	}
+	options.SetDefaults()
+	return &Relay{
+		name:      name,
+		eventlog:  eventLogger,
+		cursors:   cursorStore,
+		publisher: publisher,
+		options:   options,
+	}
+}
 
-	exec := func() (ok bool) {
-		output, events, err = s.methods.CreateTicket(ctx, txn, input)
-		if err != nil {
-			return false
-		}
-		for i, e := range events {
-			if err = CheckEventType(e); err != nil {
-				err = fmt.Errorf("checking returned event (%d): %w", i, err)
-				return false
-			}
-			switch e.(type) {
-			case EventTicketCreated:
-			default:
-				panic(fmt.Errorf(
-					"method Tickets.CreateTicket is not allowed to emit event %s",
-					reflect.TypeOf(e),
-				))
-			}
-		}
-		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
-			return false
+// Sync publishes all events appended after the stored cursor
+// advancing the cursor after every published event.
+// Publishing is retried according to the relay options,
+// once out of attempts Sync returns the error and the failed event
+// is retried during the next call.
+// Returns the latest version of the event log the relay reached.
+func (r *Relay) Sync(ctx context.Context) (
+	latestVersion EventlogVersion,
+	err error,
+) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return runtime.Tail(ctx, r.name, r.eventlog, r.cursors, r.publish)
+}
+
+// publish decodes and publishes the given event.
+// Events of unknown types are skipped unless the UnknownEvents
+// option is UnknownEventFail.
+func (r *Relay) publish(
+	ctx context.Context,
+	offset EventlogVersion,
+	tm time.Time,
+	payload []byte,
+	next EventlogVersion,
+) error {
+	typeName, err := DecodeEventTypeName(payload)
+	if err != nil {
+		return err
+	}
+	if !IsEventTypeKnown(typeName) {
+		if r.options.UnknownEvents == UnknownEventFail {
+			return UnknownEventTypeErr(fmt.Sprintf(
+				"unknown event type %s", typeName,
+			))
 		}
-		return true
+		return nil
 	}
-
-	var currentVersion EventlogVersion
-	currentVersion, err = s.projectionVersion(ctx, txn)
+	ev, err := DecodeEventJSON(payload)
 	if err != nil {
-		return
+		return err
 	}
 
-	_, _, eventsPushTime, err = s.eventlog.TryAppendJSON(
-		ctx,
-		currentVersion,
-		func() ([]byte, error) {
-			if !exec() {
-				return nil, err
-			}
-			return eventsJSON, nil
+	p := Publication{
+		EventMeta: EventMeta{
+			Offset: offset,
+			Next:   next,
+			Time:   tm,
+			Stream: GetEventStreamID(ev),
 		},
-		func() (EventlogVersion, error) { return s.sync(ctx, txn) },
+		Event:   ev,
+		Payload: payload,
+	}
+	attempts, err := runtime.Retry(
+		ctx,
+		r.options.MaxAttempts,
+		r.options.Backoff,
+		r.options.Jitter,
+		func() error { return r.publisher.Publish(ctx, p) },
 	)
-
 	if err != nil {
-		return
+		return fmt.Errorf(
+			"publishing event at %s: giving up after %d attempt(s): %w",
+			offset, attempts, err,
+		)
 	}
-	if s.options.SyncAfterPush == Enabled && len(events) > 0 {
-		_, err = s.sync(ctx, txn)
-	}
-
-	return
+	return nil
 }
 
-func (s *ServiceTickets) GetTicketByID(
-	ctx context.Context,
-	input srcticketsid.Ticket,
-) (
-	output srcticketsserviceticketsio.GetTicketByIDOut,
-	// No events
-	err error,
-) {
-	txn := s.store.NewTransactionReader()
-	defer txn.Complete()
+/* CONSUMER */
 
-	var outZero srcticketsserviceticketsio.GetTicketByIDOut
+type ConsumerOptions struct {
+	// MaxAttempts limits the number of times a handler is invoked
+	// for an event before Sync gives up.
+	//
+	// MaxAttempts is DefaultMaxAttempts by default.
+	MaxAttempts uint
 
-	defer func() {
-		if err != nil {
-			output = outZero
-			// No events to reset
-		}
-	}()
+	// Backoff determines how long to wait before the next attempt
+	// after a handler failed.
+	//
+	// Backoff is DefaultBackoff by default.
+	Backoff BackoffStrategy
 
-	exec := func() (ok bool) {
-		output, err = s.methods.GetTicketByID(ctx, txn, input)
-		if err != nil {
-			return false
-		}
+	// Jitter randomizes the backoff delay.
+	//
+	// Jitter is enabled by default.
+	Jitter Option
 
-		return true
+	// UnknownEvents defines how Sync handles events of types
+	// that aren't defined by the schema.
+	//
+	// UnknownEvents is UnknownEventIgnore by default.
+	UnknownEvents UnknownEventPolicy
+}
+
+// SetDefaults sets default values to unspecified options
+func (o *ConsumerOptions) SetDefaults() {
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+	if o.Backoff == nil {
+		o.Backoff = DefaultBackoff
+	}
+	if o.Jitter == Unspecified {
+		o.Jitter = Enabled
 	}
+}
 
-	exec()
+// EventConsumer invokes typed event handlers for events appended
+// after its stored cursor. Unlike services, consumers don't maintain
+// a projection and are suitable for side effects such as
+// sending notifications or feeding search indexes.
+// Handlers are invoked at least once per event
+// in the order of the event log.
+// Events without a registered handler are skipped without being decoded.
+type EventConsumer struct {
+	name     string
+	eventlog EventLogger
+	cursors  CursorStore
+	options  ConsumerOptions
+	lock     sync.Mutex
+
+	onTicketAutoClosed         func(context.Context, EventMeta, EventTicketAutoClosed) error
+	onTicketClosed             func(context.Context, EventMeta, EventTicketClosed) error
+	onTicketCommented          func(context.Context, EventMeta, EventTicketCommented) error
+	onTicketCreated            func(context.Context, EventMeta, EventTicketCreated) error
+	onTicketDescriptionChanged func(context.Context, EventMeta, EventTicketDescriptionChanged) error
+	onTicketTitleChanged       func(context.Context, EventMeta, EventTicketTitleChanged) error
+	onUserAssignedToTicket     func(context.Context, EventMeta, EventUserAssignedToTicket) error
+	onUserCreated              func(context.Context, EventMeta, EventUserCreated) error
+	onUserUnassignedFromTicket func(context.Context, EventMeta, EventUserUnassignedFromTicket) error
+}
 
-	return
+// NewEventConsumer creates a new consumer
+// identified by name in the cursor store.
+func NewEventConsumer(
+	name string,
+	eventLogger EventLogger,
+	cursorStore CursorStore,
+	options ConsumerOptions,
+) *EventConsumer {
+	if eventLogger == nil {
+		panic("eventLogger is nil in NewEventConsumer")
+	}
+	if cursorStore == nil {
+		panic("cursorStore is nil in NewEventConsumer")
+	}
+	options.SetDefaults()
+	return &EventConsumer{
+		name:     name,
+		eventlog: eventLogger,
+		cursors:  cursorStore,
+		options:  options,
+	}
 }
 
-func (s *ServiceTickets) UnassignUserFromTicket(
-	ctx context.Context,
-	input srcticketsserviceticketsio.UnassignUserFromTicketIn,
-) (
-	// No output
-	events []Event,
-	eventsPushTime time.Time,
-	err error,
-) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+// OnTicketAutoClosed registers the handler of event TicketAutoClosed
+// replacing any previously registered one.
+func (c *EventConsumer) OnTicketAutoClosed(
+	handler func(context.Context, EventMeta, EventTicketAutoClosed) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onTicketAutoClosed = handler
+	return c
+}
 
-	var eventsJSON []byte
+// OnTicketClosed registers the handler of event TicketClosed
+// replacing any previously registered one.
+func (c *EventConsumer) OnTicketClosed(
+	handler func(context.Context, EventMeta, EventTicketClosed) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onTicketClosed = handler
+	return c
+}
 
-	defer func() {
-		if err != nil {
-			// No output to reset
-			events = nil
-			eventsJSON = nil
-			eventsPushTime = time.Time{}
-		}
-	}()
+// OnTicketCommented registers the handler of event TicketCommented
+// replacing any previously registered one.
+func (c *EventConsumer) OnTicketCommented(
+	handler func(context.Context, EventMeta, EventTicketCommented) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onTicketCommented = handler
+	return c
+}
 
-	exec := func() (ok bool) {
-		events, err = s.methods.UnassignUserFromTicket(ctx, txn, input)
-		if err != nil {
-			return false
-		}
-		for i, e := range events {
-			if err = CheckEventType(e); err != nil {
-				err = fmt.Errorf("checking returned event (%d): %w", i, err)
-				return false
-			}
-			switch e.(type) {
-			case EventUserUnassignedFromTicket:
-			default:
-				panic(fmt.Errorf(
-					"method Tickets.UnassignUserFromTicket is not allowed to emit event %s",
-					reflect.TypeOf(e),
-				))
-			}
-		}
-		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
-			return false
-		}
-		return true
-	}
+// OnTicketCreated registers the handler of event TicketCreated
+// replacing any previously registered one.
+func (c *EventConsumer) OnTicketCreated(
+	handler func(context.Context, EventMeta, EventTicketCreated) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onTicketCreated = handler
+	return c
+}
 
-	var currentVersion EventlogVersion
-	currentVersion, err = s.projectionVersion(ctx, txn)
-	if err != nil {
-		return
-	}
+// OnTicketDescriptionChanged registers the handler of event TicketDescriptionChanged
+// replacing any previously registered one.
+func (c *EventConsumer) OnTicketDescriptionChanged(
+	handler func(context.Context, EventMeta, EventTicketDescriptionChanged) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onTicketDescriptionChanged = handler
+	return c
+}
 
-	_, _, eventsPushTime, err = s.eventlog.TryAppendJSON(
-		ctx,
-		currentVersion,
-		func() ([]byte, error) {
-			if !exec() {
-				return nil, err
-			}
-			return eventsJSON, nil
-		},
-		func() (EventlogVersion, error) { return s.sync(ctx, txn) },
-	)
+// OnTicketTitleChanged registers the handler of event TicketTitleChanged
+// replacing any previously registered one.
+func (c *EventConsumer) OnTicketTitleChanged(
+	handler func(context.Context, EventMeta, EventTicketTitleChanged) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onTicketTitleChanged = handler
+	return c
+}
 
-	if err != nil {
-		return
-	}
-	if s.options.SyncAfterPush == Enabled && len(events) > 0 {
-		_, err = s.sync(ctx, txn)
-	}
+// OnUserAssignedToTicket registers the handler of event UserAssignedToTicket
+// replacing any previously registered one.
+func (c *EventConsumer) OnUserAssignedToTicket(
+	handler func(context.Context, EventMeta, EventUserAssignedToTicket) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onUserAssignedToTicket = handler
+	return c
+}
 
-	return
+// OnUserCreated registers the handler of event UserCreated
+// replacing any previously registered one.
+func (c *EventConsumer) OnUserCreated(
+	handler func(context.Context, EventMeta, EventUserCreated) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onUserCreated = handler
+	return c
 }
 
-func (s *ServiceTickets) UpdateTicket(
-	ctx context.Context,
-	input srcticketsserviceticketsio.UpdateTicketIn,
-) (
-	// No output
-	events []Event,
-	eventsPushTime time.Time,
+// OnUserUnassignedFromTicket registers the handler of event UserUnassignedFromTicket
+// replacing any previously registered one.
+func (c *EventConsumer) OnUserUnassignedFromTicket(
+	handler func(context.Context, EventMeta, EventUserUnassignedFromTicket) error,
+) *EventConsumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onUserUnassignedFromTicket = handler
+	return c
+}
+
+// Sync invokes the registered handlers for all events appended after
+// the stored cursor advancing the cursor after every event.
+// Failed handlers are retried according to the consumer options,
+// once out of attempts Sync returns the error and the failed event
+// is retried during the next call.
+// Returns the latest version of the event log the consumer reached.
+func (c *EventConsumer) Sync(ctx context.Context) (
+	latestVersion EventlogVersion,
 	err error,
 ) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-	var eventsJSON []byte
+	return runtime.Tail(ctx, c.name, c.eventlog, c.cursors, c.handle)
+}
 
-	defer func() {
+// handle invokes the handler registered for the given event, if any
+func (c *EventConsumer) handle(
+	ctx context.Context,
+	offset EventlogVersion,
+	tm time.Time,
+	payload []byte,
+	next EventlogVersion,
+) error {
+	typeName, err := DecodeEventTypeName(payload)
+	if err != nil {
+		return err
+	}
+
+	var handle func() error
+	switch typeName {
+	case "TicketAutoClosed":
+		if c.onTicketAutoClosed == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
 		if err != nil {
-			// No output to reset
-			events = nil
-			eventsJSON = nil
-			eventsPushTime = time.Time{}
+			return err
 		}
-	}()
-
-	exec := func() (ok bool) {
-		events, err = s.methods.UpdateTicket(ctx, txn, input)
+		h, v := c.onTicketAutoClosed, ev.(EventTicketAutoClosed)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	case "TicketClosed":
+		if c.onTicketClosed == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
 		if err != nil {
-			return false
+			return err
 		}
-		for i, e := range events {
-			if err = CheckEventType(e); err != nil {
-				err = fmt.Errorf("checking returned event (%d): %w", i, err)
-				return false
-			}
-			switch e.(type) {
-			case EventTicketDescriptionChanged:
-			case EventTicketTitleChanged:
-			default:
-				panic(fmt.Errorf(
-					"method Tickets.UpdateTicket is not allowed to emit event %s",
-					reflect.TypeOf(e),
-				))
-			}
+		h, v := c.onTicketClosed, ev.(EventTicketClosed)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
 		}
-		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
-			return false
+	case "TicketCommented":
+		if c.onTicketCommented == nil {
+			return nil
 		}
-		return true
-	}
-
-	var currentVersion EventlogVersion
-	currentVersion, err = s.projectionVersion(ctx, txn)
-	if err != nil {
-		return
-	}
-
-	_, _, eventsPushTime, err = s.eventlog.TryAppendJSON(
-		ctx,
-		currentVersion,
-		func() ([]byte, error) {
-			if !exec() {
-				return nil, err
-			}
-			return eventsJSON, nil
-		},
-		func() (EventlogVersion, error) { return s.sync(ctx, txn) },
-	)
-
-	if err != nil {
-		return
-	}
-	if s.options.SyncAfterPush == Enabled && len(events) > 0 {
-		_, err = s.sync(ctx, txn)
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
+		if err != nil {
+			return err
+		}
+		h, v := c.onTicketCommented, ev.(EventTicketCommented)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	case "TicketCreated":
+		if c.onTicketCreated == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
+		if err != nil {
+			return err
+		}
+		h, v := c.onTicketCreated, ev.(EventTicketCreated)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	case "TicketDescriptionChanged":
+		if c.onTicketDescriptionChanged == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
+		if err != nil {
+			return err
+		}
+		h, v := c.onTicketDescriptionChanged, ev.(EventTicketDescriptionChanged)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	case "TicketTitleChanged":
+		if c.onTicketTitleChanged == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
+		if err != nil {
+			return err
+		}
+		h, v := c.onTicketTitleChanged, ev.(EventTicketTitleChanged)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	case "UserAssignedToTicket":
+		if c.onUserAssignedToTicket == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
+		if err != nil {
+			return err
+		}
+		h, v := c.onUserAssignedToTicket, ev.(EventUserAssignedToTicket)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	case "UserCreated":
+		if c.onUserCreated == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
+		if err != nil {
+			return err
+		}
+		h, v := c.onUserCreated, ev.(EventUserCreated)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	case "UserUnassignedFromTicket":
+		if c.onUserUnassignedFromTicket == nil {
+			return nil
+		}
+		// Decoding failures aren't retried
+		ev, err := DecodeEventJSON(payload)
+		if err != nil {
+			return err
+		}
+		h, v := c.onUserUnassignedFromTicket, ev.(EventUserUnassignedFromTicket)
+		handle = func() error {
+			return h(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, v)
+		}
+	default:
+		if c.options.UnknownEvents == UnknownEventFail {
+			return UnknownEventTypeErr(fmt.Sprintf(
+				"unknown event type %s", typeName,
+			))
+		}
+		return nil
 	}
 
-	return
-}
-
-// ServiceUsers projects the following entities:
-//  User
-// therefore, Users subscribes to the following events:
-type ServiceUsers struct {
-	eventlog EventLogger
-	logErr   Logger
-	methods  ServiceUsersMethodCaller
-	store    ServiceUsersStoreHandler
-	options  ServiceOptions
-}
-
-// ServiceUsersStoreHandler represents a store handler implementation
-// of the service Users
-type ServiceUsersStoreHandler interface {
-	// NewTransactionReadWriter creates a new exclusive
-	// read-write transaction handler.
-	// The returned transaction is passed to implementation methods
-	// and will eventually be either committed or rolled back respectively.
-	NewTransactionReadWriter() StoreTransactionReadWriter
-
-	// NewTransactionReader creates a new read-only transaction handler.
-	// The returned transaction is passed to implementation methods
-	// and will eventually be completed.
-	NewTransactionReader() StoreTransactionReader
-
-	// ProjectionVersion returns the current projection version.
-	// Returns an empty string if the projection wasn't initialized yet.
-	// In case an empty string is returned the service will fallback
-	// to the begin offset version of the eventlog.
-	ProjectionVersion(
-		context.Context,
-		TransactionReader,
-	) (EventlogVersion, error)
+	attempts, err := runtime.Retry(
+		ctx,
+		c.options.MaxAttempts,
+		c.options.Backoff,
+		c.options.Jitter,
+		handle,
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"handling %s at %s: giving up after %d attempt(s): %w",
+			typeName, offset, attempts, err,
+		)
+	}
+	return nil
+}
 
-	// UpdateProjectionVersion explicitly sets the
-	// projection version of the store
-	UpdateProjectionVersion(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-	) error
+/* PROCESSES */
 
-	// ApplyEventUserCreated applies event UserCreated to the projection.
-	// The given projection version doesn't need to be applied,
-	// it will be applied in a separate call to UpdateProjectionVersion.
-	ApplyEventUserCreated(
-		context.Context,
-		TransactionWriter,
-		EventlogVersion,
-		time.Time,
-		EventUserCreated,
-	) error
-}
+// ProcessWelcomeHandler implements the decision logic of process Welcome.
+// Every instance of process Welcome is correlated by a key
+// and keeps its own state.
+type ProcessWelcomeHandler interface {
 
-// ServiceUsersMethodCaller represents an implementation
-// of the service Users
-type ServiceUsersMethodCaller interface {
+	// OnUserCreated reacts to event UserCreated correlated by
+	// its property id and returns the new state of
+	// the process instance, which is zero for new instances.
+	// Commands issued by OnUserCreated may be issued more than once
+	// if the process fails to save the returned state.
+	OnUserCreated(
+		ctx context.Context,
+		meta EventMeta,
+		commands ProcessWelcomeCommands,
+		state srcticketsprocesswelcomestate.State,
+		event EventUserCreated,
+	) (srcticketsprocesswelcomestate.State, error)
+}
 
-	// CreateUser represents method Users.CreateUser
+// ProcessWelcomeStateStore persists the states of
+// the instances of process Welcome.
+type ProcessWelcomeStateStore interface {
+	// LoadState returns the state correlated by the given key
+	// and the version of the event log it was saved at.
+	// Returns a zero state and an empty version
+	// if there's no state for the given key yet.
 	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	CreateUser(
-		context.Context,
-		TransactionReader,
-		srcticketsserviceusersio.CreateUserIn,
+	// WARNING: LoadState is expected to be thread-safe.
+	LoadState(
+		ctx context.Context,
+		key srcticketsid.User,
 	) (
-		output srcticketsserviceusersio.CreateUserOut,
-		events []Event,
+		state srcticketsprocesswelcomestate.State,
+		version EventlogVersion,
 		err error,
 	)
 
-	// GetUserByID represents method Users.GetUserByID
+	// SaveState stores the state correlated by the given key
+	// together with the version following the handled event.
 	//
-	// WARNING: this method is read-only and must not mutate neither
-	// the state of the projection nor the projection version!
-	// The provided transaction must not be committed or rolled back
-	// and shall only be used for queries and mutations.
-	GetUserByID(
-		context.Context,
-		TransactionReader,
-		srcticketsid.User,
-	) (
-		output srcticketsserviceusersio.GetUserByIDOut,
-		// No events
-		err error,
-	)
+	// WARNING: SaveState is expected to be thread-safe.
+	SaveState(
+		ctx context.Context,
+		key srcticketsid.User,
+		state srcticketsprocesswelcomestate.State,
+		version EventlogVersion,
+	) error
 }
 
-// NewServiceUsers creates a new instance of the Users service.
-func NewServiceUsers(
-	methodCaller ServiceUsersMethodCaller,
-	storeHandler ServiceUsersStoreHandler,
-	eventLogger EventLogger,
-	errorLogger Logger,
-	options ServiceOptions,
-) *ServiceUsers {
-	if methodCaller == nil {
-		panic("methodCaller is nil in NewServiceUsers")
-	}
-	if storeHandler == nil {
-		panic("storeHandler is nil in NewServiceUsers")
-	}
-	if eventLogger == nil {
-		panic("eventLogger is nil in NewServiceUsers")
-	}
-	if errorLogger == nil {
-		errorLogger = defaultLogErr
-	}
-	options.SetDefaults()
-	return &ServiceUsers{
-		methods:  methodCaller,
-		store:    storeHandler,
-		eventlog: eventLogger,
-		logErr:   errorLogger,
-		options:  options,
-	}
+// InmemProcessWelcomeStateStore is a thread-safe
+// in-memory ProcessWelcomeStateStore
+type InmemProcessWelcomeStateStore struct {
+	lock   sync.Mutex
+	states map[srcticketsid.User]inmemProcessWelcomeState
 }
 
-// ProjectionVersion returns the current projection version
-func (s *ServiceUsers) ProjectionVersion(ctx context.Context) (
-	EventlogVersion,
-	error,
-) {
-	txn := s.store.NewTransactionReader()
-	defer txn.Complete()
+type inmemProcessWelcomeState struct {
+	state   srcticketsprocesswelcomestate.State
+	version EventlogVersion
+}
 
-	return s.projectionVersion(ctx, txn)
+// NewInmemProcessWelcomeStateStore creates a new empty in-memory state store
+func NewInmemProcessWelcomeStateStore() *InmemProcessWelcomeStateStore {
+	return &InmemProcessWelcomeStateStore{
+		states: map[srcticketsid.User]inmemProcessWelcomeState{},
+	}
 }
 
-func (s *ServiceUsers) projectionVersion(
+// LoadState implements ProcessWelcomeStateStore.LoadState
+func (s *InmemProcessWelcomeStateStore) LoadState(
 	ctx context.Context,
-	txn TransactionReader,
+	key srcticketsid.User,
 ) (
+	srcticketsprocesswelcomestate.State,
 	EventlogVersion,
 	error,
 ) {
-	v, err := s.store.ProjectionVersion(ctx, txn)
-	if err != nil {
-		return "", fmt.Errorf("reading projection version: %w", err)
-	}
-	if v != "" {
-		return v, nil
-	}
-
-	// Fallback to the beginning of the eventlog
-	v, err = s.eventlog.Begin(ctx)
-	if err != nil {
-		return "", fmt.Errorf("reading begin offset version: %w", err)
-	}
-	return v, nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	v := s.states[key]
+	return v.state, v.version, nil
 }
 
-// Sync synchronizes service Users against the eventlog.
-// Sync will scan events until it reaches the tip of the event log and
-// always return the latest version of the event log it managed to reach,
-// unless the returned error is not equal context.Canceled or
-// context.DeadlineExceeded and didn't pass isErrAcceptable (if not nil).
-func (s *ServiceUsers) Sync(
+// SaveState implements ProcessWelcomeStateStore.SaveState
+func (s *InmemProcessWelcomeStateStore) SaveState(
 	ctx context.Context,
-	isErrAcceptable func(error) bool,
-) (
-	latestVersion EventlogVersion,
-	err error,
-) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) ||
-			(isErrAcceptable != nil && isErrAcceptable(err)) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+	key srcticketsid.User,
+	state srcticketsprocesswelcomestate.State,
+	version EventlogVersion,
+) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.states[key] = inmemProcessWelcomeState{state, version}
+	return nil
+}
 
-	return s.sync(ctx, txn)
+// ProcessWelcomeTickets defines the methods of service Tickets
+// process Welcome is allowed to call.
+// ProcessWelcomeTickets is implemented by ServiceTickets.
+type ProcessWelcomeTickets interface {
+	CreateTicket(
+		ctx context.Context,
+		input srcticketsserviceticketsio.CreateTicketIn,
+	) (
+		output srcticketsserviceticketsio.CreateTicketOut,
+		events []Event,
+		eventsPushTime time.Time,
+		err error,
+	)
 }
 
-func (s *ServiceUsers) sync(
-	ctx context.Context,
-	trx TransactionWriter,
-) (
-	latestVersion EventlogVersion,
-	err error,
-) {
-	initialVersion, err := s.projectionVersion(ctx, trx)
-	if err != nil {
-		return "", err
-	}
+// ProcessWelcomeCommands issues the commands
+// process Welcome is allowed to issue
+type ProcessWelcomeCommands struct {
+	eventlog       EventLogger
+	serviceTickets ProcessWelcomeTickets
+}
 
-	var appliedVersion EventlogVersion
-	defer func() {
-		if appliedVersion == latestVersion {
-			return
-		}
-		err = s.store.UpdateProjectionVersion(ctx, trx, latestVersion)
-		if err != nil {
-			latestVersion = appliedVersion
-		}
-	}()
+// Tickets returns the methods of service Tickets
+// process Welcome is allowed to call
+func (c ProcessWelcomeCommands) Tickets() ProcessWelcomeTickets {
+	return c.serviceTickets
+}
 
-	if err := s.eventlog.Scan(
-		ctx,
-		initialVersion,
-		0, // No limit
-		func(
-			offset EventlogVersion,
-			tm time.Time,
-			payload []byte,
-			next EventlogVersion,
-		) error {
-			ev, err := DecodeEventJSON(payload)
-			if err != nil {
-				return err
-			}
-			switch v := ev.(type) {
-			case EventUserCreated:
-				if err := s.store.ApplyEventUserCreated(
-					ctx, trx, next, tm, v,
-				); err != nil {
-					return err
-				}
-				if err := s.store.UpdateProjectionVersion(
-					ctx, trx, next,
-				); err != nil {
-					return err
-				}
-				appliedVersion = next
-			}
-			latestVersion = next
-			return nil
+// ProcessWelcome runs process Welcome reacting to the following events:
+//
+//	UserCreated
+//
+// Events are handled in the order of the event log from a stored cursor.
+// The state of a process instance is saved together with the version
+// of the handled event, which prevents an event from being applied
+// to the state of the same instance twice.
+type ProcessWelcome struct {
+	name     string
+	eventlog EventLogger
+	cursors  CursorStore
+	states   ProcessWelcomeStateStore
+	handler  ProcessWelcomeHandler
+	commands ProcessWelcomeCommands
+	options  ConsumerOptions
+	lock     sync.Mutex
+}
+
+// NewProcessWelcome creates a new process runner
+// identified by name in the cursor store.
+func NewProcessWelcome(
+	name string,
+	handler ProcessWelcomeHandler,
+	stateStore ProcessWelcomeStateStore,
+	eventLogger EventLogger,
+	cursorStore CursorStore,
+	serviceTickets ProcessWelcomeTickets,
+	options ConsumerOptions,
+) *ProcessWelcome {
+	if handler == nil {
+		panic("handler is nil in NewProcessWelcome")
+	}
+	if stateStore == nil {
+		panic("stateStore is nil in NewProcessWelcome")
+	}
+	if eventLogger == nil {
+		panic("eventLogger is nil in NewProcessWelcome")
+	}
+	if cursorStore == nil {
+		panic("cursorStore is nil in NewProcessWelcome")
+	}
+	if serviceTickets == nil {
+		panic("serviceTickets is nil in NewProcessWelcome")
+	}
+	options.SetDefaults()
+	return &ProcessWelcome{
+		name:     name,
+		eventlog: eventLogger,
+		cursors:  cursorStore,
+		states:   stateStore,
+		handler:  handler,
+		commands: ProcessWelcomeCommands{
+			eventlog:       eventLogger,
+			serviceTickets: serviceTickets,
 		},
-	); err != nil {
-		if s.eventlog.IsOffsetOutOfBoundErr(err) {
-			return latestVersion, nil
-		}
-		return "", err
+		options: options,
 	}
-	return latestVersion, nil
 }
 
-func (s *ServiceUsers) CreateUser(
-	ctx context.Context,
-	input srcticketsserviceusersio.CreateUserIn,
-) (
-	output srcticketsserviceusersio.CreateUserOut,
-	events []Event,
-	eventsPushTime time.Time,
+// Sync handles all events appended after the stored cursor
+// advancing the cursor after every event.
+// Failed handlers are retried according to the consumer options,
+// once out of attempts Sync returns the error and the failed event
+// is retried during the next call.
+// Returns the latest version of the event log the process reached.
+func (p *ProcessWelcome) Sync(ctx context.Context) (
+	latestVersion EventlogVersion,
 	err error,
 ) {
-	txn := s.store.NewTransactionReadWriter()
-	defer func() {
-		if err == nil ||
-			errors.Is(err, context.Canceled) ||
-			errors.Is(err, context.DeadlineExceeded) {
-			txn.Commit()
-		} else {
-			txn.Rollback()
-		}
-	}()
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
-	var outZero srcticketsserviceusersio.CreateUserOut
-	var eventsJSON []byte
+	return runtime.Tail(ctx, p.name, p.eventlog, p.cursors, p.handle)
+}
 
-	defer func() {
-		if err != nil {
-			output = outZero
-			events = nil
-			eventsJSON = nil
-			eventsPushTime = time.Time{}
-		}
-	}()
+// handle invokes the handler for the given event
+// if process Welcome reacts to it
+func (p *ProcessWelcome) handle(
+	ctx context.Context,
+	offset EventlogVersion,
+	tm time.Time,
+	payload []byte,
+	next EventlogVersion,
+) error {
+	typeName, err := DecodeEventTypeName(payload)
+	if err != nil {
+		return err
+	}
 
-	exec := func() (ok bool) {
-		output, events, err = s.methods.CreateUser(ctx, txn, input)
+	var key srcticketsid.User
+	var handle func(state srcticketsprocesswelcomestate.State) (
+		srcticketsprocesswelcomestate.State, error,
+	)
+	switch typeName {
+	case "UserCreated":
+		ev, err := DecodeEventJSON(payload)
 		if err != nil {
-			return false
+			return err
 		}
-		for i, e := range events {
-			if err = CheckEventType(e); err != nil {
-				err = fmt.Errorf("checking returned event (%d): %w", i, err)
-				return false
-			}
-			switch e.(type) {
-			case EventUserCreated:
-			default:
-				panic(fmt.Errorf(
-					"method Users.CreateUser is not allowed to emit event %s",
-					reflect.TypeOf(e),
-				))
-			}
+		v := ev.(EventUserCreated)
+		key = v.Id
+		handle = func(state srcticketsprocesswelcomestate.State) (
+			srcticketsprocesswelcomestate.State, error,
+		) {
+			return p.handler.OnUserCreated(ctx, EventMeta{
+				Offset: offset,
+				Next:   next,
+				Time:   tm,
+				Stream: GetEventStreamID(v),
+			}, p.commands, state, v)
 		}
-		if eventsJSON, err = EncodeEventJSON(events...); err != nil {
-			return false
+	default:
+		if p.options.UnknownEvents == UnknownEventFail &&
+			!IsEventTypeKnown(typeName) {
+			return UnknownEventTypeErr(fmt.Sprintf(
+				"unknown event type %s", typeName,
+			))
 		}
-		return true
-	}
-
-	var currentVersion EventlogVersion
-	currentVersion, err = s.projectionVersion(ctx, txn)
-	if err != nil {
-		return
+		return nil
 	}
 
-	_, _, eventsPushTime, err = s.eventlog.TryAppendJSON(
+	attempts, err := runtime.Retry(
 		ctx,
-		currentVersion,
-		func() ([]byte, error) {
-			if !exec() {
-				return nil, err
+		p.options.MaxAttempts,
+		p.options.Backoff,
+		p.options.Jitter,
+		func() error {
+			state, version, err := p.states.LoadState(ctx, key)
+			if err != nil {
+				return fmt.Errorf("loading state: %w", err)
+			}
+			if version == next {
+				// Already handled
+				return nil
+			}
+			if state, err = handle(state); err != nil {
+				return err
+			}
+			if err := p.states.SaveState(ctx, key, state, next); err != nil {
+				return fmt.Errorf("saving state: %w", err)
 			}
-			return eventsJSON, nil
+			return nil
 		},
-		func() (EventlogVersion, error) { return s.sync(ctx, txn) },
 	)
-
 	if err != nil {
-		return
-	}
-	if s.options.SyncAfterPush == Enabled && len(events) > 0 {
-		_, err = s.sync(ctx, txn)
-	}
-
-	return
-}
-
-func (s *ServiceUsers) GetUserByID(
-	ctx context.Context,
-	input srcticketsid.User,
-) (
-	output srcticketsserviceusersio.GetUserByIDOut,
-	// No events
-	err error,
-) {
-	txn := s.store.NewTransactionReader()
-	defer txn.Complete()
-
-	var outZero srcticketsserviceusersio.GetUserByIDOut
-
-	defer func() {
-		if err != nil {
-			output = outZero
-			// No events to reset
-		}
-	}()
-
-	exec := func() (ok bool) {
-		output, err = s.methods.GetUserByID(ctx, txn, input)
-		if err != nil {
-			return false
-		}
-
-		return true
+		return fmt.Errorf(
+			"handling %s at %s: giving up after %d attempt(s): %w",
+			typeName, offset, attempts, err,
+		)
 	}
-
-	exec()
-
-	return
+	return nil
 }
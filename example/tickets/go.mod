@@ -1,10 +1,26 @@
 module tickets
 
-go 1.15
+go 1.18
 
 require (
 	github.com/mattn/go-sqlite3 v1.14.4
 	github.com/romshark/eventlog v0.0.0-20200826005514-c3f99c96f1a3
+	github.com/romshark/goesgen v0.0.0
 	github.com/satori/go.uuid v1.2.0
-	github.com/stretchr/testify v1.4.0
+	github.com/stretchr/testify v1.7.0
 )
+
+require (
+	github.com/andybalholm/brotli v1.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/fasthttp/websocket v1.4.3 // indirect
+	github.com/klauspost/compress v1.10.4 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/savsgio/gotils v0.0.0-20200608150037-a5f6f5aef16c // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.14.0 // indirect
+	github.com/valyala/fastjson v1.4.5 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+)
+
+replace github.com/romshark/goesgen => ../..
@@ -6,6 +6,7 @@ type (
 	User    string
 	Ticket  string
 	Comment string
+	Tenant  string
 )
 
 func New() string {
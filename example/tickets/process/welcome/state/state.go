@@ -0,0 +1,9 @@
+package state
+
+import "tickets/id"
+
+// State is the state of a welcome process instance
+type State struct {
+	// Ticket is the welcome ticket created for the user
+	Ticket id.Ticket
+}
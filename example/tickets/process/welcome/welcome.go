@@ -0,0 +1,43 @@
+package welcome
+
+import (
+	"context"
+	"fmt"
+	"tickets"
+	"tickets/auth"
+	"tickets/generated"
+	"tickets/process/welcome/state"
+	"tickets/service/tickets/io"
+)
+
+// Process creates a welcome ticket for every new user
+type Process struct{}
+
+func New() *Process { return &Process{} }
+
+func (p *Process) OnUserCreated(
+	ctx context.Context,
+	meta generated.EventMeta,
+	commands generated.ProcessWelcomeCommands,
+	s state.State,
+	e generated.EventUserCreated,
+) (state.State, error) {
+	if s.Ticket != "" {
+		// Welcome ticket already created
+		return s, nil
+	}
+	out, _, _, err := commands.Tickets().CreateTicket(
+		context.WithValue(ctx, auth.CtxKeyUser, e.Id),
+		io.CreateTicketIn{
+			Title: "Welcome",
+			Description: tickets.TicketDescription(
+				"Welcome " + string(e.Name) + "!",
+			),
+		},
+	)
+	if err != nil {
+		return s, fmt.Errorf("creating welcome ticket: %w", err)
+	}
+	s.Ticket = out.ID
+	return s, nil
+}
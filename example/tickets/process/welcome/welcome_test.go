@@ -0,0 +1,71 @@
+package welcome_test
+
+import (
+	"context"
+	"testing"
+	"tickets/generated"
+	"tickets/process/welcome"
+	stickets "tickets/service/tickets"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog/inmem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWelcome(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := inmem.New(inmem.Options{Clock: func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}})
+	b, err := generated.EncodeEventJSON(
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+	)
+	r.NoError(err)
+	_, _, _, err = l.AppendJSON(ctx, nil, b)
+	r.NoError(err)
+
+	ticketsStore := stickets.NewStore()
+	serviceTickets := generated.NewServiceTickets(
+		stickets.New(
+			ticketsStore.ProjectionTicket(), ticketsStore.ProjectionUser(),
+		),
+		ticketsStore, l, nil,
+		generated.ServiceOptions{},
+	)
+	_, err = serviceTickets.Sync(ctx, nil)
+	r.NoError(err)
+
+	states := generated.NewInmemProcessWelcomeStateStore()
+	p := generated.NewProcessWelcome(
+		"welcome",
+		welcome.New(),
+		states,
+		l,
+		generated.NewInmemCursorStore(),
+		serviceTickets,
+		generated.ConsumerOptions{},
+	)
+
+	v, err := p.Sync(ctx)
+	r.NoError(err)
+	r.Equal("1", v)
+
+	s, sv, err := states.LoadState(ctx, "user_foo")
+	r.NoError(err)
+	r.Equal("1", sv)
+	r.NotZero(s.Ticket)
+
+	// The welcome ticket was created
+	r.Equal("2", l.Version())
+	_, err = serviceTickets.Sync(ctx, nil)
+	r.NoError(err)
+	o, err := serviceTickets.GetTicketByID(ctx, s.Ticket)
+	r.NoError(err)
+	r.Equal("Welcome", string(o.Title))
+
+	// Events appended by the process itself are skipped
+	v, err = p.Sync(ctx)
+	r.NoError(err)
+	r.Equal("2", v)
+}
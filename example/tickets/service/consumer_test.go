@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tickets/generated"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventConsumer(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := newEventlog(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventTicketClosed{Ticket: "ticket_foo", By: "user_foo"},
+		generated.EventUserCreated{Id: "user_bar", Name: "Bar"},
+	)
+	cursors := generated.NewInmemCursorStore()
+
+	var metas []generated.EventMeta
+	var users []generated.EventUserCreated
+	c := generated.NewEventConsumer(
+		"test", l, cursors, generated.ConsumerOptions{},
+	).OnUserCreated(func(
+		ctx context.Context,
+		m generated.EventMeta,
+		e generated.EventUserCreated,
+	) error {
+		metas = append(metas, m)
+		users = append(users, e)
+		return nil
+	})
+
+	v, err := c.Sync(ctx)
+	r.NoError(err)
+	r.Equal("3", v)
+
+	// EventTicketClosed has no handler
+	r.Equal([]generated.EventUserCreated{
+		{Id: "user_foo", Name: "Foo"},
+		{Id: "user_bar", Name: "Bar"},
+	}, users)
+	r.Equal([]generated.EventMeta{
+		{Offset: "0", Next: "1", Time: now(), Stream: "User/user_foo"},
+		{Offset: "2", Next: "3", Time: now(), Stream: "User/user_bar"},
+	}, metas)
+
+	cursor, err := cursors.LoadCursor(ctx, "test")
+	r.NoError(err)
+	r.Equal("3", cursor)
+}
+
+func TestEventConsumerErrGiveUp(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := newEventlog(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventUserCreated{Id: "user_bar", Name: "Bar"},
+	)
+
+	attempts := 0
+	c := generated.NewEventConsumer(
+		"test", l, generated.NewInmemCursorStore(),
+		generated.ConsumerOptions{
+			MaxAttempts: 2,
+			Backoff:     generated.ConstantBackoff(0),
+		},
+	).OnUserCreated(func(
+		ctx context.Context,
+		m generated.EventMeta,
+		e generated.EventUserCreated,
+	) error {
+		attempts++
+		if e.Id == "user_bar" {
+			return errHandlerFailed
+		}
+		return nil
+	})
+
+	v, err := c.Sync(ctx)
+	r.ErrorIs(err, errHandlerFailed)
+	r.Equal("1", v)
+	r.Equal(3, attempts)
+}
+
+func TestEventConsumerErrDecodeNotRetried(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := newEventlog(t)
+	_, _, _, err := l.AppendJSON(ctx, nil, []byte(
+		`{"type":"UserCreated","payload":{"id":42}}`,
+	))
+	r.NoError(err)
+
+	backoffs, calls := 0, 0
+	c := generated.NewEventConsumer(
+		"test", l, generated.NewInmemCursorStore(),
+		generated.ConsumerOptions{
+			MaxAttempts: 3,
+			Backoff: func(uint) time.Duration {
+				backoffs++
+				return 0
+			},
+		},
+	).OnUserCreated(func(
+		context.Context,
+		generated.EventMeta,
+		generated.EventUserCreated,
+	) error {
+		calls++
+		return nil
+	})
+
+	v, err := c.Sync(ctx)
+	r.Error(err)
+	r.Equal("0", v)
+	r.Zero(backoffs)
+	r.Zero(calls)
+}
+
+var errHandlerFailed = errors.New("handler failed")
@@ -57,11 +57,12 @@ func (a *EventlogAdapter) Scan(
 }
 
 // AppendJSON appends one or multiple new events
-// in JSON format onto the log.
+// in JSON format onto the log associating them with the given streams.
 //
 // WARNING: AppendJSON is expected to be thread-safe.
 func (a *EventlogAdapter) AppendJSON(
 	ctx context.Context,
+	streams []generated.StreamID,
 	payload []byte,
 ) (
 	offset generated.EventlogVersion,
@@ -72,25 +73,54 @@ func (a *EventlogAdapter) AppendJSON(
 	return a.Client.AppendJSON(ctx, payload)
 }
 
-// TryAppendJSON keeps executing transaction until either cancelled,
-// succeeded (assumed and actual event log versions match)
-// or failed due to an error.
+// IsMismatchingVersionsErr returns true if the given error
+// is a mismatching-versions error returned by AppendCheckJSON
+func (a *EventlogAdapter) IsMismatchingVersionsErr(err error) bool {
+	return err == eventlog.ErrMismatchingVersions
+}
+
+// AppendCheckJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if the assumed version matches the actual version of the log,
+// otherwise the append is rejected with an error satisfying
+// IsMismatchingVersionsErr.
+// AppendCheckJSON must not retry, retries are driven by the service.
 //
-// WARNING: TryAppendJSON is expected to be thread-safe.
-func (a *EventlogAdapter) TryAppendJSON(
+// WARNING: AppendCheckJSON is expected to be thread-safe.
+func (a *EventlogAdapter) AppendCheckJSON(
 	ctx context.Context,
 	assumedVersion generated.EventlogVersion,
-	transaction func() (events []byte, err error),
-	sync func() (generated.EventlogVersion, error),
+	streams []generated.StreamID,
+	payload []byte,
 ) (
 	offset generated.EventlogVersion,
 	newVersion generated.EventlogVersion,
 	tm time.Time,
 	err error,
 ) {
-	return a.Client.TryAppendJSON(
-		ctx, assumedVersion,
-		func() (events []byte, err error) { return transaction() },
-		func() (string, error) { return sync() },
-	)
+	return a.Client.AppendCheckJSON(ctx, assumedVersion, payload)
+}
+
+// AppendCheckStreamsJSON appends one or multiple new events
+// in JSON format onto the log associating them with the given streams
+// if none of the given streams received any events after
+// the assumed version, otherwise the append is rejected with an error
+// satisfying IsMismatchingVersionsErr.
+//
+// romshark/eventlog doesn't index streams, therefore the entire log
+// is checked instead which is stricter but never violates consistency.
+//
+// WARNING: AppendCheckStreamsJSON is expected to be thread-safe.
+func (a *EventlogAdapter) AppendCheckStreamsJSON(
+	ctx context.Context,
+	assumedVersion generated.EventlogVersion,
+	streams []generated.StreamID,
+	payload []byte,
+) (
+	offset generated.EventlogVersion,
+	newVersion generated.EventlogVersion,
+	tm time.Time,
+	err error,
+) {
+	return a.Client.AppendCheckJSON(ctx, assumedVersion, payload)
 }
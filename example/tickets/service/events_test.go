@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"testing"
+	"tickets/generated"
+
+	"github.com/stretchr/testify/require"
+)
+
+// visitedEvents records the names of the visited events
+type visitedEvents []string
+
+func (v *visitedEvents) visit(e generated.Event) error {
+	*v = append(*v, e.EventTypeName())
+	return nil
+}
+
+func (v *visitedEvents) VisitEventTicketAutoClosed(
+	e generated.EventTicketAutoClosed,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventTicketClosed(
+	e generated.EventTicketClosed,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventTicketCommented(
+	e generated.EventTicketCommented,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventTicketCreated(
+	e generated.EventTicketCreated,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventTicketDescriptionChanged(
+	e generated.EventTicketDescriptionChanged,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventTicketTitleChanged(
+	e generated.EventTicketTitleChanged,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventUserAssignedToTicket(
+	e generated.EventUserAssignedToTicket,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventUserCreated(
+	e generated.EventUserCreated,
+) error {
+	return v.visit(e)
+}
+
+func (v *visitedEvents) VisitEventUserUnassignedFromTicket(
+	e generated.EventUserUnassignedFromTicket,
+) error {
+	return v.visit(e)
+}
+
+func TestVisitEvent(t *testing.T) {
+	r := require.New(t)
+	var v visitedEvents
+
+	r.NoError(generated.VisitEvent(
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"}, &v,
+	))
+	r.NoError(generated.VisitEvent(
+		generated.EventTicketClosed{Ticket: "ticket_foo"}, &v,
+	))
+	r.Equal(visitedEvents{"UserCreated", "TicketClosed"}, v)
+
+	err := generated.VisitEvent(nil, &v)
+	r.Error(err)
+	r.IsType(generated.UnknownEventTypeErr(""), err)
+}
@@ -0,0 +1,147 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tickets/generated"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog/inmem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelay(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := newEventlog(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventTicketClosed{Ticket: "ticket_foo", By: "user_foo"},
+	)
+	cursors := generated.NewInmemCursorStore()
+	p := make(generated.ChannelPublisher, 8)
+	relay := generated.NewRelay("test", l, cursors, p, generated.RelayOptions{})
+
+	v, err := relay.Sync(ctx)
+	r.NoError(err)
+	r.Equal("2", v)
+	r.Len(p, 2)
+
+	e := <-p
+	r.Equal("0", e.Offset)
+	r.Equal("1", e.Next)
+	r.Equal(now(), e.Time)
+	r.Equal(generated.StreamIDUser("user_foo"), e.Stream)
+	r.Equal(generated.EventUserCreated{Id: "user_foo", Name: "Foo"}, e.Event)
+
+	e = <-p
+	r.Equal("1", e.Offset)
+	r.Equal(generated.StreamIDTicket("ticket_foo"), e.Stream)
+	r.IsType(generated.EventTicketClosed{}, e.Event)
+
+	c, err := cursors.LoadCursor(ctx, "test")
+	r.NoError(err)
+	r.Equal("2", c)
+
+	// Nothing left to publish
+	v, err = relay.Sync(ctx)
+	r.NoError(err)
+	r.Equal("2", v)
+	r.Len(p, 0)
+}
+
+func TestRelayRetry(t *testing.T) {
+	r := require.New(t)
+	l := newEventlog(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+	)
+	p := &failingPublisher{failures: 2}
+	relay := generated.NewRelay(
+		"test", l, generated.NewInmemCursorStore(), p,
+		generated.RelayOptions{
+			MaxAttempts: 3,
+			Backoff:     generated.ConstantBackoff(0),
+		},
+	)
+
+	v, err := relay.Sync(context.Background())
+	r.NoError(err)
+	r.Equal("1", v)
+	r.Equal(3, p.attempts)
+	r.Len(p.published, 1)
+}
+
+func TestRelayErrGiveUp(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := newEventlog(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventUserCreated{Id: "user_bar", Name: "Bar"},
+	)
+	cursors := generated.NewInmemCursorStore()
+	p := &failingPublisher{failures: 3, failAfter: 1}
+	relay := generated.NewRelay(
+		"test", l, cursors, p,
+		generated.RelayOptions{
+			MaxAttempts: 2,
+			Backoff:     generated.ConstantBackoff(0),
+		},
+	)
+
+	v, err := relay.Sync(ctx)
+	r.ErrorIs(err, errPublishFailed)
+	r.Equal("1", v)
+	r.Len(p.published, 1)
+
+	c, err := cursors.LoadCursor(ctx, "test")
+	r.NoError(err)
+	r.Equal("1", c)
+
+	// The failed event is published during the next sync
+	v, err = relay.Sync(ctx)
+	r.NoError(err)
+	r.Equal("2", v)
+	r.Len(p.published, 2)
+	r.Equal("1", p.published[1].Offset)
+}
+
+var errPublishFailed = errors.New("publish failed")
+
+// failingPublisher fails the given number of times
+// after failAfter successful publications
+type failingPublisher struct {
+	failures  int
+	failAfter int
+	attempts  int
+	published []generated.Publication
+}
+
+func (p *failingPublisher) Publish(
+	ctx context.Context,
+	e generated.Publication,
+) error {
+	p.attempts++
+	if len(p.published) >= p.failAfter && p.failures > 0 {
+		p.failures--
+		return errPublishFailed
+	}
+	p.published = append(p.published, e)
+	return nil
+}
+
+func now() time.Time {
+	return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newEventlog(t *testing.T, events ...generated.Event) *inmem.EventLog {
+	l := inmem.New(inmem.Options{Clock: now})
+	for _, e := range events {
+		b, err := generated.EncodeEventJSON(e)
+		require.NoError(t, err)
+		_, _, _, err = l.AppendJSON(
+			context.Background(), generated.GetEventStreamIDs(e), b,
+		)
+		require.NoError(t, err)
+	}
+	return l
+}
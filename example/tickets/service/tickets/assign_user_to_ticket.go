@@ -10,29 +10,36 @@ import (
 
 func (s *Service) AssignUserToTicket(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	cmd *generated.ServiceTicketsAssignUserToTicketCommand,
 	in io.AssignUserToTicketIn,
-) ([]generated.Event, error) {
+) error {
+	tx := cmd.Reader()
+
 	client, err := auth.User(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if _, ok := tx.(transaction).store.state.users[client]; !ok {
-		return nil, fmt.Errorf("user %s not found", client)
+	if err := s.checkUser(ctx, tx, client); err != nil {
+		return err
+	}
+	if err := s.checkUser(ctx, tx, in.User); err != nil {
+		return err
 	}
-	if _, ok := tx.(transaction).store.state.users[in.User]; !ok {
-		return nil, fmt.Errorf("user %s not found", in.User)
+	t, err := s.openTicket(ctx, tx, in.Ticket)
+	if err != nil {
+		return err
 	}
-	if _, ok := tx.(transaction).store.state.tickets[in.Ticket]; !ok {
-		return nil, fmt.Errorf("ticket %s not found", in.Ticket)
+	if t.Assignees.Contain(in.User) {
+		return fmt.Errorf(
+			"user %s is already assigned to ticket %s", in.User, in.Ticket,
+		)
 	}
 
-	return []generated.Event{
-		generated.EventUserAssignedToTicket{
-			User:   in.User,
-			Ticket: in.Ticket,
-			By:     client,
-		},
-	}, nil
+	cmd.EmitUserAssignedToTicket(generated.EventUserAssignedToTicket{
+		User:   in.User,
+		Ticket: in.Ticket,
+		By:     client,
+	})
+	return nil
 }
@@ -0,0 +1,18 @@
+package tickets
+
+import (
+	"context"
+	"tickets/generated"
+	"tickets/id"
+	"tickets/store"
+	"time"
+)
+
+func (s *Service) TimerAutoCloseStalledTicket(
+	ctx context.Context,
+	tx store.Transaction,
+	key id.Ticket,
+	deadline time.Time,
+) (generated.EventTicketAutoClosed, error) {
+	return generated.EventTicketAutoClosed{Ticket: key}, nil
+}
@@ -10,30 +10,31 @@ import (
 
 func (s *Service) CloseTicket(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	cmd *generated.ServiceTicketsCloseTicketCommand,
 	in io.CloseTicketIn,
-) ([]generated.Event, error) {
+) error {
+	tx := cmd.Reader()
+
 	client, err := auth.User(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if _, ok := tx.(transaction).store.state.users[client]; !ok {
-		return nil, fmt.Errorf("user %s not found", client)
+	if err := s.checkUser(ctx, tx, client); err != nil {
+		return err
 	}
 
-	t, ok := tx.(transaction).store.state.tickets[in.Ticket]
-	if !ok {
-		return nil, fmt.Errorf("ticket %s not found", in.Ticket)
+	t, err := s.ticket(ctx, tx, in.Ticket)
+	if err != nil {
+		return err
 	}
-	if t.State == generated.ProjectionTicketStateClosed {
-		return nil, fmt.Errorf("ticket already closed")
+	if t.State() == generated.ProjectionTicketStateClosed {
+		return fmt.Errorf("ticket already closed")
 	}
 
-	return []generated.Event{
-		generated.EventTicketClosed{
-			Ticket: in.Ticket,
-			By:     client,
-		},
-	}, nil
+	cmd.EmitTicketClosed(generated.EventTicketClosed{
+		Ticket: in.Ticket,
+		By:     client,
+	})
+	return nil
 }
@@ -12,25 +12,24 @@ import (
 
 func (s *Service) CreateComment(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	cmd *generated.ServiceTicketsCreateCommentCommand,
 	in io.CreateCommentIn,
 ) (
 	output io.CreateCommentOut,
-	events []generated.Event,
 	err error,
 ) {
+	tx := cmd.Reader()
+
 	client, err := auth.User(ctx)
 	if err != nil {
 		return
 	}
 
-	if _, ok := tx.(transaction).store.state.users[client]; !ok {
-		err = fmt.Errorf("user %s not found", client)
+	if err = s.checkUser(ctx, tx, client); err != nil {
 		return
 	}
 
-	if _, ok := tx.(transaction).store.state.tickets[in.Ticket]; !ok {
-		err = fmt.Errorf("ticket %s not found", in.Ticket)
+	if _, err = s.openTicket(ctx, tx, in.Ticket); err != nil {
 		return
 	}
 
@@ -41,14 +40,12 @@ func (s *Service) CreateComment(
 
 	newID := id.Comment(id.New())
 
-	events = []generated.Event{
-		generated.EventTicketCommented{
-			Id:      newID,
-			Ticket:  in.Ticket,
-			Message: in.Message,
-			By:      client,
-		},
-	}
+	cmd.EmitTicketCommented(generated.EventTicketCommented{
+		Id:      newID,
+		Ticket:  in.Ticket,
+		Message: in.Message,
+		By:      client,
+	})
 	output = io.CreateCommentOut{
 		Id:      newID,
 		Ticket:  in.Ticket,
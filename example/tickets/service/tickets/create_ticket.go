@@ -12,20 +12,20 @@ import (
 
 func (s *Service) CreateTicket(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	cmd *generated.ServiceTicketsCreateTicketCommand,
 	in io.CreateTicketIn,
 ) (
 	output io.CreateTicketOut,
-	events []generated.Event,
 	err error,
 ) {
+	tx := cmd.Reader()
+
 	client, err := auth.User(ctx)
 	if err != nil {
 		return
 	}
 
-	if _, ok := tx.(transaction).store.state.users[client]; !ok {
-		err = fmt.Errorf("user %s not found", client)
+	if err = s.checkUser(ctx, tx, client); err != nil {
 		return
 	}
 
@@ -42,13 +42,11 @@ func (s *Service) CreateTicket(
 		Title:       in.Title,
 		ID:          newID,
 	}
-	events = []generated.Event{
-		generated.EventTicketCreated{
-			Id:          newID,
-			Title:       in.Title,
-			Description: in.Description,
-			Author:      client,
-		},
-	}
+	cmd.EmitTicketCreated(generated.EventTicketCreated{
+		Id:          newID,
+		Title:       in.Title,
+		Description: in.Description,
+		Author:      client,
+	})
 	return
 }
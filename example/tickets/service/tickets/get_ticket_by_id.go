@@ -3,32 +3,32 @@ package tickets
 import (
 	"context"
 	"fmt"
-	"tickets/generated"
 	"tickets/id"
 	"tickets/service/tickets/io"
+	"tickets/store"
 )
 
 func (s *Service) GetTicketByID(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	tx store.Transaction,
 	in id.Ticket,
 ) (
 	output io.GetTicketByIDOut,
 	err error,
 ) {
-	t, ok := tx.(transactionRead).store.state.tickets[in]
+	t, ok, err := s.tickets.GetByID(ctx, tx, in)
+	if err != nil {
+		return
+	}
 	if !ok {
 		err = fmt.Errorf("not found")
 		return
 	}
 
-	output.Assignees = make([]id.User, 0, len(t.Assignees))
-	for u := range t.Assignees {
-		output.Assignees = append(output.Assignees, u.ID)
-	}
-	output.Author = t.Author.ID
+	output.Assignees = append([]id.User(nil), t.Assignees...)
+	output.Author = t.Author
 	output.Description = t.Description
 	output.Title = t.Title
-	output.ID = t.ID
+	output.ID = t.Id
 	return
 }
@@ -0,0 +1,36 @@
+package tickets
+
+import (
+	"context"
+	"tickets"
+	"tickets/generated"
+	"time"
+)
+
+// ticketProjection applies events to the Ticket projection
+// using the mappings declared in the schema except for
+// UserUnassignedFromTicket, which can't be expressed as a mapping
+type ticketProjection struct {
+	generated.ProjectionTicketMappings
+}
+
+func (ticketProjection) OnUserUnassignedFromTicket(
+	ctx context.Context,
+	tm time.Time,
+	p generated.ProjectionTicket,
+	e generated.EventUserUnassignedFromTicket,
+) (generated.ProjectionTicket, error) {
+	// Copy the assignees since p shares them with the previous version
+	a := make(tickets.TicketAssignees, 0, len(p.Assignees))
+	for _, u := range p.Assignees {
+		if u != e.User {
+			a = append(a, u)
+		}
+	}
+	p.Assignees = a
+	if len(p.Assignees) < 1 &&
+		p.State() == generated.ProjectionTicketStateInProgress {
+		p = p.WithState(generated.ProjectionTicketStateStalled)
+	}
+	return p, nil
+}
@@ -1,7 +1,70 @@
 package tickets
 
-type Service struct{}
+import (
+	"context"
+	"fmt"
+	"tickets/generated"
+	"tickets/id"
+	"tickets/store"
+)
 
-func New() *Service {
-	return &Service{}
+type Service struct {
+	tickets generated.ProjectionTicketReader
+	users   generated.ProjectionUserReader
+}
+
+func New(
+	tickets generated.ProjectionTicketReader,
+	users generated.ProjectionUserReader,
+) *Service {
+	return &Service{tickets: tickets, users: users}
+}
+
+// checkUser returns an error if user u doesn't exist
+func (s *Service) checkUser(
+	ctx context.Context,
+	tx store.Transaction,
+	u id.User,
+) error {
+	_, ok, err := s.users.GetByID(ctx, tx, u)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("user %s not found", u)
+	}
+	return nil
+}
+
+// ticket returns ticket t, or an error if it doesn't exist
+func (s *Service) ticket(
+	ctx context.Context,
+	tx store.Transaction,
+	t id.Ticket,
+) (generated.ProjectionTicket, error) {
+	p, ok, err := s.tickets.GetByID(ctx, tx, t)
+	if err != nil {
+		return p, err
+	}
+	if !ok {
+		return p, fmt.Errorf("ticket %s not found", t)
+	}
+	return p, nil
+}
+
+// openTicket returns ticket t, or an error if it doesn't exist
+// or is already closed
+func (s *Service) openTicket(
+	ctx context.Context,
+	tx store.Transaction,
+	t id.Ticket,
+) (generated.ProjectionTicket, error) {
+	p, err := s.ticket(ctx, tx, t)
+	if err != nil {
+		return p, err
+	}
+	if p.State() == generated.ProjectionTicketStateClosed {
+		return p, fmt.Errorf("ticket %s is closed", t)
+	}
+	return p, nil
 }
@@ -2,21 +2,22 @@ package tickets_test
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
+	"sync"
 	"testing"
 	"tickets"
 	"tickets/auth"
 	"tickets/generated"
 	"tickets/id"
-	"tickets/service"
 	stickets "tickets/service/tickets"
 	"tickets/service/tickets/io"
+	"tickets/store"
 	"time"
 
-	"github.com/romshark/eventlog/client"
-	"github.com/romshark/eventlog/eventlog"
-	"github.com/romshark/eventlog/eventlog/inmem"
+	"github.com/romshark/goesgen/eventlog"
+	"github.com/romshark/goesgen/eventlog/inmem"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,7 +41,7 @@ func TestCreateTicket(t *testing.T) {
 
 	// Check output
 	require.NoError(t, err)
-	require.WithinDuration(t, time.Now(), tm, time.Second)
+	require.Equal(t, now(), tm)
 	require.Len(t, e, 1)
 	require.IsType(t, generated.EventTicketCreated{}, e[0])
 
@@ -56,8 +57,8 @@ func TestCreateTicket(t *testing.T) {
 	require.Len(t, o.ID, 36)
 
 	// Check pushed events
-	require.Equal(t, uint64(2), s.Eventlog.Version())
-	s.checkEvent(1,
+	require.Equal(t, "2", s.Eventlog.Version())
+	s.checkEvent("1",
 		func(tm time.Time, e generated.Event) {
 			require.IsType(t, generated.EventTicketCreated{}, e)
 			v := e.(generated.EventTicketCreated)
@@ -101,23 +102,772 @@ func TestCreateTicketErrInvalidTitle(t *testing.T) {
 	require.Zero(t, o)
 
 	// Check pushed events
-	require.Equal(t, uint64(1), s.Eventlog.Version())
+	require.Equal(t, "1", s.Eventlog.Version())
+}
+
+func TestCreateTicketErrConflict(t *testing.T) {
+	s := NewSetup(t, generated.EventUserCreated{
+		Id:   "user_foo",
+		Name: "Foo",
+	})
+	s.Service = generated.NewServiceTickets(
+		newMethods(s.Store),
+		s.Store,
+		conflictingEventlog{s.Eventlog},
+		nil,
+		generated.ServiceOptions{
+			MaxAttempts: 3,
+			Backoff:     generated.ConstantBackoff(0),
+		},
+	)
+
+	o, e, tm, err := s.Service.CreateTicket(
+		context.WithValue(
+			context.Background(),
+			auth.CtxKeyUser,
+			id.User("user_foo"),
+		),
+		io.CreateTicketIn{
+			Description: "test description",
+			Title:       "test title",
+		},
+	)
+
+	// Check output
+	require.Error(t, err)
+	var errConflict generated.ConflictErr
+	require.True(t, errors.As(err, &errConflict))
+	require.Equal(t, uint(3), errConflict.Attempts)
+	require.Equal(t, "Tickets.CreateTicket", errConflict.Method)
+	require.True(t, errors.Is(err, eventlog.ErrMismatchingVersions))
+	require.Zero(t, tm)
+	require.Zero(t, e)
+	require.Zero(t, o)
+
+	// Check pushed events
+	require.Equal(t, "1", s.Eventlog.Version())
+}
+
+// conflictingEventlog rejects every checked append
+// as if the event log was concurrently modified
+type conflictingEventlog struct{ *inmem.EventLog }
+
+func (conflictingEventlog) AppendCheckStreamsJSON(
+	ctx context.Context,
+	assumedVersion generated.EventlogVersion,
+	streams []generated.StreamID,
+	payload []byte,
+) (
+	offset generated.EventlogVersion,
+	newVersion generated.EventlogVersion,
+	tm time.Time,
+	err error,
+) {
+	err = eventlog.ErrMismatchingVersions
+	return
+}
+
+func TestSyncSaveSnapshot(t *testing.T) {
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+		generated.EventUserCreated{Id: "user_b", Name: "B"},
+		generated.EventUserCreated{Id: "user_c", Name: "C"},
+		generated.EventUserCreated{Id: "user_d", Name: "D"},
+		generated.EventUserCreated{Id: "user_e", Name: "E"},
+	)
+	store := &snapshottingStore{Store: stickets.NewStore()}
+	srv := generated.NewServiceTickets(
+		newMethods(store.Store),
+		store,
+		s.Eventlog,
+		nil,
+		generated.ServiceOptions{SnapshotFrequency: 2},
+	)
+
+	v, err := srv.Sync(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "5", v)
+	require.Equal(t, []generated.EventlogVersion{"2", "4"}, store.saved)
+	require.Len(t, store.applied, 5)
+}
+
+func TestSyncLoadSnapshot(t *testing.T) {
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+		generated.EventUserCreated{Id: "user_b", Name: "B"},
+		generated.EventUserCreated{Id: "user_c", Name: "C"},
+	)
+	store := &snapshottingStore{Store: stickets.NewStore(), load: "2"}
+	srv := generated.NewServiceTickets(
+		newMethods(store.Store),
+		store,
+		s.Eventlog,
+		nil,
+		generated.ServiceOptions{},
+	)
+
+	v, err := srv.Sync(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "3", v)
+	require.Equal(t, 1, store.loaded)
+	require.Equal(t, []id.User{"user_c"}, store.applied)
+	require.Len(t, store.saved, 0)
+
+	// The snapshot must not be loaded again
+	// once the projection is initialized
+	_, err = srv.Sync(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, store.loaded)
+}
+
+func TestRebuild(t *testing.T) {
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+		generated.EventTicketCreated{
+			Id:          "ticket_a",
+			Title:       "Ticket A",
+			Description: "Ticket A description",
+			Author:      "user_a",
+		},
+		generated.EventTicketTitleChanged{
+			Ticket:   "ticket_a",
+			NewTitle: "Ticket A updated",
+			By:       "user_a",
+		},
+	)
+
+	var progress []generated.RebuildProgress
+	v, err := s.Service.Rebuild(
+		context.Background(),
+		generated.RebuildOptions{
+			OnProgress: func(p generated.RebuildProgress) {
+				progress = append(progress, p)
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "3", v)
+	require.Equal(t, []generated.RebuildProgress{
+		{Version: "1", Applied: 1},
+		{Version: "2", Applied: 2},
+		{Version: "3", Applied: 3},
+	}, progress)
+
+	pv, err := s.Service.ProjectionVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "3", pv)
+
+	o, err := s.Service.GetTicketByID(context.Background(), "ticket_a")
+	require.NoError(t, err)
+	require.Equal(t, tickets.TicketTitle("Ticket A updated"), o.Title)
+	require.Equal(t, id.User("user_a"), o.Author)
+}
+
+func TestSyncDeadLetter(t *testing.T) {
+	s := NewSetup(t)
+	s.appendEvents(
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+		generated.EventTicketCreated{
+			Id:     "ticket_a",
+			Title:  "Ticket A",
+			Author: "user_a",
+		},
+		generated.EventUserCreated{Id: "user_b", Name: "B"},
+	)
+
+	store := &failingStore{Store: stickets.NewStore(), failures: 1}
+	sink := &deadLetterSink{}
+	srv := generated.NewServiceTickets(
+		newMethods(store.Store),
+		store,
+		s.Eventlog,
+		nil,
+		generated.ServiceOptions{
+			FailurePolicy:  generated.FailurePolicySkip,
+			DeadLetterSink: sink,
+		},
+	)
+
+	v, err := srv.Sync(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "3", v)
+	require.Len(t, sink.deadLetters, 1)
+	d := sink.deadLetters[0]
+	require.Equal(t, "Tickets", d.Service)
+	require.Equal(t, "1", d.Offset)
+	require.Equal(t, "2", d.Next)
+	require.Equal(t, errApplyFailed, d.Err)
+
+	_, err = srv.GetTicketByID(context.Background(), "ticket_a")
+	require.Error(t, err)
+
+	// Reprocess the dead-lettered event
+	require.NoError(t, srv.Reprocess(context.Background(), d))
+
+	o, err := srv.GetTicketByID(context.Background(), "ticket_a")
+	require.NoError(t, err)
+	require.Equal(t, tickets.TicketTitle("Ticket A"), o.Title)
+
+	pv, err := srv.ProjectionVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "3", pv)
+}
+
+func TestSyncApplyRetries(t *testing.T) {
+	s := NewSetup(t)
+	s.appendEvents(
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+		generated.EventTicketCreated{
+			Id:     "ticket_a",
+			Title:  "Ticket A",
+			Author: "user_a",
+		},
+	)
+
+	store := &failingStore{Store: stickets.NewStore(), failures: 2}
+	srv := generated.NewServiceTickets(
+		newMethods(store.Store),
+		store,
+		s.Eventlog,
+		nil,
+		generated.ServiceOptions{ApplyRetries: 2},
+	)
+
+	v, err := srv.Sync(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "2", v)
+	require.Equal(t, 0, store.failures)
+}
+
+func TestSyncErrStop(t *testing.T) {
+	s := NewSetup(t)
+	s.appendEvents(
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+		generated.EventTicketCreated{
+			Id:     "ticket_a",
+			Title:  "Ticket A",
+			Author: "user_a",
+		},
+	)
+
+	store := &failingStore{Store: stickets.NewStore(), failures: 2}
+	srv := generated.NewServiceTickets(
+		newMethods(store.Store),
+		store,
+		s.Eventlog,
+		nil,
+		generated.ServiceOptions{ApplyRetries: 1},
+	)
+
+	_, err := srv.Sync(context.Background(), nil)
+	require.Equal(t, errApplyFailed, err)
+
+	// The transaction must have been rolled back
+	pv, err := srv.ProjectionVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "0", pv)
+}
+
+func TestSyncUnknownEventType(t *testing.T) {
+	s := NewSetup(t)
+	s.appendEvents(generated.EventUserCreated{Id: "user_a", Name: "A"})
+	_, _, _, err := s.Eventlog.AppendJSON(
+		context.Background(),
+		nil,
+		[]byte(`{"type":"FutureEvent","payload":{"foo":"bar"}}`),
+	)
+	require.NoError(t, err)
+	s.appendEvents(generated.EventUserCreated{Id: "user_b", Name: "B"})
+
+	t.Run("ignore", func(t *testing.T) {
+		store := stickets.NewStore()
+		srv := generated.NewServiceTickets(
+			newMethods(store),
+			store,
+			s.Eventlog,
+			nil,
+			generated.ServiceOptions{},
+		)
+		v, err := srv.Sync(context.Background(), nil)
+		require.NoError(t, err)
+		require.Equal(t, "3", v)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		store := stickets.NewStore()
+		srv := generated.NewServiceTickets(
+			newMethods(store),
+			store,
+			s.Eventlog,
+			nil,
+			generated.ServiceOptions{
+				UnknownEvents: generated.UnknownEventFail,
+			},
+		)
+		_, err := srv.Sync(context.Background(), nil)
+		require.Error(t, err)
+		require.IsType(t, generated.UnknownEventTypeErr(""), err)
+		require.Equal(t, "unknown event type FutureEvent", err.Error())
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		sink := &deadLetterSink{}
+		store := stickets.NewStore()
+		srv := generated.NewServiceTickets(
+			newMethods(store),
+			store,
+			s.Eventlog,
+			nil,
+			generated.ServiceOptions{
+				UnknownEvents:  generated.UnknownEventFail,
+				FailurePolicy:  generated.FailurePolicySkip,
+				DeadLetterSink: sink,
+			},
+		)
+		v, err := srv.Sync(context.Background(), nil)
+		require.NoError(t, err)
+		require.Equal(t, "3", v)
+		require.Len(t, sink.deadLetters, 1)
+		require.Equal(t, "1", sink.deadLetters[0].Offset)
+	})
+}
+
+var errApplyFailed = errors.New("apply failed")
+
+// failingStore fails applying TicketCreated a number of times
+type failingStore struct {
+	*stickets.Store
+	failures int
+}
+
+func (s *failingStore) ApplyEventTicketCreatedToTicket(
+	ctx context.Context,
+	tx store.Transaction,
+	v generated.EventlogVersion,
+	tm time.Time,
+	e generated.EventTicketCreated,
+) error {
+	if s.failures > 0 {
+		s.failures--
+		return errApplyFailed
+	}
+	return s.Store.ApplyEventTicketCreatedToTicket(ctx, tx, v, tm, e)
+}
+
+type deadLetterSink struct{ deadLetters []generated.DeadLetter }
+
+func (s *deadLetterSink) RecordDeadLetter(
+	ctx context.Context,
+	d generated.DeadLetter,
+) error {
+	s.deadLetters = append(s.deadLetters, d)
+	return nil
+}
+
+// snapshottingStore records snapshots and applied users
+type snapshottingStore struct {
+	*stickets.Store
+	load    generated.EventlogVersion
+	loaded  int
+	saved   []generated.EventlogVersion
+	applied []id.User
+}
+
+func (s *snapshottingStore) SaveSnapshot(
+	ctx context.Context,
+	tx store.Transaction,
+	v generated.EventlogVersion,
+) error {
+	s.saved = append(s.saved, v)
+	return nil
+}
+
+func (s *snapshottingStore) LoadSnapshot(
+	ctx context.Context,
+	tx store.Transaction,
+) (generated.EventlogVersion, error) {
+	s.loaded++
+	return s.load, nil
+}
+
+func (s *snapshottingStore) ApplyEventUserCreatedToUser(
+	ctx context.Context,
+	tx store.Transaction,
+	v generated.EventlogVersion,
+	tm time.Time,
+	e generated.EventUserCreated,
+) error {
+	s.applied = append(s.applied, e.Id)
+	return s.Store.ApplyEventUserCreatedToUser(ctx, tx, v, tm, e)
+}
+
+func TestProjectionTicketReader(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventUserCreated{Id: "user_bar", Name: "Bar"},
+		generated.EventTicketCreated{
+			Id: "ticket_b", Title: "B", Author: "user_foo",
+		},
+		generated.EventTicketCreated{
+			Id: "ticket_c", Title: "C", Author: "user_bar",
+		},
+		generated.EventTicketCreated{
+			Id: "ticket_a", Title: "A", Author: "user_foo",
+		},
+		generated.EventUserAssignedToTicket{
+			User: "user_bar", Ticket: "ticket_b", By: "user_foo",
+		},
+		generated.EventTicketTitleChanged{
+			Ticket: "ticket_b", NewTitle: "B2", By: "user_foo",
+		},
+	)
+	reader := s.Store.ProjectionTicket()
+	rd := s.Store.NewTransactionReader()
+	defer rd.Complete()
+
+	p, ok, err := reader.GetByID(ctx, rd, "ticket_b")
+	r.NoError(err)
+	r.True(ok)
+	r.Equal(id.Ticket("ticket_b"), p.Id)
+	r.Equal(tickets.TicketTitle("B2"), p.Title)
+	r.Equal(id.User("user_foo"), p.Author)
+	r.Equal(generated.ProjectionTicketStateInProgress, p.State())
+
+	_, ok, err = reader.GetByID(ctx, rd, "ticket_x")
+	r.NoError(err)
+	r.False(ok)
+
+	// Listed in the order of creation
+	l, err := reader.ListByAuthor(ctx, rd, "user_foo")
+	r.NoError(err)
+	r.Len(l, 2)
+	r.Equal(id.Ticket("ticket_b"), l[0].Id)
+	r.Equal(id.Ticket("ticket_a"), l[1].Id)
+
+	l, err = reader.ListByAuthor(ctx, rd, "user_baz")
+	r.NoError(err)
+	r.Len(l, 0)
+}
+
+func TestProjectionTicketIllegalTransition(t *testing.T) {
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventTicketCreated{
+			Id: "ticket_foo", Title: "Foo", Author: "user_foo",
+		},
+	)
+	tx := s.Store.NewTransactionReadWriter()
+	defer tx.Rollback()
+
+	// TicketAutoClosed is only legal for stalled tickets
+	err := s.Store.ApplyEventTicketAutoClosedToTicket(
+		context.Background(), tx, "", now(),
+		generated.EventTicketAutoClosed{Ticket: "ticket_foo"},
+	)
+	require.Error(t, err)
+	require.Equal(t,
+		"applying TicketAutoClosed: "+
+			"illegal transition of Ticket ticket_foo: New -> Closed",
+		err.Error(),
+	)
+}
+
+func TestStoreRollback(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventTicketCreated{
+			Id: "ticket_a", Title: "A", Author: "user_foo",
+		},
+	)
+	reader := s.Store.ProjectionTicket()
+
+	tx := s.Store.NewTransactionReadWriter()
+	r.NoError(s.Store.ApplyEventTicketCreatedToTicket(
+		ctx, tx, "", now(), generated.EventTicketCreated{
+			Id: "ticket_b", Title: "B", Author: "user_foo",
+		},
+	))
+	r.NoError(s.Store.ApplyEventTicketTitleChangedToTicket(
+		ctx, tx, "", now(), generated.EventTicketTitleChanged{
+			Ticket: "ticket_a", NewTitle: "A2", By: "user_foo",
+		},
+	))
+	r.NoError(s.Store.UpdateProjectionVersion(
+		ctx, tx, generated.ProjectionNameTicket, "100",
+	))
+	tx.Rollback()
+
+	rd := s.Store.NewTransactionReader()
+	defer rd.Complete()
+
+	v, err := s.Store.ProjectionVersion(
+		ctx, rd, generated.ProjectionNameTicket,
+	)
+	r.NoError(err)
+	r.Equal("2", v)
+
+	p, ok, err := reader.GetByID(ctx, rd, "ticket_a")
+	r.NoError(err)
+	r.True(ok)
+	r.Equal(tickets.TicketTitle("A"), p.Title)
+
+	_, ok, err = reader.GetByID(ctx, rd, "ticket_b")
+	r.NoError(err)
+	r.False(ok)
+
+	l, err := reader.ListByAuthor(ctx, rd, "user_foo")
+	r.NoError(err)
+	r.Len(l, 1)
+	r.Equal(id.Ticket("ticket_a"), l[0].Id)
+}
+
+// readOnlyMethods records whether CloseTicket received
+// a read-only view of the store transaction
+type readOnlyMethods struct {
+	*stickets.Service
+	readOnly bool
+}
+
+func (m *readOnlyMethods) CloseTicket(
+	ctx context.Context,
+	cmd *generated.ServiceTicketsCloseTicketCommand,
+	in io.CloseTicketIn,
+) error {
+	m.readOnly = cmd.Reader().ReadOnly()
+	return m.Service.CloseTicket(ctx, cmd, in)
+}
+
+func TestCommandReadOnlyView(t *testing.T) {
+	r := require.New(t)
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventTicketCreated{
+			Id: "ticket_foo", Title: "Foo", Author: "user_foo",
+		},
+	)
+	m := &readOnlyMethods{Service: newMethods(s.Store)}
+	srv := generated.NewServiceTickets(
+		m, s.Store, s.Eventlog, nil, generated.ServiceOptions{},
+	)
+
+	_, _, err := srv.CloseTicket(
+		context.WithValue(
+			context.Background(), auth.CtxKeyUser, id.User("user_foo"),
+		),
+		io.CloseTicketIn{Ticket: "ticket_foo"},
+	)
+	r.NoError(err)
+	r.True(m.readOnly)
+	r.Equal("3", s.Eventlog.Version())
+}
+
+func TestTenants(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	l := inmem.New(inmem.Options{Clock: now})
+	tenants := generated.NewServiceTicketsTenants(
+		generated.ServiceTicketsTenantProviderFunc(func(
+			context.Context, id.Tenant,
+		) (
+			generated.ServiceTicketsMethodCaller,
+			generated.ServiceTicketsStoreHandler,
+			error,
+		) {
+			store := &tenantStore{Store: stickets.NewStore()}
+			return newMethods(store.Store), store, nil
+		}),
+		l,
+		nil,
+		generated.ServiceOptions{},
+	)
+
+	a, err := tenants.Tenant(ctx, "tenant_a")
+	r.NoError(err)
+	b, err := tenants.Tenant(ctx, "tenant_b")
+	r.NoError(err)
+	r.NotSame(a, b)
+
+	// Instances are reused
+	a2, err := tenants.Tenant(ctx, "tenant_a")
+	r.NoError(err)
+	r.Same(a, a2)
+
+	la, err := generated.TenantEventLog(l, "tenant_a")
+	r.NoError(err)
+	events := []generated.Event{
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+		generated.EventTicketCreated{
+			Id:     "ticket_a",
+			Title:  "Ticket A",
+			Author: "user_a",
+		},
+	}
+	p, err := generated.EncodeEventJSON(events...)
+	r.NoError(err)
+	_, _, _, err = la.AppendJSON(
+		ctx, generated.GetEventStreamIDs(events...), p,
+	)
+	r.NoError(err)
+
+	_, err = a.Sync(ctx, nil)
+	r.NoError(err)
+	_, err = b.Sync(ctx, nil)
+	r.NoError(err)
+
+	o, err := a.GetTicketByID(ctx, "ticket_a")
+	r.NoError(err)
+	r.Equal(tickets.TicketTitle("Ticket A"), o.Title)
+
+	// Tenant B doesn't see the events of tenant A
+	_, err = b.GetTicketByID(ctx, "ticket_a")
+	r.Error(err)
+	va, err := a.ProjectionVersion(ctx)
+	r.NoError(err)
+	r.Equal("2", va)
+	vb, err := b.ProjectionVersion(ctx)
+	r.NoError(err)
+	r.Equal("0", vb)
+	r.Equal("2", l.Version())
+
+	// The store handler receives the tenant
+	for _, tenant := range []id.Tenant{"tenant_a", "tenant_b"} {
+		s, err := tenants.Tenant(ctx, tenant)
+		r.NoError(err)
+		_, err = s.ProjectionVersion(ctx)
+		r.NoError(err)
+	}
+	_, ok := generated.TenantFromContext(ctx)
+	r.False(ok)
+
+	// Removed instances are created anew
+	r.True(tenants.Remove("tenant_a"))
+	r.False(tenants.Remove("tenant_a"))
+	a3, err := tenants.Tenant(ctx, "tenant_a")
+	r.NoError(err)
+	r.NotSame(a, a3)
+}
+
+func TestTenantsConcurrent(t *testing.T) {
+	r := require.New(t)
+	var lock sync.Mutex
+	provided := 0
+	tenants := generated.NewServiceTicketsTenants(
+		generated.ServiceTicketsTenantProviderFunc(func(
+			context.Context, id.Tenant,
+		) (
+			generated.ServiceTicketsMethodCaller,
+			generated.ServiceTicketsStoreHandler,
+			error,
+		) {
+			lock.Lock()
+			defer lock.Unlock()
+			provided++
+			store := stickets.NewStore()
+			return newMethods(store), store, nil
+		}),
+		inmem.New(inmem.Options{Clock: now}),
+		nil,
+		generated.ServiceOptions{},
+	)
+
+	var wg sync.WaitGroup
+	instances := make([]*generated.ServiceTickets, 8)
+	for i := range instances {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s, err := tenants.Tenant(context.Background(), "tenant_a")
+			if err == nil {
+				instances[i] = s
+			}
+		}(i)
+	}
+	wg.Wait()
+	r.Equal(1, provided)
+	for _, s := range instances {
+		r.NotNil(s)
+		r.Same(instances[0], s)
+	}
+}
+
+func TestTenantsDeadLetter(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := inmem.New(inmem.Options{Clock: now})
+	la, err := generated.TenantEventLog(l, "tenant_a")
+	r.NoError(err)
+	_, _, _, err = la.AppendJSON(
+		ctx, nil, []byte(`{"type":"FutureEvent","payload":{}}`),
+	)
+	r.NoError(err)
+
+	sink := &deadLetterSink{}
+	tenants := generated.NewServiceTicketsTenants(
+		generated.ServiceTicketsTenantProviderFunc(func(
+			context.Context, id.Tenant,
+		) (
+			generated.ServiceTicketsMethodCaller,
+			generated.ServiceTicketsStoreHandler,
+			error,
+		) {
+			store := stickets.NewStore()
+			return newMethods(store), store, nil
+		}),
+		l,
+		nil,
+		generated.ServiceOptions{
+			UnknownEvents:  generated.UnknownEventFail,
+			FailurePolicy:  generated.FailurePolicySkip,
+			DeadLetterSink: sink,
+		},
+	)
+
+	a, err := tenants.Tenant(ctx, "tenant_a")
+	r.NoError(err)
+	_, err = a.Sync(ctx, nil)
+	r.NoError(err)
+	r.Len(sink.deadLetters, 1)
+	r.Equal(id.Tenant("tenant_a"), sink.deadLetters[0].Tenant)
+}
+
+// tenantStore is a store handler requiring the tenant
+// of the service instance invoking it
+type tenantStore struct{ *stickets.Store }
+
+func (s *tenantStore) ProjectionVersion(
+	ctx context.Context,
+	trx store.Transaction,
+	p generated.ProjectionName,
+) (generated.EventlogVersion, error) {
+	if _, ok := generated.TenantFromContext(ctx); !ok {
+		return "", errors.New("missing tenant")
+	}
+	return s.Store.ProjectionVersion(ctx, trx, p)
 }
 
 type Setup struct {
 	t        *testing.T
 	Service  *generated.ServiceTickets
-	Eventlog *eventlog.EventLog
+	Store    *stickets.Store
+	Eventlog *inmem.EventLog
 }
 
+// now is the fake clock of the event log
+func now() time.Time { return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC) }
+
 func NewSetup(t *testing.T, events ...generated.Event) Setup {
 	lErr := log.New(os.Stderr, "ERR", log.LstdFlags)
-	l := eventlog.New(inmem.New())
-	c := client.New(client.NewInmem(l))
+	l := inmem.New(inmem.Options{Clock: now})
+	store := stickets.NewStore()
 	srv := generated.NewServiceTickets(
-		stickets.New(),
-		stickets.NewStore(),
-		&service.EventlogAdapter{Client: c},
+		newMethods(store),
+		store,
+		l,
 		lErr,
 		generated.ServiceOptions{},
 	)
@@ -125,6 +875,7 @@ func NewSetup(t *testing.T, events ...generated.Event) Setup {
 	s := Setup{
 		t:        t,
 		Eventlog: l,
+		Store:    store,
 		Service:  srv,
 	}
 
@@ -136,32 +887,138 @@ func NewSetup(t *testing.T, events ...generated.Event) Setup {
 	return s
 }
 
+// newMethods creates the tickets service method caller
+// reading the projections of the given store
+func newMethods(store *stickets.Store) *stickets.Service {
+	return stickets.New(store.ProjectionTicket(), store.ProjectionUser())
+}
+
 func (s Setup) appendEvents(e ...generated.Event) {
-	payloads := make([][]byte, len(e))
-	for i, e := range e {
-		b, err := generated.EncodeEventJSON(e)
-		require.NoError(s.t, err)
-		payloads[i] = b
+	if len(e) < 1 {
+		return
 	}
-	_, _, _, err := s.Eventlog.AppendMulti(payloads...)
+	b, err := generated.EncodeEventJSON(e...)
+	require.NoError(s.t, err)
+	_, _, _, err = s.Eventlog.AppendJSON(
+		context.Background(),
+		generated.GetEventStreamIDs(e...),
+		b,
+	)
 	require.NoError(s.t, err)
 }
 
 func (s Setup) checkEvent(
-	offset uint64,
+	offset generated.EventlogVersion,
 	onEvent ...func(tm time.Time, e generated.Event),
 ) {
-	for i, check := range onEvent {
-		_, err := s.Eventlog.Scan(
-			offset+uint64(i),
-			uint64(len(onEvent)),
-			func(timestamp uint64, payload []byte, offset uint64) error {
-				e, err := generated.DecodeEventJSON(payload)
-				require.NoError(s.t, err)
-				check(time.Unix(int64(timestamp), 0), e)
-				return nil
-			},
-		)
-		require.NoError(s.t, err)
-	}
+	i := 0
+	err := s.Eventlog.Scan(
+		context.Background(),
+		offset,
+		uint(len(onEvent)),
+		func(
+			offset generated.EventlogVersion,
+			tm time.Time,
+			payload []byte,
+			next generated.EventlogVersion,
+		) error {
+			e, err := generated.DecodeEventJSON(payload)
+			require.NoError(s.t, err)
+			onEvent[i](tm, e)
+			i++
+			return nil
+		},
+	)
+	require.NoError(s.t, err)
+	require.Equal(s.t, len(onEvent), i)
+}
+
+func TestTimerAutoCloseStalledTicket(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	clock := now()
+	l := inmem.New(inmem.Options{Clock: func() time.Time { return clock }})
+	store := stickets.NewStore()
+	srv := generated.NewServiceTickets(
+		newMethods(store), store, l, nil,
+		generated.ServiceOptions{
+			Clock: func() time.Time { return clock },
+		},
+	)
+	s := Setup{t: t, Service: srv, Store: store, Eventlog: l}
+	s.appendEvents(
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventTicketCreated{
+			Id:     "ticket_foo",
+			Title:  "Foo",
+			Author: "user_foo",
+		},
+		generated.EventUserAssignedToTicket{
+			User: "user_foo", Ticket: "ticket_foo", By: "user_foo",
+		},
+		generated.EventUserUnassignedFromTicket{
+			User: "user_foo", Ticket: "ticket_foo", By: "user_foo",
+		},
+	)
+
+	// The ticket is stalled but not for long enough
+	clock = clock.Add(7*24*time.Hour - time.Second)
+	fired, err := srv.FireTimers(ctx)
+	r.NoError(err)
+	r.Zero(fired)
+	r.Equal("4", l.Version())
+
+	clock = clock.Add(time.Second)
+	fired, err = srv.FireTimers(ctx)
+	r.NoError(err)
+	r.Equal(uint(1), fired)
+	s.checkEvent("4", func(tm time.Time, e generated.Event) {
+		r.Equal(generated.EventTicketAutoClosed{Ticket: "ticket_foo"}, e)
+	})
+
+	rd := store.NewTransactionReader()
+	st, err := store.ProjectionTicketState(ctx, rd, "ticket_foo")
+	rd.Complete()
+	r.NoError(err)
+	r.Equal(generated.ProjectionTicketStateClosed, st)
+
+	// The timer doesn't fire twice
+	fired, err = srv.FireTimers(ctx)
+	r.NoError(err)
+	r.Zero(fired)
+}
+
+func TestTimerAutoCloseStalledTicketCancel(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	clock := now()
+	s := NewSetup(t,
+		generated.EventUserCreated{Id: "user_foo", Name: "Foo"},
+		generated.EventTicketCreated{
+			Id:     "ticket_foo",
+			Title:  "Foo",
+			Author: "user_foo",
+		},
+		generated.EventUserAssignedToTicket{
+			User: "user_foo", Ticket: "ticket_foo", By: "user_foo",
+		},
+		generated.EventUserUnassignedFromTicket{
+			User: "user_foo", Ticket: "ticket_foo", By: "user_foo",
+		},
+		// Leaves the stalled state before the deadline
+		generated.EventUserAssignedToTicket{
+			User: "user_foo", Ticket: "ticket_foo", By: "user_foo",
+		},
+	)
+	s.Service = generated.NewServiceTickets(
+		newMethods(s.Store), s.Store, s.Eventlog, nil,
+		generated.ServiceOptions{
+			Clock: func() time.Time { return clock.Add(8 * 24 * time.Hour) },
+		},
+	)
+
+	fired, err := s.Service.FireTimers(ctx)
+	r.NoError(err)
+	r.Zero(fired)
+	r.Equal("5", s.Eventlog.Version())
 }
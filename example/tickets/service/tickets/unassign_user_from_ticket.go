@@ -10,30 +10,37 @@ import (
 
 func (s *Service) UnassignUserFromTicket(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	cmd *generated.ServiceTicketsUnassignUserFromTicketCommand,
 	in io.UnassignUserFromTicketIn,
-) ([]generated.Event, error) {
+) error {
+	tx := cmd.Reader()
+
 	client, err := auth.User(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if _, ok := tx.(transaction).store.state.users[client]; !ok {
-		return nil, fmt.Errorf("user %s not found", client)
+	if err := s.checkUser(ctx, tx, client); err != nil {
+		return err
 	}
-	if _, ok := tx.(transaction).store.state.users[in.User]; !ok {
-		return nil, fmt.Errorf("user %s not found", in.User)
+	if err := s.checkUser(ctx, tx, in.User); err != nil {
+		return err
 	}
 
-	if _, ok := tx.(transaction).store.state.tickets[in.Ticket]; !ok {
-		return nil, fmt.Errorf("ticket %s not found", in.Ticket)
+	t, err := s.openTicket(ctx, tx, in.Ticket)
+	if err != nil {
+		return err
+	}
+	if !t.Assignees.Contain(in.User) {
+		return fmt.Errorf(
+			"user %s isn't assigned to ticket %s", in.User, in.Ticket,
+		)
 	}
 
-	return []generated.Event{
-		generated.EventUserUnassignedFromTicket{
-			User:   in.User,
-			Ticket: in.Ticket,
-			By:     client,
-		},
-	}, nil
+	cmd.EmitUserUnassignedFromTicket(generated.EventUserUnassignedFromTicket{
+		User:   in.User,
+		Ticket: in.Ticket,
+		By:     client,
+	})
+	return nil
 }
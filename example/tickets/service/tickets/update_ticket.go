@@ -11,26 +11,28 @@ import (
 
 func (s *Service) UpdateTicket(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	cmd *generated.ServiceTicketsUpdateTicketCommand,
 	in io.UpdateTicketIn,
-) (events []generated.Event, err error) {
+) error {
+	tx := cmd.Reader()
+
 	client, err := auth.User(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if _, ok := tx.(transaction).store.state.users[client]; !ok {
-		return nil, fmt.Errorf("user %s not found", client)
+	if err := s.checkUser(ctx, tx, client); err != nil {
+		return err
 	}
 
-	t, ok := tx.(transaction).store.state.tickets[in.Ticket]
-	if !ok {
-		return nil, fmt.Errorf("ticket %s not found", in.Ticket)
+	t, err := s.openTicket(ctx, tx, in.Ticket)
+	if err != nil {
+		return err
 	}
 
 	if in.NewDescription != nil && *in.NewDescription != t.Description {
-		events = append(events, generated.EventTicketDescriptionChanged{
-			Ticket:         t.ID,
+		cmd.EmitTicketDescriptionChanged(generated.EventTicketDescriptionChanged{
+			Ticket:         t.Id,
 			NewDescription: *in.NewDescription,
 			By:             client,
 		})
@@ -38,16 +40,16 @@ func (s *Service) UpdateTicket(
 
 	if in.NewTitle != nil {
 		if err := tickets.ValidateTicketTitle(*in.NewTitle); err != nil {
-			return nil, fmt.Errorf("invalid new title: %w", err)
+			return fmt.Errorf("invalid new title: %w", err)
 		}
 		if t.Title != *in.NewTitle {
-			events = append(events, generated.EventTicketTitleChanged{
-				Ticket:   t.ID,
+			cmd.EmitTicketTitleChanged(generated.EventTicketTitleChanged{
+				Ticket:   t.Id,
 				NewTitle: *in.NewTitle,
 				By:       client,
 			})
 		}
 	}
 
-	return events, nil
+	return nil
 }
@@ -12,11 +12,10 @@ import (
 
 func (s *Service) CreateUser(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	cmd *generated.ServiceUsersCreateUserCommand,
 	in io.CreateUserIn,
 ) (
 	output io.CreateUserOut,
-	events []generated.Event,
 	err error,
 ) {
 	if err = tickets.ValidateUserName(in.Name); err != nil {
@@ -25,8 +24,8 @@ func (s *Service) CreateUser(
 	}
 
 	// Make sure the user name isn't yet reserved by an existing user
-	row := tx.(transaction).tx.QueryRow(
-		`SELECT id FROM users WHERE name = ?`,
+	row := cmd.Reader().QueryRowContext(ctx,
+		`SELECT id FROM users_user WHERE name = $1`,
 		in.Name,
 	)
 	var uid id.User
@@ -43,11 +42,9 @@ func (s *Service) CreateUser(
 
 	output.ID = id.User(id.New())
 	output.Name = in.Name
-	events = []generated.Event{
-		generated.EventUserCreated{
-			Id:   output.ID,
-			Name: in.Name,
-		},
-	}
+	cmd.EmitUserCreated(generated.EventUserCreated{
+		Id:   output.ID,
+		Name: in.Name,
+	})
 	return
 }
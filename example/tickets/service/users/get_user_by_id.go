@@ -12,14 +12,14 @@ import (
 
 func (s *Service) GetUserByID(
 	ctx context.Context,
-	tx generated.TransactionReader,
+	tx generated.SQLReadOnlyView,
 	in id.User,
 ) (
 	output io.GetUserByIDOut,
 	err error,
 ) {
-	row := tx.(transaction).tx.QueryRow(
-		`SELECT name FROM users WHERE id = ?`,
+	row := tx.QueryRowContext(ctx,
+		`SELECT name FROM users_user WHERE id = $1`,
 		in,
 	)
 	switch err = row.Scan(&output.Name); err {
@@ -0,0 +1,244 @@
+package users_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tickets"
+	"tickets/generated"
+	"tickets/service/users"
+	"tickets/service/users/io"
+	"time"
+
+	"github.com/romshark/goesgen/eventlog/inmem"
+	"github.com/romshark/goesgen/eventlog/sqldb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUser(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	s, store := newService(t)
+
+	o, e, _, err := s.CreateUser(ctx, io.CreateUserIn{Name: "Foobar"})
+	r.NoError(err)
+	r.Len(e, 1)
+	r.Equal(tickets.UserName("Foobar"), o.Name)
+
+	u, err := s.GetUserByID(ctx, o.ID)
+	r.NoError(err)
+	r.Equal(io.GetUserByIDOut{ID: o.ID, Name: "Foobar"}, u)
+
+	tx := store.NewTransactionReader()
+	defer tx.Complete()
+	v, err := store.ProjectionVersion(ctx, tx, generated.ProjectionNameUser)
+	r.NoError(err)
+	r.Equal("1", v)
+}
+
+func TestCreateUserErrReserved(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	s, _ := newService(t)
+
+	_, _, _, err := s.CreateUser(ctx, io.CreateUserIn{Name: "Foobar"})
+	r.NoError(err)
+
+	_, _, _, err = s.CreateUser(ctx, io.CreateUserIn{Name: "Foobar"})
+	r.Error(err)
+	r.Equal("username reserved", err.Error())
+}
+
+func TestGetUserByIDErrNotFound(t *testing.T) {
+	s, _ := newService(t)
+	_, err := s.GetUserByID(context.Background(), "unknown")
+	require.Error(t, err)
+	require.Equal(t, "user not found", err.Error())
+}
+
+func TestSyncApplyRetries(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := inmem.New(inmem.Options{})
+	b, err := generated.EncodeEventJSON(
+		generated.EventUserCreated{Id: "user_a", Name: "A"},
+	)
+	r.NoError(err)
+	_, _, _, err = l.AppendJSON(ctx, nil, b)
+	r.NoError(err)
+
+	failing, err := users.NewInmemSQLStore()
+	r.NoError(err)
+	srv := generated.NewServiceUsers(
+		users.New(),
+		&failingStore{Store: failing, failures: 1},
+		l,
+		nil,
+		generated.ServiceOptions{ApplyRetries: 1},
+	)
+	v, err := srv.Sync(ctx, nil)
+	r.NoError(err)
+	r.Equal("1", v)
+
+	// The failed attempt was rolled back to the savepoint
+	tx := failing.NewTransactionReader()
+	defer tx.Complete()
+	var n int
+	r.NoError(tx.Tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users_user`,
+	).Scan(&n))
+	r.Equal(1, n)
+}
+
+func TestSyncSkipRollback(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	l := inmem.New(inmem.Options{})
+	for _, e := range []generated.EventUserCreated{
+		{Id: "user_a", Name: "Foobar"},
+		{Id: "user_b", Name: "Barbaz"},
+	} {
+		b, err := generated.EncodeEventJSON(e)
+		r.NoError(err)
+		_, _, _, err = l.AppendJSON(ctx, nil, b)
+		r.NoError(err)
+	}
+
+	sink := &deadLetterSink{}
+	failing, err := users.NewInmemSQLStore()
+	r.NoError(err)
+	srv := generated.NewServiceUsers(
+		users.New(),
+		&failingStore{Store: failing, failures: 1},
+		l,
+		nil,
+		generated.ServiceOptions{
+			FailurePolicy:  generated.FailurePolicySkip,
+			DeadLetterSink: sink,
+		},
+	)
+	v, err := srv.Sync(ctx, nil)
+	r.NoError(err)
+	r.Equal("2", v)
+	r.Len(sink.deadLetters, 1)
+
+	// The insert of the skipped event was rolled back
+	_, err = srv.GetUserByID(ctx, "user_a")
+	r.Error(err)
+	o, err := srv.GetUserByID(ctx, "user_b")
+	r.NoError(err)
+	r.Equal(tickets.UserName("Barbaz"), o.Name)
+}
+
+// deadLetterSink records dead letters
+type deadLetterSink struct{ deadLetters []generated.DeadLetter }
+
+func (s *deadLetterSink) RecordDeadLetter(
+	_ context.Context,
+	d generated.DeadLetter,
+) error {
+	s.deadLetters = append(s.deadLetters, d)
+	return nil
+}
+
+func TestCreateUserSharedTx(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	store, err := users.NewInmemSQLStore()
+	r.NoError(err)
+
+	// The event log shares the database of the store
+	l, err := sqldb.New(ctx, store.DB(), sqldb.Options{})
+	r.NoError(err)
+
+	s := generated.NewServiceUsers(
+		users.New(), store, l, nil, generated.ServiceOptions{},
+	)
+	_, _, _, err = s.CreateUser(ctx, io.CreateUserIn{Name: "Foobar"})
+	r.NoError(err)
+	checkVersions := func(expected string) {
+		v, err := l.Version(ctx)
+		r.NoError(err)
+		r.Equal(expected, v)
+		pv, err := s.ProjectionVersion(ctx)
+		r.NoError(err)
+		r.Equal(expected, pv)
+	}
+	checkVersions("1")
+
+	// Applying the appended event fails after inserting the user
+	s = generated.NewServiceUsers(
+		users.New(),
+		&failingStore{Store: store, failures: 1},
+		l,
+		nil,
+		generated.ServiceOptions{},
+	)
+	_, _, _, err = s.CreateUser(ctx, io.CreateUserIn{Name: "Barbaz"})
+	r.Error(err)
+
+	// Both the append and the projection update were rolled back
+	checkVersions("1")
+	tx := store.NewTransactionReader()
+	defer tx.Complete()
+	var n int
+	r.NoError(tx.Tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users_user`,
+	).Scan(&n))
+	r.Equal(1, n)
+}
+
+func TestErrBeginTransaction(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	s, store := newService(t)
+	r.NoError(store.DB().Close())
+
+	// The methods fail instead of panicking
+	_, err := s.GetUserByID(ctx, "user_a")
+	r.Error(err)
+	r.Contains(err.Error(), "beginning read-only transaction")
+
+	_, _, _, err = s.CreateUser(ctx, io.CreateUserIn{Name: "Foobar"})
+	r.Error(err)
+	r.Contains(err.Error(), "beginning read-write transaction")
+
+	_, err = s.Sync(ctx, nil)
+	r.Error(err)
+}
+
+// failingStore fails a number of times after applying UserCreated
+type failingStore struct {
+	*users.Store
+	failures int
+}
+
+func (s *failingStore) ApplyEventUserCreatedToUser(
+	ctx context.Context,
+	tx *generated.SQLTransaction,
+	v generated.EventlogVersion,
+	tm time.Time,
+	e generated.EventUserCreated,
+) error {
+	if err := s.Store.ApplyEventUserCreatedToUser(
+		ctx, tx, v, tm, e,
+	); err != nil {
+		return err
+	}
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("apply failed")
+	}
+	return nil
+}
+
+func newService(t *testing.T) (*generated.ServiceUsers, *users.Store) {
+	store, err := users.NewInmemSQLStore()
+	require.NoError(t, err)
+	l := inmem.New(inmem.Options{Clock: func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	}})
+	return generated.NewServiceUsers(
+		users.New(), store, l, nil, generated.ServiceOptions{},
+	), store
+}
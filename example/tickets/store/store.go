@@ -0,0 +1,8 @@
+// Package store defines the types of the store transactions
+// of the services
+package store
+
+import "github.com/romshark/goesgen/inmemstore"
+
+// Transaction is the transaction type of the in-memory store handlers
+type Transaction = *inmemstore.Transaction
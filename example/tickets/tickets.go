@@ -1,11 +1,16 @@
 package tickets
 
-import "fmt"
+import (
+	"fmt"
+	"tickets/id"
+)
 
 type (
 	TicketDescription    string
 	TicketCommentMessage string
 	TicketTitle          string
+	TicketAssignees      []id.User
+	TicketComments       []id.Comment
 	UserName             string
 )
 
@@ -32,3 +37,13 @@ func ValidateUserName(v UserName) error {
 	}
 	return nil
 }
+
+// Contain returns true if u is among the assignees
+func (a TicketAssignees) Contain(u id.User) bool {
+	for _, x := range a {
+		if x == u {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+// Package exhaustive provides an analyzer reporting switches over
+// the events and projection states of packages generated by goesgen
+// that don't handle all of them.
+//
+// Type switches over the generated Event interface must have a case
+// for every event type of the schema, expression switches over
+// a generated projection state type must have a case for
+// every state of the projection. Adding an event or a state to the schema
+// therefore makes the analyzer report all switches that don't handle it.
+// A default case doesn't make a switch exhaustive unless
+// the analyzer is run with -default-signifies-exhaustive.
+package exhaustive
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// Analyzer reports non-exhaustive switches over generated
+// events and projection states
+var Analyzer = &analysis.Analyzer{
+	Name: "exhaustive",
+	Doc: "reports switches over the events and projection states " +
+		"of packages generated by goesgen that don't handle all of them",
+	Run:       run,
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	FactTypes: []analysis.Fact{new(generatedPackage)},
+}
+
+var defaultSignifiesExhaustive bool
+
+func init() {
+	Analyzer.Flags.BoolVar(
+		&defaultSignifiesExhaustive,
+		"default-signifies-exhaustive",
+		false,
+		"consider switches with a default case exhaustive",
+	)
+}
+
+// generatedPackage marks packages generated by goesgen
+type generatedPackage struct {
+	// Generator is the generator of the package
+	Generator string
+}
+
+func (*generatedPackage) AFact() {}
+
+func (*generatedPackage) String() string { return "generatedPackage" }
+
+var (
+	regexpGeneratedHeader = regexp.MustCompile(
+		`^// Code generated by (github\.com/romshark/goesgen) - DO NOT EDIT\.$`,
+	)
+	regexpProjectionState = regexp.MustCompile(`^Projection\w+State$`)
+)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	generatedFiles := map[*ast.File]bool{}
+	for _, f := range pass.Files {
+		if g := generator(f); g != "" {
+			generatedFiles[f] = true
+			pass.ExportPackageFact(&generatedPackage{Generator: g})
+		}
+	}
+
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.WithStack([]ast.Node{
+		(*ast.TypeSwitchStmt)(nil),
+		(*ast.SwitchStmt)(nil),
+	}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push || generatedFiles[stack[0].(*ast.File)] {
+			return true
+		}
+		switch n := n.(type) {
+		case *ast.TypeSwitchStmt:
+			checkTypeSwitch(pass, n)
+		case *ast.SwitchStmt:
+			checkSwitch(pass, n)
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// generator returns the generator of f if f was generated by goesgen,
+// otherwise returns an empty string
+func generator(f *ast.File) string {
+	for _, c := range f.Comments {
+		if c.Pos() > f.Package {
+			break
+		}
+		for _, l := range c.List {
+			if m := regexpGeneratedHeader.FindStringSubmatch(l.Text); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+// isGenerated returns true if pkg was generated by goesgen
+func isGenerated(pass *analysis.Pass, pkg *types.Package) bool {
+	return pkg != nil && pass.ImportPackageFact(pkg, new(generatedPackage))
+}
+
+// checkTypeSwitch reports type switches over the generated Event
+// interface that don't handle all event types
+func checkTypeSwitch(pass *analysis.Pass, s *ast.TypeSwitchStmt) {
+	var x ast.Expr
+	switch a := s.Assign.(type) {
+	case *ast.AssignStmt:
+		x = a.Rhs[0].(*ast.TypeAssertExpr).X
+	case *ast.ExprStmt:
+		x = a.X.(*ast.TypeAssertExpr).X
+	}
+	named, ok := pass.TypesInfo.TypeOf(x).(*types.Named)
+	if !ok || named.Obj().Name() != "Event" ||
+		!isGenerated(pass, named.Obj().Pkg()) {
+		return
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		return
+	}
+
+	// All named types of the generated package implementing Event
+	missing := map[string]types.Type{}
+	scope := named.Obj().Pkg().Scope()
+	for _, n := range scope.Names() {
+		t, ok := scope.Lookup(n).(*types.TypeName)
+		if !ok || t.IsAlias() || types.IsInterface(t.Type()) {
+			continue
+		}
+		if types.Implements(t.Type(), iface) {
+			missing[n] = t.Type()
+		}
+	}
+
+	for _, c := range s.Body.List {
+		c := c.(*ast.CaseClause)
+		if c.List == nil && defaultSignifiesExhaustive {
+			return
+		}
+		for _, e := range c.List {
+			t := pass.TypesInfo.TypeOf(e)
+			for n, x := range missing {
+				if t != nil && types.Identical(t, x) {
+					delete(missing, n)
+				}
+			}
+		}
+	}
+	report(pass, s, named, missing)
+}
+
+// checkSwitch reports expression switches over generated projection
+// state types that don't handle all states
+func checkSwitch(pass *analysis.Pass, s *ast.SwitchStmt) {
+	if s.Tag == nil {
+		return
+	}
+	named, ok := pass.TypesInfo.TypeOf(s.Tag).(*types.Named)
+	if !ok || !regexpProjectionState.MatchString(named.Obj().Name()) ||
+		!isGenerated(pass, named.Obj().Pkg()) {
+		return
+	}
+
+	// All constants of the state type
+	missing := map[string]types.Type{}
+	scope := named.Obj().Pkg().Scope()
+	for _, n := range scope.Names() {
+		c, ok := scope.Lookup(n).(*types.Const)
+		if ok && types.Identical(c.Type(), named) {
+			missing[n] = named
+		}
+	}
+
+	for _, c := range s.Body.List {
+		c := c.(*ast.CaseClause)
+		if c.List == nil && defaultSignifiesExhaustive {
+			return
+		}
+		for _, e := range c.List {
+			if c := constOf(pass, e); c != nil {
+				delete(missing, c.Name())
+			}
+		}
+	}
+	report(pass, s, named, missing)
+}
+
+// constOf returns the constant referenced by e, nil if none
+func constOf(pass *analysis.Pass, e ast.Expr) *types.Const {
+	var id *ast.Ident
+	switch e := e.(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		id = e.Sel
+	case *ast.ParenExpr:
+		return constOf(pass, e.X)
+	default:
+		return nil
+	}
+	c, _ := pass.TypesInfo.Uses[id].(*types.Const)
+	return c
+}
+
+func report(
+	pass *analysis.Pass,
+	s ast.Node,
+	t *types.Named,
+	missing map[string]types.Type,
+) {
+	if len(missing) < 1 {
+		return
+	}
+	names := make([]string, 0, len(missing))
+	for n := range missing {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	pass.Reportf(
+		s.Pos(), "missing cases in switch of type %s.%s: %s",
+		t.Obj().Pkg().Name(), t.Obj().Name(), strings.Join(names, ", "),
+	)
+}
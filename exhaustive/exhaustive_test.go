@@ -0,0 +1,22 @@
+package exhaustive_test
+
+import (
+	"testing"
+
+	"github.com/romshark/goesgen/exhaustive"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), exhaustive.Analyzer, "a")
+}
+
+func TestAnalyzerDefaultSignifiesExhaustive(t *testing.T) {
+	f := exhaustive.Analyzer.Flags.Lookup("default-signifies-exhaustive")
+	if err := f.Value.Set("true"); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Value.Set("false")
+	analysistest.Run(t, analysistest.TestData(), exhaustive.Analyzer, "b")
+}
@@ -0,0 +1,43 @@
+package a
+
+import "generated"
+
+func events(e generated.Event) {
+	switch e.(type) { // want `missing cases in switch of type generated.Event: EventB`
+	case generated.EventA:
+	}
+
+	switch e := e.(type) { // want `missing cases in switch of type generated.Event: EventA, EventB`
+	case nil:
+		_ = e
+	default:
+	}
+
+	switch e.(type) {
+	case generated.EventA, generated.EventB:
+	}
+}
+
+func states(s generated.ProjectionPState) {
+	switch s { // want `missing cases in switch of type generated.ProjectionPState: ProjectionPStateY`
+	case generated.ProjectionPStateX:
+	}
+
+	switch s {
+	case generated.ProjectionPStateX:
+	case generated.ProjectionPStateY:
+	}
+
+	// Switches over other types aren't checked
+	switch string(s) {
+	case "X":
+	}
+}
+
+type Event interface{ isEvent() }
+
+// Event types of other packages aren't checked
+func other(e Event) {
+	switch e.(type) {
+	}
+}
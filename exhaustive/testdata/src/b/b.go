@@ -0,0 +1,16 @@
+package b
+
+import "generated"
+
+func events(e generated.Event) {
+	switch e.(type) {
+	case generated.EventA:
+	default:
+	}
+}
+
+func states(s generated.ProjectionPState) {
+	switch s {
+	default:
+	}
+}
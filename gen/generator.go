@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"unicode"
 )
 
 type Generator struct {
@@ -24,18 +25,46 @@ var tmplEvents string
 //go:embed tmpl_event_codec.gtpl
 var tmplEventCodec string
 
+//go:embed tmpl_streams.gtpl
+var tmplStreams string
+
 //go:embed tmpl_projections.gtpl
 var tmplProjections string
 
+//go:embed tmpl_inmem_stores.gtpl
+var tmplInmemStores string
+
+//go:embed tmpl_sql_stores.gtpl
+var tmplSQLStores string
+
 //go:embed tmpl_services.gtpl
 var tmplServices string
 
+//go:embed tmpl_tenants.gtpl
+var tmplTenants string
+
+//go:embed tmpl_relay.gtpl
+var tmplRelay string
+
+//go:embed tmpl_consumer.gtpl
+var tmplConsumer string
+
+//go:embed tmpl_processes.gtpl
+var tmplProcesses string
+
 func NewGenerator() *Generator {
 	t := template.Must(template.New("generated").Parse(tmplGenerated))
 	template.Must(t.Parse(tmplEvents))
 	template.Must(t.Parse(tmplEventCodec))
+	template.Must(t.Parse(tmplStreams))
 	template.Must(t.Parse(tmplProjections))
 	template.Must(t.Parse(tmplServices))
+	template.Must(t.Parse(tmplTenants))
+	template.Must(t.Parse(tmplInmemStores))
+	template.Must(t.Parse(tmplSQLStores))
+	template.Must(t.Parse(tmplRelay))
+	template.Must(t.Parse(tmplConsumer))
+	template.Must(t.Parse(tmplProcesses))
 	return &Generator{
 		tmpl: t,
 	}
@@ -50,6 +79,21 @@ func (g *Generator) Generate(
 		return "", fmt.Errorf("preparing options: %w", err)
 	}
 
+	if options.ExcludeProjections {
+		for _, s := range schema.Services {
+			if len(s.Timers) > 0 {
+				return "", fmt.Errorf(
+					"timers of service %s require projections", s.Name,
+				)
+			}
+			if s.Store != "" {
+				return "", fmt.Errorf(
+					"store of service %s requires projections", s.Name,
+				)
+			}
+		}
+	}
+
 	outPackagePath = filepath.Join(outputPath, options.PackageName)
 	if err := os.MkdirAll(outPackagePath, 0777); err != nil {
 		return "", fmt.Errorf("setting up %s: %w", outPackagePath, err)
@@ -126,6 +170,71 @@ type templateContext struct {
 	Schema  *Schema
 }
 
+// KeyedProjections returns true if the generated package
+// contains at least one projection declaring a key
+func (c templateContext) KeyedProjections() bool {
+	if c.Options.ExcludeProjections {
+		return false
+	}
+	for _, p := range c.Schema.Projections {
+		if p.Key != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SQLStores returns true if the generated package
+// contains at least one SQL store handler
+func (c templateContext) SQLStores() bool {
+	if c.Options.ExcludeProjections {
+		return false
+	}
+	for _, s := range c.Schema.Services {
+		if s.Store == "sql" {
+			return true
+		}
+	}
+	return false
+}
+
+// TransactionType returns the type of the store transactions
+// of service s, or an empty string if the transactions aren't typed
+func (c templateContext) TransactionType(s *Service) string {
+	if s.Store == "sql" {
+		return "*SQLTransaction"
+	}
+	if s.Transaction != nil {
+		return c.TypeID(s.Transaction)
+	}
+	return ""
+}
+
+// TransactionViewType returns the type of the read-only views
+// of the store transactions of service s passed to its command,
+// timer and read-only methods
+func (c templateContext) TransactionViewType(s *Service) string {
+	if s.Store == "sql" {
+		return "SQLReadOnlyView"
+	}
+	return c.TransactionType(s)
+}
+
+// SnakeCase converts camel and pascal case identifiers to snake case
+func (templateContext) SnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 func (templateContext) Capitalize(s string) string {
 	return strings.Title(s)
 }
@@ -83,6 +83,51 @@ func TestGenerate(t *testing.T) {
 	r.NoError(cmd.Run(), errOut.String())
 }
 
+func TestGenerateSQLStore(t *testing.T) {
+	r := require.New(t)
+	root, files := Setup(t, Files{
+		"schema.yaml": SQLStoreSchemaYAML,
+		"src.go":      SQLStoreSrcGO,
+		"go.mod":      ValidSchemaGoMOD,
+	})
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r.NoError(err)
+
+	outPkgPath, err := gen.NewGenerator().Generate(
+		schema, root, gen.GeneratorOptions{PackageName: "generated"},
+	)
+	r.NoError(err)
+	b, err := os.ReadFile(filepath.Join(outPkgPath, "generated.go"))
+	r.NoError(err)
+	r.Contains(string(b), "type SQLServiceS1Store struct")
+	r.Contains(string(b), "score DOUBLE PRECISION NOT NULL")
+	r.Contains(string(b), "func (s *ServiceS1) readOnlyView("+
+		"trx *SQLTransaction) SQLReadOnlyView {\n\treturn trx.ReadOnlyView()")
+	r.Contains(string(b), "type SQLReadOnlyView struct{ tx *sql.Tx }")
+	r.NotContains(string(b), "func (v SQLReadOnlyView) Exec")
+}
+
+func TestGenerateErrExcludeProjectionsStore(t *testing.T) {
+	r := require.New(t)
+	root, files := Setup(t, Files{
+		"schema.yaml": SQLStoreSchemaYAML,
+		"src.go":      SQLStoreSrcGO,
+		"go.mod":      ValidSchemaGoMOD,
+	})
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r.NoError(err)
+
+	_, err = gen.NewGenerator().Generate(
+		schema, root, gen.GeneratorOptions{
+			PackageName:        "generated",
+			ExcludeProjections: true,
+		},
+	)
+	r.Error(err)
+	r.Equal("store of service S1 requires projections", err.Error())
+	r.NoDirExists(filepath.Join(root, "generated"))
+}
+
 func AssumeFilesExist(t *testing.T, root string, expected ...string) {
 	unexpected := []string{}
 	require.NoError(t, filepath.Walk(
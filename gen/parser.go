@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"go/token"
+	"go/types"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"golang.org/x/tools/go/packages"
@@ -20,18 +23,49 @@ import (
 type (
 	ModelSchema struct {
 		Events      map[EventName]ModelEvent           `yaml:"events"`
+		Streams     map[StreamName]ModelStream         `yaml:"streams"`
 		Projections map[ProjectionName]ModelProjection `yaml:"projections"`
 		Services    map[ServiceName]ModelService       `yaml:"services"`
+		Processes   map[ProcessName]ModelProcess       `yaml:"processes"`
+		Tenant      *TypeID                            `yaml:"tenant"`
+	}
+	ModelProcess struct {
+		Key   TypeID                     `yaml:"key"`
+		State TypeID                     `yaml:"state"`
+		On    map[EventName]PropertyName `yaml:"on"`
+		Calls []string                   `yaml:"calls"`
+		Emits []EventName                `yaml:"emits"`
+	}
+	ModelStream struct {
+		Key    TypeID                     `yaml:"key"`
+		Events map[EventName]PropertyName `yaml:"events"`
 	}
 	ModelProjection struct {
 		States      []ProjectionState               `yaml:"states"`
 		Properties  ModelProperties                 `yaml:"properties"`
+		Key         PropertyName                    `yaml:"key"`
+		Indexes     []PropertyName                  `yaml:"indexes"`
 		CreateOn    EventName                       `yaml:"createOn"`
 		Transitions map[EventName][]ModelTransition `yaml:"transitions"`
+		Apply       map[EventName]ModelMapping      `yaml:"apply"`
+	}
+	ModelMapping struct {
+		Set    map[PropertyName]PropertyName `yaml:"set"`
+		Append map[PropertyName]PropertyName `yaml:"append"`
+		State  ProjectionState               `yaml:"state"`
 	}
 	ModelService struct {
-		Projections []ProjectionName    `yaml:"projections"`
-		Methods     ModelServiceMethods `yaml:"methods"`
+		Projections []ProjectionName         `yaml:"projections"`
+		Methods     ModelServiceMethods      `yaml:"methods"`
+		Timers      map[TimerName]ModelTimer `yaml:"timers"`
+		Store       ServiceStore             `yaml:"store"`
+		Transaction *TypeID                  `yaml:"transaction"`
+	}
+	ModelTimer struct {
+		Projection ProjectionName  `yaml:"projection"`
+		State      ProjectionState `yaml:"state"`
+		After      string          `yaml:"after"`
+		Emits      EventName       `yaml:"emits"`
 	}
 	ModelServiceMethod struct {
 		Pos          int
@@ -40,6 +74,7 @@ type (
 		Output       *TypeID           `yaml:"out"`
 		Type         ServiceMethodType `yaml:"type"`
 		Emits        []EventName       `yaml:"emits"`
+		Streams      []StreamName      `yaml:"streams"`
 	}
 	ModelEvent      = ModelProperties
 	ModelProperties struct {
@@ -56,7 +91,11 @@ type (
 	ModelTransition   = string
 	ServiceMethodName = string
 	ServiceMethodType = string
+	ServiceStore      = string
 	ServiceName       = string
+	ProcessName       = string
+	TimerName         = string
+	StreamName        = string
 	EventName         = string
 	ProjectionName    = string
 	ProjectionState   = string
@@ -147,10 +186,20 @@ func (m *ModelServiceMethods) UnmarshalYAML(v *yaml.Node) error {
 				for i, c := range methodNode.Content[i].Content {
 					v.Emits[i] = c.Value
 				}
+			case "streams":
+				i++
+				v.Streams = make(
+					[]StreamName,
+					len(methodNode.Content[i].Content),
+				)
+				for i, c := range methodNode.Content[i].Content {
+					v.Streams[i] = c.Value
+				}
 			default:
 				return fmt.Errorf(
 					`unexpected field %q (expected either of %q) at %d:%d`,
-					c.Value, "in, out, type, emits", c.Line, c.Column,
+					c.Value, "in, out, type, emits, streams",
+					c.Line, c.Column,
 				)
 			}
 		}
@@ -173,11 +222,17 @@ type (
 	Schema struct {
 		Raw            string
 		Events         map[EventName]*Event
+		Streams        map[StreamName]*Stream
 		Projections    map[ProjectionName]*Projection
 		Services       map[ServiceName]*Service
+		Processes      map[ProcessName]*Process
 		SourcePackages map[SourcePackageID]*SourcePackage
 		SourcePackage  *SourcePackage
 		SourceModule   string
+
+		// Tenant is the type identifying tenants,
+		// nil if the schema isn't multi-tenant
+		Tenant *Type
 	}
 	Type struct {
 		ID             string
@@ -185,6 +240,10 @@ type (
 		Package        *SourcePackage
 		SourceLocation token.Position
 		References     []interface{}
+
+		// Underlying is the underlying Go type,
+		// nil until the source packages are parsed
+		Underlying types.Type
 	}
 	Projection struct {
 		Schema       *Schema
@@ -194,6 +253,21 @@ type (
 		Properties   []*Property
 		CreateOn     *Event
 		Transitions  map[*Event][]*Transition
+
+		// Key is the property identifying projection instances,
+		// nil if the projection doesn't declare a key
+		Key *Property
+
+		// Indexes lists the properties instances can be listed by
+		Indexes []*Property
+
+		// Stream is the stream all events of the projection belong to,
+		// nil if the projection doesn't declare a key
+		Stream *Stream
+
+		// Mappings maps events to the declarative mappings
+		// applying them to the projection
+		Mappings map[*Event]*Mapping
 	}
 	Service struct {
 		Schema        *Schema
@@ -201,6 +275,27 @@ type (
 		Projections   []*Projection
 		Methods       map[ServiceMethodName]*ServiceMethod
 		Subscriptions map[EventName]*Event
+		Timers        map[TimerName]*Timer
+
+		// Store is the kind of store handler scaffolding generated
+		// for the service, empty if none
+		Store ServiceStore
+
+		// Transaction is the type of the store transactions
+		// of the service, nil if the transactions aren't typed
+		Transaction *Type
+	}
+	Timer struct {
+		Service    *Service
+		Name       TimerName
+		Projection *Projection
+		State      ProjectionState
+		After      time.Duration
+		Emits      *Event
+
+		// Stream is the stream all events of the projection belong to,
+		// its key identifies the projection instance
+		Stream *Stream
 	}
 	ServiceMethod struct {
 		Service      *Service
@@ -209,6 +304,7 @@ type (
 		Input        *Type
 		Output       *Type
 		Emits        []*Event
+		Streams      []*Stream
 		CommentLines []string
 	}
 	Property struct {
@@ -222,6 +318,59 @@ type (
 		Name       string
 		Properties []*Property
 		References []interface{}
+
+		// Stream is the stream the event belongs to, nil if none
+		Stream *Stream
+
+		// StreamKey is the property identifying the stream, nil if none
+		StreamKey *Property
+	}
+	Stream struct {
+		Schema *Schema
+		Name   StreamName
+		Key    *Type
+		Events map[*Event]*Property
+	}
+	Process struct {
+		Schema *Schema
+		Name   ProcessName
+		Key    *Type
+		State  *Type
+
+		// On maps the names of the events the process reacts to
+		// to the properties correlating them with a process instance
+		On map[EventName]*ProcessTrigger
+
+		// Calls maps service names to the methods the process may call
+		Calls map[ServiceName][]*ServiceMethod
+
+		// Emits lists the events the process may append directly
+		Emits []*Event
+	}
+	ProcessTrigger struct {
+		Event *Event
+		Key   *Property
+	}
+	Mapping struct {
+		Projection *Projection
+		On         *Event
+
+		// Set lists the properties set to event properties
+		Set []*PropertyMapping
+
+		// Append lists the list properties event properties are appended to
+		Append []*PropertyMapping
+
+		// State is the state the projection instance transitions to,
+		// empty if the state doesn't change
+		State ProjectionState
+	}
+	PropertyMapping struct {
+		// Property is the projection property
+		Property *Property
+
+		// From is the event property
+		From *Property
 	}
 	Transition struct {
 		Projection *Projection
@@ -239,6 +388,18 @@ func ValidateProjectionState(n ProjectionState) error {
 	return ValidatePascalCase(n)
 }
 
+func ValidateStreamName(n StreamName) error {
+	return ValidatePascalCase(n)
+}
+
+func ValidateProcessName(n ProcessName) error {
+	return ValidatePascalCase(n)
+}
+
+func ValidateTimerName(n TimerName) error {
+	return ValidatePascalCase(n)
+}
+
 func ValidateServiceName(n ServiceName) error {
 	return ValidatePascalCase(n)
 }
@@ -372,6 +533,71 @@ func parseEventProperties(
 	return nil
 }
 
+func parseStreams(
+	ctx context,
+	m map[StreamName]ModelStream,
+) error {
+	ctx.schema.Streams = make(map[StreamName]*Stream, len(m))
+	for n, sm := range m {
+		ctx := ctx.Subcontext(n)
+
+		if err := ValidateStreamName(n); err != nil {
+			return ctx.syntaxErr("invalid stream name (%q): %s", n, err)
+		}
+		st := &Stream{
+			Schema: ctx.schema,
+			Name:   n,
+			Events: make(map[*Event]*Property, len(sm.Events)),
+		}
+
+		kt, err := registerReferencedType(ctx.Subcontext("key"), sm.Key)
+		if err != nil {
+			return err
+		}
+		st.Key = kt
+		kt.References = append(kt.References, st)
+
+		if len(sm.Events) < 1 {
+			return ctx.Subcontext("events").semanticErr("missing events")
+		}
+		for en, pn := range sm.Events {
+			ctx := ctx.Subcontext("events", en)
+			e, ok := ctx.schema.Events[en]
+			if !ok {
+				return ctx.semanticErr("undefined event (%q)", en)
+			}
+			if e.Stream != nil {
+				return ctx.semanticErr(
+					"event %s already belongs to stream %s",
+					e.Name, e.Stream.Name,
+				)
+			}
+			var key *Property
+			for _, p := range e.Properties {
+				if p.Name == pn {
+					key = p
+					break
+				}
+			}
+			if key == nil {
+				return ctx.semanticErr("undefined event property (%q)", pn)
+			}
+			if key.Type != st.Key {
+				return ctx.semanticErr(
+					"type of property %s (%s) doesn't match "+
+						"the stream key type (%s)",
+					pn, key.Type.ID, st.Key.ID,
+				)
+			}
+			e.Stream, e.StreamKey = st, key
+			st.Events[e] = key
+		}
+
+		ctx.schema.Streams[n] = st
+	}
+	return nil
+}
+
 func parseProjectionStates(
 	ctx context,
 	p *Projection,
@@ -451,6 +677,195 @@ func parseProjectionTransitions(
 	return nil
 }
 
+func parseProjectionKey(
+	ctx context,
+	p *Projection,
+	m *ModelProjection,
+) error {
+	if m.Key == "" {
+		return nil
+	}
+	p.Key = p.Property(m.Key)
+	if p.Key == nil {
+		return ctx.semanticErr("undefined property (%q)", m.Key)
+	}
+
+	// All events of the projection must belong to the same stream
+	// keyed by the key property type for the projection instances
+	// to be identifiable
+	for _, e := range p.Events() {
+		if e.Stream == nil {
+			return ctx.semanticErr(
+				"event %s doesn't belong to any stream", e.Name,
+			)
+		}
+		if p.Stream == nil {
+			p.Stream = e.Stream
+		} else if e.Stream != p.Stream {
+			return ctx.semanticErr(
+				"events belong to multiple streams (%s, %s)",
+				p.Stream.Name, e.Stream.Name,
+			)
+		}
+	}
+	if p.Stream.Key != p.Key.Type {
+		return ctx.semanticErr(
+			"type of property %s (%s) doesn't match "+
+				"the key type of stream %s (%s)",
+			p.Key.Name, p.Key.Type.ID, p.Stream.Name, p.Stream.Key.ID,
+		)
+	}
+	return nil
+}
+
+func parseProjectionIndexes(
+	ctx context,
+	p *Projection,
+	m *ModelProjection,
+) error {
+	if len(m.Indexes) > 0 && p.Key == nil {
+		return ctx.semanticErr("indexes require a projection key")
+	}
+	p.Indexes = make([]*Property, len(m.Indexes))
+	for i, n := range m.Indexes {
+		ctx := ctx.Subcontext(strconv.Itoa(i))
+		x := p.Property(n)
+		if x == nil {
+			return ctx.semanticErr("undefined property (%q)", n)
+		}
+		if x == p.Key {
+			return ctx.semanticErr("property %s is the key", n)
+		}
+		for _, y := range p.Indexes[:i] {
+			if y == x {
+				return ctx.semanticErr("duplicate index (%q)", n)
+			}
+		}
+		p.Indexes[i] = x
+	}
+	return nil
+}
+
+func parseProjectionMappings(
+	ctx context,
+	p *Projection,
+	m *ModelProjection,
+) error {
+	if len(m.Apply) > 0 && p.Key == nil {
+		return ctx.semanticErr("mappings require a projection key")
+	}
+	p.Mappings = make(map[*Event]*Mapping, len(m.Apply))
+	for en, v := range m.Apply {
+		ctx := ctx.Subcontext(en)
+
+		e, ok := ctx.schema.Events[en]
+		if !ok {
+			return ctx.semanticErr("undefined event (%q)", en)
+		}
+		if e != p.CreateOn && p.Transitions[e] == nil {
+			return ctx.semanticErr(
+				"event %s neither creates nor transitions %s", en, p.Name,
+			)
+		}
+		x := &Mapping{Projection: p, On: e, State: v.State}
+
+		parse := func(
+			ctx context,
+			m map[PropertyName]PropertyName,
+		) ([]*PropertyMapping, error) {
+			l := make([]*PropertyMapping, 0, len(m))
+			for pn, epn := range m {
+				ctx := ctx.Subcontext(pn)
+				pp := p.Property(pn)
+				if pp == nil {
+					return nil, ctx.semanticErr(
+						"undefined property (%q)", pn,
+					)
+				}
+				if pp == p.Key {
+					return nil, ctx.semanticErr(
+						"property %s is the key", pn,
+					)
+				}
+				ep := e.Property(epn)
+				if ep == nil {
+					return nil, ctx.semanticErr(
+						"undefined property of event %s (%q)", en, epn,
+					)
+				}
+				l = append(l, &PropertyMapping{Property: pp, From: ep})
+			}
+			sort.Slice(l, func(i, j int) bool {
+				return l[i].Property.Position < l[j].Property.Position
+			})
+			return l, nil
+		}
+		var err error
+		if x.Set, err = parse(ctx.Subcontext("set"), v.Set); err != nil {
+			return err
+		}
+		for _, s := range x.Set {
+			if s.Property.Type != s.From.Type {
+				return ctx.Subcontext("set", s.Property.Name).semanticErr(
+					"type of property %s (%s) doesn't match "+
+						"the type of event property %s (%s)",
+					s.Property.Name, s.Property.Type.ID,
+					s.From.Name, s.From.Type.ID,
+				)
+			}
+		}
+		if x.Append, err = parse(
+			ctx.Subcontext("append"), v.Append,
+		); err != nil {
+			return err
+		}
+		for _, a := range x.Append {
+			if _, ok := v.Set[a.Property.Name]; ok {
+				return ctx.Subcontext("append", a.Property.Name).semanticErr(
+					"property %s is both set and appended to",
+					a.Property.Name,
+				)
+			}
+		}
+
+		// The mapped state must be a legal transition from all states
+		// the event transitions from
+		ctxState := ctx.Subcontext("state")
+		if e == p.CreateOn {
+			if x.State != "" && x.State != p.InitialState {
+				return ctxState.semanticErr(
+					"event %s creates %s in state %s",
+					en, p.Name, p.InitialState,
+				)
+			}
+			x.State = ""
+		} else {
+			if x.State != "" {
+				if _, ok := p.States[x.State]; !ok {
+					return ctxState.semanticErr(
+						"undefined state (%q)", x.State,
+					)
+				}
+			}
+			for _, t := range p.Transitions[e] {
+				to := x.State
+				if to == "" {
+					to = t.From
+				}
+				if !p.HasTransition(e, t.From, to) {
+					return ctxState.semanticErr(
+						"undefined transition (%s -> %s) on event %s",
+						t.From, to, en,
+					)
+				}
+			}
+		}
+
+		p.Mappings[e] = x
+	}
+	return nil
+}
+
 func parseProjections(
 	ctx context,
 	m map[ProjectionName]ModelProjection,
@@ -497,6 +912,21 @@ func parseProjections(
 				)
 			}
 		}
+		if err := parseProjectionKey(
+			ctx.Subcontext("key"), p, &pm,
+		); err != nil {
+			return err
+		}
+		if err := parseProjectionIndexes(
+			ctx.Subcontext("indexes"), p, &pm,
+		); err != nil {
+			return err
+		}
+		if err := parseProjectionMappings(
+			ctx.Subcontext("apply"), p, &pm,
+		); err != nil {
+			return err
+		}
 
 		ctx.schema.Projections[p.Name] = p
 	}
@@ -507,12 +937,28 @@ func parseSchema(
 	ctx context,
 	m *ModelSchema,
 ) error {
+	if m.Tenant != nil {
+		t, err := registerReferencedType(
+			ctx.Subcontext("tenant"), *m.Tenant,
+		)
+		if err != nil {
+			return err
+		}
+		t.References = append(t.References, ctx.schema)
+		ctx.schema.Tenant = t
+	}
 	if err := parseEvents(
 		ctx.Subcontext("events"),
 		m.Events,
 	); err != nil {
 		return err
 	}
+	if err := parseStreams(
+		ctx.Subcontext("streams"),
+		m.Streams,
+	); err != nil {
+		return err
+	}
 	if err := parseProjections(
 		ctx.Subcontext("projections"),
 		m.Projections,
@@ -525,6 +971,12 @@ func parseSchema(
 	); err != nil {
 		return err
 	}
+	if err := parseProcesses(
+		ctx.Subcontext("processes"),
+		m.Processes,
+	); err != nil {
+		return err
+	}
 
 	for _, e := range ctx.schema.Events {
 		if len(e.References) < 1 {
@@ -685,6 +1137,13 @@ func parseServiceMethods(
 		); err != nil {
 			return err
 		}
+		if err := parseServiceMethodStreams(
+			ctx.Subcontext("streams"),
+			m,
+			model.Streams,
+		); err != nil {
+			return err
+		}
 		v.Methods[name] = m
 	}
 	return nil
@@ -726,6 +1185,62 @@ func parseServiceMethodType(
 	return nil
 }
 
+func parseServiceMethodStreams(
+	ctx context,
+	m *ServiceMethod,
+	streams []StreamName,
+) error {
+	if len(streams) < 1 {
+		return nil
+	}
+	if m.Type != "transaction" {
+		return ctx.semanticErr(
+			"streams are only allowed for transaction methods",
+		)
+	}
+	m.Streams = make([]*Stream, len(streams))
+	for i, n := range streams {
+		st, ok := ctx.schema.Streams[n]
+		if !ok {
+			return ctx.Subcontext(strconv.Itoa(i)).
+				semanticErr("undefined stream (%q)", n)
+		}
+		for _, x := range m.Streams[:i] {
+			if x == st {
+				return ctx.Subcontext(strconv.Itoa(i)).
+					semanticErr("duplicate stream (%q)", n)
+			}
+		}
+		m.Streams[i] = st
+	}
+
+	// Make sure the stream of every emitted event is known
+	for _, e := range m.Emits {
+		if e.Stream == nil {
+			return ctx.semanticErr(
+				"emitted event %s doesn't belong to any stream", e.Name,
+			)
+		}
+		if !m.InStreams(e) {
+			return ctx.semanticErr(
+				"emitted event %s belongs to undeclared stream %s",
+				e.Name, e.Stream.Name,
+			)
+		}
+	}
+	return nil
+}
+
+// InStreams returns true if e belongs to either of the streams of m.
+func (m *ServiceMethod) InStreams(e *Event) bool {
+	for _, s := range m.Streams {
+		if e.Stream == s {
+			return true
+		}
+	}
+	return false
+}
+
 func parseServiceMethodEmits(
 	ctx context,
 	m *ServiceMethod,
@@ -768,6 +1283,28 @@ func parseServices(
 			Schema: ctx.schema,
 			Name:   n,
 		}
+		switch v.Store {
+		case "", "sql":
+			sv.Store = v.Store
+		default:
+			return ctx.Subcontext("store").syntaxErr(
+				"invalid store (%q), expected sql", v.Store,
+			)
+		}
+		if v.Transaction != nil {
+			ctx := ctx.Subcontext("transaction")
+			if sv.Store != "" {
+				return ctx.semanticErr(
+					"store %s defines the transaction type", sv.Store,
+				)
+			}
+			t, err := registerReferencedType(ctx, *v.Transaction)
+			if err != nil {
+				return err
+			}
+			t.References = append(t.References, sv)
+			sv.Transaction = t
+		}
 		if err := parseServiceProjections(
 			ctx.Subcontext("projections"), sv, &v,
 		); err != nil {
@@ -788,11 +1325,375 @@ func parseServices(
 			}
 		}
 
+		if err := parseServiceTimers(
+			ctx.Subcontext("timers"), sv, v.Timers,
+		); err != nil {
+			return err
+		}
+
 		ctx.schema.Services[n] = sv
 	}
 	return nil
 }
 
+func parseServiceTimers(
+	ctx context,
+	sv *Service,
+	m map[TimerName]ModelTimer,
+) error {
+	sv.Timers = make(map[TimerName]*Timer, len(m))
+	for n, v := range m {
+		ctx := ctx.Subcontext(n)
+
+		if err := ValidateTimerName(n); err != nil {
+			return ctx.syntaxErr("invalid timer name (%q): %s", n, err)
+		}
+		t := &Timer{
+			Service: sv,
+			Name:    n,
+			State:   v.State,
+		}
+
+		for _, p := range sv.Projections {
+			if p.Name == v.Projection {
+				t.Projection = p
+				break
+			}
+		}
+		if t.Projection == nil {
+			return ctx.Subcontext("projection").semanticErr(
+				"projection (%q) isn't projected by the service",
+				v.Projection,
+			)
+		}
+		if _, ok := t.Projection.States[v.State]; !ok {
+			return ctx.Subcontext("state").semanticErr(
+				"undefined state (%q)", v.State,
+			)
+		}
+
+		d, err := time.ParseDuration(v.After)
+		if err != nil {
+			return ctx.Subcontext("after").syntaxErr(
+				"invalid duration (%q): %s", v.After, err,
+			)
+		}
+		if d <= 0 {
+			return ctx.Subcontext("after").semanticErr(
+				"non-positive duration (%s)", d,
+			)
+		}
+		t.After = d
+
+		// All events of the projection must belong to the same stream
+		// for the projection instances to be identifiable
+		for _, e := range t.Projection.Events() {
+			if e.Stream == nil {
+				return ctx.semanticErr(
+					"event %s of projection %s doesn't belong to any stream",
+					e.Name, t.Projection.Name,
+				)
+			}
+			if t.Stream == nil {
+				t.Stream = e.Stream
+			} else if e.Stream != t.Stream {
+				return ctx.semanticErr(
+					"events of projection %s belong to multiple streams "+
+						"(%s, %s)",
+					t.Projection.Name, t.Stream.Name, e.Stream.Name,
+				)
+			}
+		}
+
+		ctxEmits := ctx.Subcontext("emits")
+		e, ok := ctx.schema.Events[v.Emits]
+		if !ok {
+			return ctxEmits.semanticErr("undefined event (%q)", v.Emits)
+		}
+		leaves := false
+		for _, tr := range t.Projection.Transitions[e] {
+			if tr.From != t.State {
+				continue
+			}
+			if tr.To == t.State {
+				return ctxEmits.semanticErr(
+					"event %s mustn't transition %s to itself",
+					e.Name, t.State,
+				)
+			}
+			leaves = true
+		}
+		if !leaves {
+			return ctxEmits.semanticErr(
+				"event %s doesn't transition projection %s from state %s",
+				e.Name, t.Projection.Name, t.State,
+			)
+		}
+		t.Emits = e
+		e.References = append(e.References, t)
+
+		sv.Timers[n] = t
+	}
+	return nil
+}
+
+// Events returns the events creating and transitioning p
+func (p *Projection) Events() []*Event {
+	e := []*Event{p.CreateOn}
+	for t := range p.Transitions {
+		if t != p.CreateOn {
+			e = append(e, t)
+		}
+	}
+	sort.Slice(e, func(i, j int) bool { return e[i].Name < e[j].Name })
+	return e
+}
+
+// Property returns the property of e by name, nil if there's none
+func (e *Event) Property(n PropertyName) *Property {
+	for _, x := range e.Properties {
+		if x.Name == n {
+			return x
+		}
+	}
+	return nil
+}
+
+// HasTransition returns true if e transitions p from -> to
+func (p *Projection) HasTransition(
+	e *Event,
+	from, to ProjectionState,
+) bool {
+	for _, t := range p.Transitions[e] {
+		if t.From == from && t.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Property returns the property of p by name, nil if there's none
+func (p *Projection) Property(n PropertyName) *Property {
+	for _, x := range p.Properties {
+		if x.Name == n {
+			return x
+		}
+	}
+	return nil
+}
+
+// TimedProjections returns the projections of s timers are tied to
+// mapped to the streams identifying their instances
+func (s *Service) TimedProjections() map[ProjectionName]*Stream {
+	m := make(map[ProjectionName]*Stream, len(s.Timers))
+	for _, t := range s.Timers {
+		m[t.Projection.Name] = t.Stream
+	}
+	return m
+}
+
+// ProjectionsKeyed returns true if s projects at least one projection
+// and all of its projections declare a key
+func (s *Service) ProjectionsKeyed() bool {
+	for _, p := range s.Projections {
+		if p.Key == nil {
+			return false
+		}
+	}
+	return len(s.Projections) > 0
+}
+
+// ProjectionsOn returns the projections of s affected by e
+// InmemStore returns true if an in-memory store handler
+// is generated for s, which requires all projections to be keyed
+// and the transactions to be either untyped or of type
+// *inmemstore.Transaction
+func (s *Service) InmemStore() bool {
+	if !s.ProjectionsKeyed() || s.Store != "" {
+		return false
+	}
+	return s.Transaction == nil ||
+		types.TypeString(s.Transaction.Underlying, nil) ==
+			"*github.com/romshark/goesgen/inmemstore.Transaction"
+}
+
+func (s *Service) ProjectionsOn(e *Event) []*Projection {
+	var l []*Projection
+	for _, p := range s.Projections {
+		for _, x := range p.Events() {
+			if x == e {
+				l = append(l, p)
+				break
+			}
+		}
+	}
+	return l
+}
+
+// TimersOn returns the timers of s tied to a projection affected by e
+func (s *Service) TimersOn(e *Event) []*Timer {
+	var l []*Timer
+	for _, t := range s.Timers {
+		for _, x := range t.Projection.Events() {
+			if x == e {
+				l = append(l, t)
+				break
+			}
+		}
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	return l
+}
+
+func parseProcesses(
+	ctx context,
+	m map[ProcessName]ModelProcess,
+) error {
+	ctx.schema.Processes = make(map[ProcessName]*Process, len(m))
+	for n, v := range m {
+		ctx := ctx.Subcontext(n)
+
+		if err := ValidateProcessName(n); err != nil {
+			return ctx.syntaxErr("invalid process name (%q): %s", n, err)
+		}
+		pr := &Process{
+			Schema: ctx.schema,
+			Name:   n,
+		}
+
+		kt, err := registerReferencedType(ctx.Subcontext("key"), v.Key)
+		if err != nil {
+			return err
+		}
+		pr.Key = kt
+		kt.References = append(kt.References, pr)
+
+		st, err := registerReferencedType(ctx.Subcontext("state"), v.State)
+		if err != nil {
+			return err
+		}
+		pr.State = st
+		st.References = append(st.References, pr)
+
+		if err := parseProcessOn(ctx.Subcontext("on"), pr, v.On); err != nil {
+			return err
+		}
+		if err := parseProcessCalls(
+			ctx.Subcontext("calls"), pr, v.Calls,
+		); err != nil {
+			return err
+		}
+		if err := parseProcessEmits(
+			ctx.Subcontext("emits"), pr, v.Emits,
+		); err != nil {
+			return err
+		}
+		if len(pr.Calls) < 1 && len(pr.Emits) < 1 {
+			return ctx.semanticErr("missing calls or emits")
+		}
+
+		ctx.schema.Processes[n] = pr
+	}
+	return nil
+}
+
+func parseProcessOn(
+	ctx context,
+	p *Process,
+	on map[EventName]PropertyName,
+) error {
+	if len(on) < 1 {
+		return ctx.semanticErr("missing events")
+	}
+	p.On = make(map[EventName]*ProcessTrigger, len(on))
+	for en, pn := range on {
+		ctx := ctx.Subcontext(en)
+		e, ok := ctx.schema.Events[en]
+		if !ok {
+			return ctx.semanticErr("undefined event (%q)", en)
+		}
+		var key *Property
+		for _, p := range e.Properties {
+			if p.Name == pn {
+				key = p
+				break
+			}
+		}
+		if key == nil {
+			return ctx.semanticErr("undefined event property (%q)", pn)
+		}
+		if key.Type != p.Key {
+			return ctx.semanticErr(
+				"type of property %s (%s) doesn't match "+
+					"the process key type (%s)",
+				pn, key.Type.ID, p.Key.ID,
+			)
+		}
+		p.On[en] = &ProcessTrigger{Event: e, Key: key}
+		e.References = append(e.References, p)
+	}
+	return nil
+}
+
+func parseProcessCalls(
+	ctx context,
+	p *Process,
+	calls []string,
+) error {
+	p.Calls = make(map[ServiceName][]*ServiceMethod)
+	for i, c := range calls {
+		ctx := ctx.Subcontext(strconv.Itoa(i))
+		f := strings.Split(c, ".")
+		if len(f) != 2 {
+			return ctx.syntaxErr(
+				"invalid method reference (%q), expected Service.Method", c,
+			)
+		}
+		sv, ok := ctx.schema.Services[f[0]]
+		if !ok {
+			return ctx.semanticErr("undefined service (%q)", f[0])
+		}
+		m, ok := sv.Methods[f[1]]
+		if !ok {
+			return ctx.semanticErr("undefined method (%q)", c)
+		}
+		if m.Type == "readonly" {
+			return ctx.semanticErr("readonly method (%q) can't be called", c)
+		}
+		for _, x := range p.Calls[sv.Name] {
+			if x == m {
+				return ctx.semanticErr("duplicate method (%q)", c)
+			}
+		}
+		p.Calls[sv.Name] = append(p.Calls[sv.Name], m)
+	}
+	return nil
+}
+
+func parseProcessEmits(
+	ctx context,
+	p *Process,
+	emits []EventName,
+) error {
+	p.Emits = make([]*Event, len(emits))
+	for i, n := range emits {
+		e, ok := ctx.schema.Events[n]
+		if !ok {
+			return ctx.Subcontext(strconv.Itoa(i)).
+				semanticErr("undefined event (%q)", n)
+		}
+		for _, x := range p.Emits[:i] {
+			if x == e {
+				return ctx.Subcontext(strconv.Itoa(i)).
+					semanticErr("duplicate event (%q)", n)
+			}
+		}
+		p.Emits[i] = e
+		e.References = append(e.References, p)
+	}
+	return nil
+}
+
 // registerReferencedType registers a new referenced type
 // and returns it. If the given type is already registered
 // it is returned instead.
@@ -893,6 +1794,7 @@ func parseSources(
 		for _, t := range p.Types {
 			if typ := s.Lookup(t.Name); typ != nil {
 				t.SourceLocation = pk.Fset.Position(typ.Pos())
+				t.Underlying = typ.Type().Underlying()
 			}
 		}
 	}
@@ -913,9 +1815,66 @@ func parseSources(
 		)
 	}
 
+	if err := checkTenant(ctx.Subcontext("tenant")); err != nil {
+		return err
+	}
+	return checkSQLStores(ctx.Subcontext("services"))
+}
+
+// checkTenant makes sure the tenant type can be used as a map key
+func checkTenant(ctx context) error {
+	t := ctx.schema.Tenant
+	if t != nil && !types.Comparable(t.Underlying) {
+		return ctx.semanticErr(
+			"tenant type %s isn't comparable", t.ID,
+		)
+	}
+	return nil
+}
+
+// checkSQLStores makes sure the properties of the projections
+// of services with SQL stores can be stored in SQL columns
+func checkSQLStores(ctx context) error {
+	for _, s := range ctx.schema.Services {
+		if s.Store != "sql" {
+			continue
+		}
+		for _, p := range s.Projections {
+			for _, x := range p.Properties {
+				if x.Type.SQLColumnType() == "" {
+					return ctx.Subcontext(s.Name, "store").semanticErr(
+						"type of property %s.%s (%s) "+
+							"can't be stored in an SQL column",
+						p.Name, x.Name, x.Type.ID,
+					)
+				}
+			}
+		}
+	}
 	return nil
 }
 
+// SQLColumnType returns the SQL type of the columns storing
+// values of type t, or an empty string if t has
+// no SQL counterpart
+func (t *Type) SQLColumnType() string {
+	b, ok := t.Underlying.(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch i := b.Info(); {
+	case i&types.IsBoolean != 0:
+		return "BOOLEAN"
+	case i&types.IsInteger != 0:
+		return "BIGINT"
+	case i&types.IsFloat != 0:
+		return "DOUBLE PRECISION"
+	case i&types.IsString != 0:
+		return "TEXT"
+	}
+	return ""
+}
+
 func Parse(
 	sourcePackagePath,
 	schemaFilePath string,
@@ -4,7 +4,9 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/romshark/goesgen/gen"
 
@@ -158,6 +160,41 @@ func TestParse(t *testing.T) {
 		CheckType(t, s, e.Properties[0].Type)
 	}
 
+	{ // streams
+		r.Len(s.Streams, 1)
+
+		// streams.X1
+		r.Contains(s.Streams, "X1")
+		st := s.Streams["X1"]
+		r.Equal(s, st.Schema)
+		r.Equal("X1", st.Name)
+		CheckType(t, s, st.Key)
+		r.Equal("Foo", st.Key.Name)
+
+		// streams.X1.events
+		r.Len(st.Events, 2)
+
+		{ // streams.X1.events.E1
+			e := s.Events["E1"]
+			r.Contains(st.Events, e)
+			r.Equal(e.Properties[0], st.Events[e])
+			r.Equal(st, e.Stream)
+			r.Equal(e.Properties[0], e.StreamKey)
+		}
+
+		{ // streams.X1.events.E3
+			e := s.Events["E3"]
+			r.Contains(st.Events, e)
+			r.Equal(e.Properties[0], st.Events[e])
+			r.Equal(st, e.Stream)
+			r.Equal(e.Properties[0], e.StreamKey)
+		}
+
+		// E2 doesn't belong to any stream
+		r.Nil(s.Events["E2"].Stream)
+		r.Nil(s.Events["E2"].StreamKey)
+	}
+
 	{ // projections
 		r.Len(s.Projections, 1)
 
@@ -304,6 +341,7 @@ func TestParse(t *testing.T) {
 					},
 					m.Emits,
 				)
+				r.Equal([]*gen.Stream{s.Streams["X1"]}, m.Streams)
 			}
 		}
 	}
@@ -406,141 +444,1012 @@ go 1.15`,
 	r.Nil(schema)
 }
 
-func withOpenFile(p string, cb func(*os.File) error) error {
-	f, err := os.OpenFile(
-		p,
-		os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
-		0777,
-	)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+func TestParseStreamKeyTypeMismatch(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+    bar: U
+streams:
+  X1:
+    key: T
+    events:
+      E1: bar
+projections:
+  P1:
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+`,
+		"src.go": `package src; type T = int; type U = string`,
+		"go.mod": `module src
 
-	return cb(f)
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: streams.X1.events.E1: `+
+		`type of property bar (src.U) doesn't match `+
+		`the stream key type (src.T)`, err.Error())
+	r.Nil(schema)
 }
 
-func Setup(
-	t *testing.T,
-	files Files,
-) (root string, paths map[string]string) {
-	root = t.TempDir()
-	paths = make(map[string]string, len(files))
-	for filePath, contents := range files {
-		if dir, _ := filepath.Split(filePath); dir != "" {
-			require.NoError(t, os.MkdirAll(
-				filepath.Join(root, dir),
-				0777,
-			))
-		}
+func TestParseMethodStreamsUndeclaredEventStream(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+  E2:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+projections:
+  P1:
+    states:
+      - ST1
+    createOn: E1
+    transitions:
+      E2:
+        - ST1 -> ST1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        streams:
+          - X1
+        emits:
+          - E1
+          - E2
+`,
+		"src.go": `package src; type T = int`,
+		"go.mod": `module src
 
-		p := filepath.Join(root, filePath)
-		err := withOpenFile(p, func(f *os.File) error {
-			_, err := f.WriteString(contents)
-			if err != nil {
-				return err
-			}
+go 1.15`,
+	})
 
-			paths[filePath] = p
-			return nil
-		})
-		require.NoError(t, err)
-	}
-	return
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: services.S1.methods.streams: `+
+		`emitted event E2 doesn't belong to any stream`, err.Error())
+	r.Nil(schema)
 }
 
-type Files map[string]string
+func TestParseProcess(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+  E2:
+    bar: T
+projections:
+  P1:
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+processes:
+  R1:
+    key: T
+    state: U
+    on:
+      E1: foo
+    calls:
+      - S1.M1
+    emits:
+      - E2
+`,
+		"src.go": `package src; type T = int; type U struct{}`,
+		"go.mod": `module src
 
-func CheckType(t *testing.T, s *gen.Schema, typ *gen.Type) {
-	require.Contains(t, s.SourcePackages, typ.Package.ID)
-	require.Contains(t, s.SourcePackages[typ.Package.ID].Types, typ.ID)
-}
+go 1.15`,
+	})
 
-var ValidSetup = Files{
-	"schema.yaml":          ValidSchemaSchemaYAML,
-	"cmd/main/main.go":     ValidSchemaMainGO,
-	"src.go":               ValidSchemaSrcGO,
-	"sub/sub.go":           ValidSchemaSubGO,
-	"sub/subsub/subsub.go": ValidSchemaSubsubGO,
-	"go.mod":               ValidSchemaGoMOD,
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.NoError(err)
+	r.Len(schema.Processes, 1)
+
+	pr := schema.Processes["R1"]
+	r.Equal("R1", pr.Name)
+	r.Equal("src.T", pr.Key.ID)
+	r.Equal("src.U", pr.State.ID)
+	r.Len(pr.On, 1)
+	r.Equal(schema.Events["E1"], pr.On["E1"].Event)
+	r.Equal("foo", pr.On["E1"].Key.Name)
+	r.Equal(map[gen.ServiceName][]*gen.ServiceMethod{
+		"S1": {schema.Services["S1"].Methods["M1"]},
+	}, pr.Calls)
+	r.Equal([]*gen.Event{schema.Events["E2"]}, pr.Emits)
 }
 
-const ValidSchemaSrcGO = `package src
-type Foo = string
-`
+func TestParseProcessUndefinedMethod(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+projections:
+  P1:
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+processes:
+  R1:
+    key: T
+    state: T
+    on:
+      E1: foo
+    calls:
+      - S1.M2
+`,
+		"src.go": `package src; type T = int`,
+		"go.mod": `module src
 
-const ValidSchemaSubGO = `package sub
-type Bar int
-`
+go 1.15`,
+	})
 
-const ValidSchemaSubsubGO = `package subsub
-type Baz struct { Number float64 }
-`
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: processes.R1.calls.0: `+
+		`undefined method ("S1.M2")`, err.Error())
+	r.Nil(schema)
+}
 
-const ValidSchemaSchemaYAML = `
+func TestParseTimer(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
 ---
 events:
   E1:
-    # foo defines foo
-    foo: Foo
+    foo: T
   E2:
-    bar: sub.Bar
-    # baz represents baz
-    # 
-    # and another ## comment line
-    baz: sub.subsub.Baz
+    foo: T
   E3:
-    maz: Foo
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+      E2: foo
+      E3: foo
 projections:
   P1:
-    properties:
-      prop1: Foo
-      prop2: sub.subsub.Baz
     states:
       - ST1
       - ST2
-      - ST3
     createOn: E1
     transitions:
       E2:
         - ST1 -> ST2
       E3:
-        - ST2 -> ST2
-        - ST3 -> ST3
+        - ST2 -> ST1
 services:
   S1:
     projections:
       - P1
     methods:
-      # M1 does something
       M1:
-        in: Foo
-        out: sub.Bar
-        type: transaction
         emits:
           - E1
-      # M2 does something
-      #
-      # another line
-      M2:
-        type: append
-        emits:
-          - E2
-          - E3
-      M3:
-        out: sub.subsub.Baz
-      M4:
-        type: readonly
-      M5:
-        emits:
-          - E3
-`
+    timers:
+      T1:
+        projection: P1
+        state: ST2
+        after: 1h
+        emits: E3
+`,
+		"src.go": `package src; type T = int`,
+		"go.mod": `module src
 
-const ValidSchemaGoMOD = `module testmod
+go 1.15`,
+	})
 
-go 1.15`
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.NoError(err)
 
-const ValidSchemaMainGO = `package main
+	s := schema.Services["S1"]
+	r.Len(s.Timers, 1)
+	tm := s.Timers["T1"]
+	r.Equal("T1", tm.Name)
+	r.Equal(s, tm.Service)
+	r.Equal(schema.Projections["P1"], tm.Projection)
+	r.Equal("ST2", tm.State)
+	r.Equal(time.Hour, tm.After)
+	r.Equal(schema.Events["E3"], tm.Emits)
+	r.Equal(schema.Streams["X1"], tm.Stream)
+	r.Equal([]*gen.Timer{tm}, s.TimersOn(schema.Events["E2"]))
+}
 
-func main() {}
-`
+func TestParseTimerEmitsNotLeavingState(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+  E2:
+    foo: T
+  E3:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+      E2: foo
+      E3: foo
+projections:
+  P1:
+    states:
+      - ST1
+      - ST2
+    createOn: E1
+    transitions:
+      E2:
+        - ST1 -> ST2
+      E3:
+        - ST2 -> ST2
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+    timers:
+      T1:
+        projection: P1
+        state: ST2
+        after: 1h
+        emits: E3
+`,
+		"src.go": `package src; type T = int`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: services.S1.timers.T1.emits: `+
+		`event E3 mustn't transition ST2 to itself`, err.Error())
+	r.Nil(schema)
+}
+
+func TestParseProjectionKey(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+    bar: U
+  E2:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+      E2: foo
+projections:
+  P1:
+    key: id
+    indexes:
+      - owner
+    properties:
+      id: T
+      owner: U
+    states:
+      - ST1
+      - ST2
+    createOn: E1
+    transitions:
+      E2:
+        - ST1 -> ST2
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+          - E2
+`,
+		"src.go": `package src; type T = int; type U = string`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.NoError(err)
+
+	p := schema.Projections["P1"]
+	r.Equal(p.Property("id"), p.Key)
+	r.Equal([]*gen.Property{p.Property("owner")}, p.Indexes)
+	r.Equal(schema.Streams["X1"], p.Stream)
+	r.Nil(p.Property("undefined"))
+}
+
+func TestParseProjectionKeyTypeMismatch(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: U
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+`,
+		"src.go": `package src; type T = int; type U = string`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: projections.P1.key: `+
+		`type of property id (src.U) doesn't match `+
+		`the key type of stream X1 (src.T)`, err.Error())
+	r.Nil(schema)
+}
+
+func TestParseProjectionMappings(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+    bar: U
+  E2:
+    foo: T
+    baz: U
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+      E2: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: T
+      owner: U
+      owners: L
+    states:
+      - ST1
+      - ST2
+    createOn: E1
+    transitions:
+      E2:
+        - ST1 -> ST2
+        - ST2 -> ST2
+    apply:
+      E1:
+        set:
+          owner: bar
+      E2:
+        append:
+          owners: baz
+        state: ST2
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+          - E2
+`,
+		"src.go": `package src; type T = int; type U = string; type L []U`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.NoError(err)
+
+	p := schema.Projections["P1"]
+	e1, e2 := schema.Events["E1"], schema.Events["E2"]
+	r.Len(p.Mappings, 2)
+	r.Equal(&gen.Mapping{
+		Projection: p,
+		On:         e1,
+		Set: []*gen.PropertyMapping{
+			{Property: p.Property("owner"), From: e1.Property("bar")},
+		},
+		Append: []*gen.PropertyMapping{},
+	}, p.Mappings[e1])
+	r.Equal(&gen.Mapping{
+		Projection: p,
+		On:         e2,
+		Set:        []*gen.PropertyMapping{},
+		Append: []*gen.PropertyMapping{
+			{Property: p.Property("owners"), From: e2.Property("baz")},
+		},
+		State: "ST2",
+	}, p.Mappings[e2])
+}
+
+func TestParseProjectionMappingsUndefinedTransition(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+    bar: U
+  E2:
+    foo: T
+    baz: U
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+      E2: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: T
+      owner: U
+      owners: L
+    states:
+      - ST1
+      - ST2
+    createOn: E1
+    transitions:
+      E2:
+        - ST1 -> ST2
+        - ST2 -> ST2
+    apply:
+      E1:
+        set:
+          owner: bar
+      E2:
+        append:
+          owners: baz
+        state: ST1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+          - E2
+`,
+		"src.go": `package src; type T = int; type U = string; type L []U`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: projections.P1.apply.E2.state: `+
+		`undefined transition (ST1 -> ST1) on event E2`, err.Error())
+	r.Nil(schema)
+}
+
+func withOpenFile(p string, cb func(*os.File) error) error {
+	f, err := os.OpenFile(
+		p,
+		os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
+		0777,
+	)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return cb(f)
+}
+
+func Setup(
+	t *testing.T,
+	files Files,
+) (root string, paths map[string]string) {
+	root = t.TempDir()
+	paths = make(map[string]string, len(files))
+	for filePath, contents := range files {
+		if dir, _ := filepath.Split(filePath); dir != "" {
+			require.NoError(t, os.MkdirAll(
+				filepath.Join(root, dir),
+				0777,
+			))
+		}
+
+		p := filepath.Join(root, filePath)
+		err := withOpenFile(p, func(f *os.File) error {
+			_, err := f.WriteString(contents)
+			if err != nil {
+				return err
+			}
+
+			paths[filePath] = p
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	return
+}
+
+type Files map[string]string
+
+func CheckType(t *testing.T, s *gen.Schema, typ *gen.Type) {
+	require.Contains(t, s.SourcePackages, typ.Package.ID)
+	require.Contains(t, s.SourcePackages[typ.Package.ID].Types, typ.ID)
+}
+
+var ValidSetup = Files{
+	"schema.yaml":          ValidSchemaSchemaYAML,
+	"cmd/main/main.go":     ValidSchemaMainGO,
+	"src.go":               ValidSchemaSrcGO,
+	"sub/sub.go":           ValidSchemaSubGO,
+	"sub/subsub/subsub.go": ValidSchemaSubsubGO,
+	"go.mod":               ValidSchemaGoMOD,
+}
+
+const ValidSchemaSrcGO = `package src
+type Foo = string
+`
+
+const ValidSchemaSubGO = `package sub
+type Bar int
+`
+
+const ValidSchemaSubsubGO = `package subsub
+type Baz struct { Number float64 }
+`
+
+const ValidSchemaSchemaYAML = `
+---
+events:
+  E1:
+    # foo defines foo
+    foo: Foo
+  E2:
+    bar: sub.Bar
+    # baz represents baz
+    # 
+    # and another ## comment line
+    baz: sub.subsub.Baz
+  E3:
+    maz: Foo
+streams:
+  X1:
+    key: Foo
+    events:
+      E1: foo
+      E3: maz
+projections:
+  P1:
+    properties:
+      prop1: Foo
+      prop2: sub.subsub.Baz
+    states:
+      - ST1
+      - ST2
+      - ST3
+    createOn: E1
+    transitions:
+      E2:
+        - ST1 -> ST2
+      E3:
+        - ST2 -> ST2
+        - ST3 -> ST3
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      # M1 does something
+      M1:
+        in: Foo
+        out: sub.Bar
+        type: transaction
+        emits:
+          - E1
+      # M2 does something
+      #
+      # another line
+      M2:
+        type: append
+        emits:
+          - E2
+          - E3
+      M3:
+        out: sub.subsub.Baz
+      M4:
+        type: readonly
+      M5:
+        streams:
+          - X1
+        emits:
+          - E3
+`
+
+const ValidSchemaGoMOD = `module testmod
+
+go 1.15`
+
+const ValidSchemaMainGO = `package main
+
+func main() {}
+`
+
+func TestParseServiceTransaction(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: T
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    transaction: Tx
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+`,
+		"src.go": `package src; type T = int; type Tx struct{}`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.NoError(err)
+
+	s := schema.Services["S1"]
+	r.NotNil(s.Transaction)
+	r.Equal("src.Tx", s.Transaction.ID)
+	r.Equal([]interface{}{s}, s.Transaction.References)
+
+	// Transactions of other types can't be rolled back
+	// by the generated in-memory store
+	r.False(s.InmemStore())
+}
+
+func TestParseServiceTransactionStore(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: T
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    store: sql
+    transaction: Tx
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+`,
+		"src.go": `package src; type T = int; type Tx struct{}`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: services.S1.transaction: `+
+		`store sql defines the transaction type`, err.Error())
+	r.Nil(schema)
+}
+
+const SQLStoreSchemaYAML = `
+---
+events:
+  E1:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: T
+      name: Name
+      active: Active
+      score: Score
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    store: sql
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+`
+
+const SQLStoreSrcGO = `package src
+type T = int
+type Name string
+type Active bool
+type Score float64
+`
+
+func TestParseServiceStoreSQL(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": SQLStoreSchemaYAML,
+		"src.go":      SQLStoreSrcGO,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.NoError(err)
+
+	s := schema.Services["S1"]
+	r.Equal("sql", s.Store)
+	r.False(s.InmemStore())
+
+	columnTypes := map[string]string{}
+	for _, x := range schema.Projections["P1"].Properties {
+		columnTypes[x.Name] = x.Type.SQLColumnType()
+	}
+	r.Equal(map[string]string{
+		"id":     "BIGINT",
+		"name":   "TEXT",
+		"active": "BOOLEAN",
+		"score":  "DOUBLE PRECISION",
+	}, columnTypes)
+}
+
+func TestParseServiceStoreErrInvalid(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": strings.Replace(
+			SQLStoreSchemaYAML, "store: sql", "store: mongo", 1,
+		),
+		"src.go": SQLStoreSrcGO,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SyntaxErr(""), err, err.Error())
+	r.Equal(`syntax error: services.S1.store: `+
+		`invalid store ("mongo"), expected sql`, err.Error())
+	r.Nil(schema)
+}
+
+func TestParseServiceStoreSQLErrColumnType(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": SQLStoreSchemaYAML,
+		"src.go": strings.Replace(
+			SQLStoreSrcGO, "type Name string", "type Name struct{}", 1,
+		),
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: services.S1.store: `+
+		`type of property P1.name (src.Name) `+
+		`can't be stored in an SQL column`, err.Error())
+	r.Nil(schema)
+}
+
+func TestParseTenant(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: T
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+tenant: Tenant
+`,
+		"src.go": `package src; type T = int; type Tenant string`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.NoError(err)
+	r.NotNil(schema.Tenant)
+	r.Equal("src.Tenant", schema.Tenant.ID)
+	r.Equal([]interface{}{schema}, schema.Tenant.References)
+}
+
+func TestParseTenantNotComparable(t *testing.T) {
+	root, files := Setup(t, Files{
+		"schema.yaml": `
+---
+events:
+  E1:
+    foo: T
+streams:
+  X1:
+    key: T
+    events:
+      E1: foo
+projections:
+  P1:
+    key: id
+    properties:
+      id: T
+    states:
+      - ST1
+    createOn: E1
+services:
+  S1:
+    projections:
+      - P1
+    methods:
+      M1:
+        emits:
+          - E1
+tenant: Tenant
+`,
+		"src.go": `package src; type T = int; type Tenant []byte`,
+		"go.mod": `module src
+
+go 1.15`,
+	})
+
+	schema, err := gen.Parse(root, files["schema.yaml"])
+	r := require.New(t)
+	r.Error(err)
+	r.IsType(gen.SemanticErr(""), err, err.Error())
+	r.Equal(`semantic error: tenant: `+
+		`tenant type src.Tenant isn't comparable`, err.Error())
+	r.Nil(schema)
+}
@@ -1,8 +1,9 @@
 module github.com/romshark/goesgen
 
-go 1.17
+go 1.18
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.4
 	github.com/stretchr/testify v1.7.0
 	golang.org/x/tools v0.1.6
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
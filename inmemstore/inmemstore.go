@@ -0,0 +1,177 @@
+// Package inmemstore provides the transactions of the generated
+// in-memory store handlers.
+//
+// Instead of copying the state when a read-write transaction begins,
+// changes made within a transaction record how to undo them.
+// The undo log is replayed in reverse order on rollback and dropped
+// on commit, which makes both proportional to the size of the change
+// rather than the size of the state.
+package inmemstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Store synchronizes the transactions of an in-memory store handler.
+// Read-write transactions are exclusive, read-only transactions
+// are shared. The zero value is ready to use.
+type Store struct {
+	lock sync.RWMutex
+}
+
+// NewTransactionReadWriter begins a new exclusive read-write transaction
+// blocking until all other transactions are completed
+func (s *Store) NewTransactionReadWriter() *Transaction {
+	s.lock.Lock()
+	return &Transaction{store: s, savepoint: -1}
+}
+
+// NewTransactionReader begins a new shared read-only transaction
+// blocking until the current read-write transaction is completed
+func (s *Store) NewTransactionReader() *Transaction {
+	s.lock.RLock()
+	return &Transaction{store: s, readOnly: true, savepoint: -1}
+}
+
+// Transaction is either an exclusive read-write transaction
+// satisfying the generated StoreTransactionReadWriter interface
+// or a shared read-only transaction satisfying
+// the generated StoreTransactionReader interface
+type Transaction struct {
+	store    *Store
+	readOnly bool
+	view     bool
+	undo     []func()
+	done     bool
+
+	// savepoint is the length of the undo log at the savepoint,
+	// -1 if there's no savepoint
+	savepoint int
+}
+
+// ReadOnlyView returns a read-only view of the transaction
+// satisfying the generated StoreTransactionViewer interface.
+// The view can't be used for mutations and must not be completed.
+func (t *Transaction) ReadOnlyView() *Transaction {
+	return &Transaction{
+		store:     t.store,
+		readOnly:  true,
+		view:      true,
+		savepoint: -1,
+	}
+}
+
+// ReadOnly returns true for read-only transactions
+func (t *Transaction) ReadOnly() bool { return t.readOnly }
+
+// OnRollback records undo in the undo log of the transaction.
+// undo must revert the change it's recorded for assuming all changes
+// recorded after it have already been reverted.
+// Panics if the transaction is read-only.
+func (t *Transaction) OnRollback(undo func()) {
+	if t.readOnly {
+		panic("recording undo in a read-only transaction")
+	}
+	if t.done {
+		panic("recording undo in a completed transaction")
+	}
+	t.undo = append(t.undo, undo)
+}
+
+// Savepoint sets the savepoint of the read-write transaction
+// replacing the previous one, which makes Transaction satisfy
+// the generated StoreTransactionSavepointer interface.
+// Panics if the transaction is read-only.
+func (t *Transaction) Savepoint(context.Context) error {
+	if t.readOnly {
+		panic("setting savepoint in a read-only transaction")
+	}
+	t.savepoint = len(t.undo)
+	return nil
+}
+
+// RollbackToSavepoint reverts all changes recorded in the undo log
+// after the savepoint keeping the transaction open.
+// Panics if there's no savepoint.
+func (t *Transaction) RollbackToSavepoint(context.Context) error {
+	if t.savepoint < 0 {
+		panic("rolling back to undefined savepoint")
+	}
+	for i := len(t.undo) - 1; i >= t.savepoint; i-- {
+		t.undo[i]()
+	}
+	t.undo = t.undo[:t.savepoint]
+	return nil
+}
+
+// ReleaseSavepoint releases the savepoint keeping all changes
+func (t *Transaction) ReleaseSavepoint(context.Context) error {
+	t.savepoint = -1
+	return nil
+}
+
+// Commit completes the read-write transaction keeping all changes
+func (t *Transaction) Commit() {
+	t.complete(false)
+}
+
+// Rollback reverts all changes recorded in the undo log
+// and completes the read-write transaction
+func (t *Transaction) Rollback() {
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.complete(false)
+}
+
+// Complete completes the read-only transaction
+func (t *Transaction) Complete() {
+	t.complete(true)
+}
+
+func (t *Transaction) complete(readOnly bool) {
+	switch {
+	case t.view:
+		panic("completing a read-only view")
+	case t.done:
+		panic("completing a completed transaction")
+	case readOnly && !t.readOnly:
+		panic("read-write transaction must be committed or rolled back")
+	case !readOnly && t.readOnly:
+		panic("read-only transaction must be completed")
+	}
+	t.done, t.undo, t.savepoint = true, nil, -1
+	if t.readOnly {
+		t.store.lock.RUnlock()
+		return
+	}
+	t.store.lock.Unlock()
+}
+
+// Writer returns trx as a read-write transaction.
+// Panics if trx isn't a read-write *Transaction.
+func Writer(trx interface{}) *Transaction {
+	t, ok := trx.(*Transaction)
+	if !ok {
+		panic(fmt.Sprintf("unexpected transaction type: %T", trx))
+	}
+	if t.readOnly {
+		panic("unexpected read-only transaction")
+	}
+	return t
+}
+
+// Reader returns trx as a transaction to read within.
+// Panics if trx isn't a *Transaction or was already completed.
+func Reader(trx interface{}) *Transaction {
+	t, ok := trx.(*Transaction)
+	if !ok || t == nil {
+		panic(fmt.Sprintf("unexpected transaction: %#v", trx))
+	}
+	if t.done {
+		panic("reading within a completed transaction")
+	}
+	return t
+}
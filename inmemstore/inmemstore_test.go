@@ -0,0 +1,163 @@
+package inmemstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romshark/goesgen/inmemstore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollback(t *testing.T) {
+	r := require.New(t)
+	var s inmemstore.Store
+	m := map[string]int{"a": 1}
+
+	set := func(trx *inmemstore.Transaction, k string, v int) {
+		p, ok := m[k]
+		trx.OnRollback(func() {
+			if ok {
+				m[k] = p
+			} else {
+				delete(m, k)
+			}
+		})
+		m[k] = v
+	}
+
+	trx := s.NewTransactionReadWriter()
+	set(trx, "a", 2)
+	set(trx, "b", 3)
+	set(trx, "a", 4)
+	r.Equal(map[string]int{"a": 4, "b": 3}, m)
+	trx.Rollback()
+	r.Equal(map[string]int{"a": 1}, m)
+
+	trx = s.NewTransactionReadWriter()
+	set(trx, "b", 5)
+	trx.Commit()
+	r.Equal(map[string]int{"a": 1, "b": 5}, m)
+
+	// Committed changes are kept
+	trx = s.NewTransactionReadWriter()
+	trx.Rollback()
+	r.Equal(map[string]int{"a": 1, "b": 5}, m)
+}
+
+func TestRollbackToSavepoint(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	var s inmemstore.Store
+	m := map[string]int{}
+
+	set := func(trx *inmemstore.Transaction, k string, v int) {
+		p := m[k]
+		trx.OnRollback(func() { m[k] = p })
+		m[k] = v
+	}
+
+	trx := s.NewTransactionReadWriter()
+	r.Panics(func() { _ = trx.RollbackToSavepoint(ctx) })
+	set(trx, "a", 1)
+	r.NoError(trx.Savepoint(ctx))
+	set(trx, "a", 2)
+	set(trx, "b", 3)
+	r.NoError(trx.RollbackToSavepoint(ctx))
+	r.Equal(map[string]int{"a": 1, "b": 0}, m)
+
+	// The savepoint is kept until released
+	set(trx, "a", 4)
+	r.NoError(trx.RollbackToSavepoint(ctx))
+	r.Equal(1, m["a"])
+	r.NoError(trx.ReleaseSavepoint(ctx))
+	r.Panics(func() { _ = trx.RollbackToSavepoint(ctx) })
+
+	// Changes preceding the savepoint are still rolled back
+	trx.Rollback()
+	r.Equal(map[string]int{"a": 0, "b": 0}, m)
+
+	rd := s.NewTransactionReader()
+	r.Panics(func() { _ = rd.Savepoint(ctx) })
+	rd.Complete()
+}
+
+func TestTransactionReaderShared(t *testing.T) {
+	var s inmemstore.Store
+	a, b := s.NewTransactionReader(), s.NewTransactionReader()
+	a.Complete()
+	b.Complete()
+
+	// The writer isn't blocked after all readers are completed
+	s.NewTransactionReadWriter().Commit()
+}
+
+func TestCompleted(t *testing.T) {
+	r := require.New(t)
+	var s inmemstore.Store
+
+	trx := s.NewTransactionReadWriter()
+	trx.Commit()
+	r.Panics(func() { trx.Commit() })
+	r.Panics(func() { trx.Rollback() })
+	r.Panics(func() { trx.OnRollback(func() {}) })
+
+	rd := s.NewTransactionReader()
+	rd.Complete()
+	r.Panics(func() { rd.Complete() })
+}
+
+func TestReadOnly(t *testing.T) {
+	r := require.New(t)
+	var s inmemstore.Store
+
+	rd := s.NewTransactionReader()
+	r.True(rd.ReadOnly())
+	r.Panics(func() { rd.OnRollback(func() {}) })
+	r.Panics(func() { rd.Commit() })
+	r.Panics(func() { rd.Rollback() })
+	r.Panics(func() { inmemstore.Writer(rd) })
+	rd.Complete()
+
+	trx := s.NewTransactionReadWriter()
+	r.False(trx.ReadOnly())
+	r.Panics(func() { trx.Complete() })
+	trx.Commit()
+}
+
+func TestWriter(t *testing.T) {
+	var s inmemstore.Store
+	trx := s.NewTransactionReadWriter()
+	defer trx.Commit()
+	require.Equal(t, trx, inmemstore.Writer(trx))
+	require.Panics(t, func() { inmemstore.Writer(struct{}{}) })
+}
+
+func TestReader(t *testing.T) {
+	r := require.New(t)
+	var s inmemstore.Store
+
+	rd := s.NewTransactionReader()
+	r.Equal(rd, inmemstore.Reader(rd))
+	v := rd.ReadOnlyView()
+	r.Equal(v, inmemstore.Reader(v))
+	rd.Complete()
+	r.Panics(func() { inmemstore.Reader(rd) })
+	r.Panics(func() { inmemstore.Reader(struct{}{}) })
+	r.Panics(func() { inmemstore.Reader(nil) })
+	r.Panics(func() { inmemstore.Reader((*inmemstore.Transaction)(nil)) })
+}
+
+func TestReadOnlyView(t *testing.T) {
+	r := require.New(t)
+	var s inmemstore.Store
+
+	trx := s.NewTransactionReadWriter()
+	v := trx.ReadOnlyView()
+	r.True(v.ReadOnly())
+	r.Panics(func() { inmemstore.Writer(v) })
+	r.Panics(func() { v.OnRollback(func() {}) })
+	r.Panics(func() { v.Complete() })
+	r.Panics(func() { v.Commit() })
+	trx.Commit()
+}
@@ -0,0 +1,318 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"time"
+)
+
+// EventlogVersion is the version of an event log
+type EventlogVersion = string
+
+type ServiceOptions struct {
+	// SyncAfterPush will synchronize a service against the event log
+	// after a successful push of events.
+	//
+	// SyncAfterPush is enabled by default.
+	SyncAfterPush Option
+
+	// MaxAttempts limits the number of times a transaction method
+	// is executed before giving up with a ConflictErr
+	// when the event log keeps changing concurrently.
+	//
+	// MaxAttempts is DefaultMaxAttempts by default.
+	MaxAttempts uint
+
+	// Backoff determines how long to wait before the next attempt
+	// after a transaction was rejected due to a version conflict.
+	//
+	// Backoff is DefaultBackoff by default.
+	Backoff BackoffStrategy
+
+	// Jitter randomizes the backoff delay to avoid conflicting
+	// transactions retrying in lockstep.
+	//
+	// Jitter is enabled by default.
+	Jitter Option
+
+	// SnapshotFrequency defines the number of applied events after which
+	// Sync saves a snapshot of the projection, given that the store handler
+	// implements the snapshotter interface of the service.
+	// Snapshots are never saved when SnapshotFrequency is 0.
+	//
+	// SnapshotFrequency is 0 by default.
+	SnapshotFrequency uint
+
+	// FailurePolicy defines how Sync handles events that fail
+	// to be decoded or applied to the projection.
+	// FailurePolicySkip rolls back failed attempts to a savepoint,
+	// which requires the store transactions to implement Savepointer.
+	//
+	// FailurePolicy is FailurePolicyStop by default.
+	FailurePolicy FailurePolicy
+
+	// ApplyRetries defines how many times applying an event is retried
+	// before FailurePolicy takes effect. Decoding failures aren't retried.
+	// Failed attempts are rolled back to a savepoint before retrying,
+	// which requires the store transactions to implement Savepointer.
+	//
+	// ApplyRetries is 0 by default.
+	ApplyRetries uint
+
+	// DeadLetterSink records events skipped due to FailurePolicySkip.
+	// DeadLetterSink is required when FailurePolicy is FailurePolicySkip.
+	DeadLetterSink DeadLetterSink
+
+	// UnknownEvents defines how Sync handles events of types
+	// that aren't defined by the schema, which is usually the case
+	// when the event log is shared with services deployed with
+	// a newer version of the schema.
+	//
+	// UnknownEvents is UnknownEventIgnore by default.
+	UnknownEvents UnknownEventPolicy
+
+	// Clock returns the current time FireTimers compares
+	// the deadlines of pending timers against.
+	//
+	// Clock is time.Now by default.
+	Clock func() time.Time
+}
+
+type UnknownEventPolicy int
+
+const (
+	// UnknownEventIgnore skips events of unknown types
+	// the same way events the service isn't subscribed to are skipped.
+	UnknownEventIgnore UnknownEventPolicy = 0
+
+	// UnknownEventFail treats events of unknown types as failures
+	// returning UnknownEventTypeErr, which is handled according to
+	// the FailurePolicy.
+	UnknownEventFail UnknownEventPolicy = 1
+)
+
+type FailurePolicy int
+
+const (
+	// FailurePolicyStop aborts synchronization returning the error
+	// leaving the projection at the version preceding the failed event.
+	FailurePolicyStop FailurePolicy = 0
+
+	// FailurePolicySkip records the failed event
+	// in the dead-letter sink and continues synchronization.
+	FailurePolicySkip FailurePolicy = 1
+)
+
+// DeadLetter is an event that failed to be decoded or applied
+type DeadLetter struct {
+	// Service is the name of the service that failed to apply the event
+	Service string
+
+	// Tenant is the ID of the tenant the service instance is hosted for,
+	// nil if the service isn't hosted per tenant
+	Tenant any
+
+	// Projections are the projections the event failed to be applied to
+	Projections Projections
+
+	// Offset is the offset version of the event
+	Offset EventlogVersion
+
+	// Next is the version following the event
+	Next EventlogVersion
+
+	// Time is the time the event was appended at
+	Time time.Time
+
+	// Payload is the raw JSON encoded event
+	Payload []byte
+
+	// Err is the error the event failed with
+	Err error
+}
+
+// DeadLetterSink receives events that failed to be decoded or applied.
+// Dead-lettered events can be reprocessed using the Reprocess method
+// of the service they were dead-lettered by.
+type DeadLetterSink interface {
+	// RecordDeadLetter records the given dead-lettered event.
+	// Synchronization is aborted if an error is returned.
+	//
+	// WARNING: RecordDeadLetter is expected to be thread-safe.
+	RecordDeadLetter(context.Context, DeadLetter) error
+}
+
+// Savepointer is implemented by store transactions that can roll back
+// the changes made after a savepoint while keeping the transaction open.
+// Sync sets a savepoint before applying an event when ApplyRetries
+// is greater than 0 or FailurePolicy is FailurePolicySkip and rolls back
+// to it before every retry and after the last failed attempt,
+// leaving the projection unchanged.
+type Savepointer interface {
+	// Savepoint sets the savepoint replacing the previous one
+	Savepoint(context.Context) error
+
+	// RollbackToSavepoint reverts all changes made after the savepoint
+	RollbackToSavepoint(context.Context) error
+
+	// ReleaseSavepoint releases the savepoint keeping all changes
+	ReleaseSavepoint(context.Context) error
+}
+
+// ImplementsSavepointer returns true if type T implements Savepointer
+func ImplementsSavepointer[T any]() bool {
+	return reflect.TypeOf((*T)(nil)).Elem().Implements(
+		reflect.TypeOf((*Savepointer)(nil)).Elem(),
+	)
+}
+
+// BackoffStrategy returns the delay before the given retry attempt.
+// attempt is the number of attempts made so far (starting at 1).
+type BackoffStrategy func(attempt uint) time.Duration
+
+// ConstantBackoff returns a backoff strategy always waiting for d.
+func ConstantBackoff(d time.Duration) BackoffStrategy {
+	return func(uint) time.Duration { return d }
+}
+
+// ExponentialBackoff returns a backoff strategy doubling the delay
+// with every attempt starting at min and never exceeding max.
+func ExponentialBackoff(min, max time.Duration) BackoffStrategy {
+	return func(attempt uint) time.Duration {
+		d := min
+		for i := uint(1); i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+const DefaultMaxAttempts = 8
+
+var DefaultBackoff = ExponentialBackoff(
+	5*time.Millisecond,
+	500*time.Millisecond,
+)
+
+type Option int
+
+const (
+	Unspecified Option = 0
+	Disabled    Option = -1
+	Enabled     Option = 1
+)
+
+// SetDefaults sets default values to unspecified options
+func (o *ServiceOptions) SetDefaults() {
+	if o.SyncAfterPush == Unspecified {
+		o.SyncAfterPush = Enabled
+	}
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+	if o.Backoff == nil {
+		o.Backoff = DefaultBackoff
+	}
+	if o.Jitter == Unspecified {
+		o.Jitter = Enabled
+	}
+	if o.Clock == nil {
+		o.Clock = time.Now
+	}
+}
+
+// RequiresSavepointer returns true if the options require
+// the store transactions to implement Savepointer, which is the case
+// when failed attempts to apply an event are retried or skipped.
+func (o ServiceOptions) RequiresSavepointer() bool {
+	return o.ApplyRetries > 0 || o.FailurePolicy == FailurePolicySkip
+}
+
+// Backoff blocks for the delay of the given attempt
+// determined by strategy or until ctx is cancelled.
+func Backoff(
+	ctx context.Context,
+	strategy BackoffStrategy,
+	jitter Option,
+	attempt uint,
+) error {
+	d := strategy(attempt)
+	if jitter == Enabled && d > 1 {
+		// Randomize the delay within [d/2, d)
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)))
+	}
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Retry invokes fn until it succeeds or maxAttempts is reached
+// backing off between attempts.
+// Returns the number of attempts made and the last error.
+func Retry(
+	ctx context.Context,
+	maxAttempts uint,
+	strategy BackoffStrategy,
+	jitter Option,
+	fn func() error,
+) (attempts uint, err error) {
+	for attempts = 1; ; attempts++ {
+		if err = fn(); err == nil || attempts >= maxAttempts {
+			return
+		}
+		if err := Backoff(ctx, strategy, jitter, attempts); err != nil {
+			return attempts, err
+		}
+	}
+}
+
+// ConflictErr is returned by transaction methods when the event log
+// was concurrently modified on every attempt.
+type ConflictErr struct {
+	Method   string
+	Attempts uint
+	Err      error
+}
+
+func (e ConflictErr) Error() string {
+	return fmt.Sprintf(
+		"%s: giving up after %d conflicting attempt(s): %s",
+		e.Method, e.Attempts, e.Err,
+	)
+}
+
+func (e ConflictErr) Unwrap() error { return e.Err }
+
+// RebuildOptions defines the options of a projection rebuild
+type RebuildOptions struct {
+	// From defines the version of the event log to start replaying from.
+	// Events are replayed from the beginning of the event log if From
+	// is empty.
+	From EventlogVersion
+
+	// OnProgress is invoked for every event applied
+	// to the rebuilt projection if not nil.
+	OnProgress func(RebuildProgress)
+}
+
+// RebuildProgress describes the progress of a projection rebuild
+type RebuildProgress struct {
+	// Version is the projection version reached so far
+	Version EventlogVersion
+
+	// Applied is the number of events applied so far
+	Applied uint
+}
@@ -0,0 +1,774 @@
+// Package runtime implements the service engine shared by all
+// generated services, which reduces the generated code to type
+// definitions and thin typed bindings of the engine.
+//
+// Service is parameterized over the transaction type T of the service
+// and the event type E of the generated package.
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProjectionName is the name of a projection
+type ProjectionName string
+
+// Projections is a set of projections
+type Projections []ProjectionName
+
+// Has returns true if the set contains the projection n
+func (p Projections) Has(n ProjectionName) bool {
+	for _, x := range p {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+// LaggingErr is returned when the projections of a service are
+// at different versions, which is the case when a projection was
+// newly added to a service and wasn't backfilled yet.
+type LaggingErr struct {
+	// Projections are the projections lagging behind
+	Projections Projections
+}
+
+func (e LaggingErr) Error() string {
+	l := make([]string, len(e.Projections))
+	for i, p := range e.Projections {
+		l[i] = string(p)
+	}
+	return fmt.Sprintf(
+		"projections lagging behind: %s", strings.Join(l, ", "),
+	)
+}
+
+// EventLog is the subset of the generated EventLogger
+// required by the service engine
+type EventLog interface {
+	IsOffsetOutOfBoundErr(error) bool
+	IsMismatchingVersionsErr(error) bool
+	Begin(context.Context) (string, error)
+	Scan(
+		ctx context.Context,
+		version EventlogVersion,
+		limit uint,
+		onEvent func(
+			offset EventlogVersion,
+			tm time.Time,
+			payload []byte,
+			next EventlogVersion,
+		) error,
+	) error
+}
+
+// Store is the subset of the generated store handler of a service
+// required by the service engine
+type Store[T any] interface {
+	ProjectionVersion(
+		context.Context,
+		T,
+		ProjectionName,
+	) (EventlogVersion, error)
+	UpdateProjectionVersion(
+		context.Context,
+		T,
+		ProjectionName,
+		EventlogVersion,
+	) error
+}
+
+// Snapshotter is the generated snapshotter of a service
+type Snapshotter[T any] interface {
+	SaveSnapshot(context.Context, T, EventlogVersion) error
+	LoadSnapshot(context.Context, T) (EventlogVersion, error)
+}
+
+// Service is the engine of a generated service.
+// Service isn't thread-safe and must only be used
+// within exclusive read-write transactions of the store,
+// except for ProjectionVersion, ProjectionVersions and Lagging.
+type Service[T, E any] struct {
+	// Name is the name of the service
+	Name string
+
+	// Projections are the projections of the service,
+	// each of which is versioned individually
+	Projections Projections
+
+	EventLog EventLog
+	Store    Store[T]
+
+	// Snapshotter is optional
+	Snapshotter Snapshotter[T]
+
+	Options ServiceOptions
+
+	// IsSubscribed peeks the type of the given encoded event
+	// and returns true if the service is subscribed to it.
+	IsSubscribed func(payload []byte) (bool, error)
+
+	// DecodeEvent decodes the given encoded event
+	DecodeEvent func(payload []byte) (E, error)
+
+	// ApplyEvent applies the given event to the given projections
+	ApplyEvent func(
+		ctx context.Context,
+		trx T,
+		projections Projections,
+		version EventlogVersion,
+		tm time.Time,
+		ev E,
+	) error
+
+	// OnApplied is invoked for every applied event if not nil
+	OnApplied func(EventlogVersion)
+
+	appliedSinceSnapshot uint
+}
+
+// group is a group of projections at the same version
+type group struct {
+	version     EventlogVersion
+	projections Projections
+}
+
+// ProjectionVersions returns the current version of each projection.
+// The version of a projection that wasn't initialized yet is empty.
+func (s *Service[T, E]) ProjectionVersions(
+	ctx context.Context,
+	trx T,
+) (map[ProjectionName]EventlogVersion, error) {
+	m := make(map[ProjectionName]EventlogVersion, len(s.Projections))
+	for _, p := range s.Projections {
+		v, err := s.Store.ProjectionVersion(ctx, trx, p)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"reading version of projection %s: %w", p, err,
+			)
+		}
+		m[p] = v
+	}
+	return m, nil
+}
+
+// groups returns the projections grouped by version in the order
+// of Projections. Projections that weren't initialized yet fall back
+// to the beginning of the event log. Services without projections
+// are always at the beginning of the event log.
+func (s *Service[T, E]) groups(
+	ctx context.Context,
+	trx T,
+) ([]group, error) {
+	versions, err := s.ProjectionVersions(ctx, trx)
+	if err != nil {
+		return nil, err
+	}
+	var begin EventlogVersion
+	if len(s.Projections) < 1 {
+		if begin, err = s.EventLog.Begin(ctx); err != nil {
+			return nil, fmt.Errorf("reading begin offset version: %w", err)
+		}
+		return []group{{version: begin}}, nil
+	}
+	var l []group
+NEXT:
+	for _, p := range s.Projections {
+		v := versions[p]
+		if v == "" {
+			if begin == "" {
+				// Fallback to the beginning of the eventlog
+				if begin, err = s.EventLog.Begin(ctx); err != nil {
+					return nil, fmt.Errorf(
+						"reading begin offset version: %w", err,
+					)
+				}
+			}
+			v = begin
+		}
+		for i := range l {
+			if l[i].version == v {
+				l[i].projections = append(l[i].projections, p)
+				continue NEXT
+			}
+		}
+		l = append(l, group{version: v, projections: Projections{p}})
+	}
+	return l, nil
+}
+
+// ProjectionVersion returns the current projection version
+// falling back to the beginning of the event log
+// if the projections weren't initialized yet.
+// Returns a LaggingErr if the projections are at different versions.
+func (s *Service[T, E]) ProjectionVersion(
+	ctx context.Context,
+	trx T,
+) (EventlogVersion, error) {
+	g, err := s.groups(ctx, trx)
+	if err != nil {
+		return "", err
+	}
+	if len(g) == 1 {
+		return g[0].version, nil
+	}
+	lagging, err := s.lagging(ctx, g)
+	if err != nil {
+		return "", err
+	}
+	return "", LaggingErr{Projections: lagging}
+}
+
+// Lagging returns the projections lagging behind the projection
+// of the service that is furthest ahead.
+// Returns nil if all projections are at the same version.
+func (s *Service[T, E]) Lagging(
+	ctx context.Context,
+	trx T,
+) (Projections, error) {
+	g, err := s.groups(ctx, trx)
+	if err != nil {
+		return nil, err
+	}
+	return s.lagging(ctx, g)
+}
+
+var errReached = errors.New("reached")
+
+// lagging returns the projections of all groups except the one
+// furthest ahead. Since versions are opaque the event log is scanned
+// once from the version of the first group until the versions
+// of all other groups are reached. The last group reached is
+// the one furthest ahead. Groups that aren't reached before
+// the end of the event log are behind the first group.
+func (s *Service[T, E]) lagging(
+	ctx context.Context,
+	groups []group,
+) (Projections, error) {
+	if len(groups) < 2 {
+		return nil, nil
+	}
+	index := make(map[EventlogVersion]int, len(groups))
+	for i, g := range groups {
+		index[g.version] = i
+	}
+	ahead, reached := 0, 1
+	err := s.EventLog.Scan(
+		ctx,
+		groups[0].version,
+		0, // No limit
+		func(
+			_ EventlogVersion,
+			_ time.Time,
+			_ []byte,
+			next EventlogVersion,
+		) error {
+			if i, ok := index[next]; ok {
+				ahead = i
+				if reached++; reached == len(groups) {
+					return errReached
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil && err != errReached &&
+		!s.EventLog.IsOffsetOutOfBoundErr(err) {
+		return nil, err
+	}
+	var l Projections
+	for i, g := range groups {
+		if i != ahead {
+			l = append(l, g.projections...)
+		}
+	}
+	return l, nil
+}
+
+// UpdateProjectionVersion sets the version of all projections
+func (s *Service[T, E]) UpdateProjectionVersion(
+	ctx context.Context,
+	trx T,
+	version EventlogVersion,
+) error {
+	for _, p := range s.Projections {
+		if err := s.Store.UpdateProjectionVersion(
+			ctx, trx, p, version,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync scans events until it reaches the tip of the event log
+// applying the events the service is subscribed to.
+// Projections at different versions are synchronized separately,
+// which backfills newly added projections without replaying
+// the events of the others, until all projections are at the same version.
+// The latest snapshot is loaded before scanning if the projections
+// weren't initialized yet and Snapshotter isn't nil.
+func (s *Service[T, E]) Sync(
+	ctx context.Context,
+	trx T,
+) (EventlogVersion, error) {
+	if s.Snapshotter != nil {
+		if err := s.loadSnapshot(ctx, trx); err != nil {
+			return "", err
+		}
+	}
+	for {
+		groups, err := s.groups(ctx, trx)
+		if err != nil {
+			return "", err
+		}
+		if len(groups) == 1 {
+			return s.sync(ctx, trx, groups[0])
+		}
+		// Repeat until all groups reached the same version
+		for _, g := range groups {
+			if _, err := s.sync(ctx, trx, g); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// sync synchronizes the projections of group g
+func (s *Service[T, E]) sync(
+	ctx context.Context,
+	trx T,
+	g group,
+) (
+	latestVersion EventlogVersion,
+	err error,
+) {
+	// Nothing needs to be applied if the projections are already up to date
+	latestVersion = g.version
+	appliedVersion := g.version
+	update := func(v EventlogVersion) error {
+		for _, p := range g.projections {
+			if err := s.Store.UpdateProjectionVersion(
+				ctx, trx, p, v,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	defer func() {
+		if err != nil || appliedVersion == latestVersion {
+			return
+		}
+		// Skip events the service isn't subscribed to
+		if err = update(latestVersion); err != nil {
+			latestVersion = appliedVersion
+		}
+	}()
+
+	var sp Savepointer
+	if s.Options.RequiresSavepointer() {
+		var ok bool
+		if sp, ok = any(trx).(Savepointer); !ok {
+			return "", fmt.Errorf(
+				"ApplyRetries and FailurePolicySkip require "+
+					"transactions of type %T to implement Savepointer", trx,
+			)
+		}
+	}
+
+	all := len(g.projections) == len(s.Projections)
+	if err := s.EventLog.Scan(
+		ctx,
+		g.version,
+		0, // No limit
+		func(
+			offset EventlogVersion,
+			tm time.Time,
+			payload []byte,
+			next EventlogVersion,
+		) error {
+			// Only decode events the service is subscribed to
+			ok, err := s.IsSubscribed(payload)
+			if ok {
+				var ev E
+				if ev, err = s.DecodeEvent(payload); err == nil {
+					var errSavepoint error
+					err, errSavepoint = s.apply(
+						ctx, trx, sp, g.projections, next, tm, ev,
+					)
+					if errSavepoint != nil {
+						return errSavepoint
+					}
+				}
+				if err == nil {
+					if err := update(next); err != nil {
+						return err
+					}
+					appliedVersion = next
+					if err := s.applied(ctx, trx, next, all); err != nil {
+						return err
+					}
+				}
+			}
+			if err != nil {
+				if s.Options.FailurePolicy != FailurePolicySkip {
+					return err
+				}
+				if err := s.Options.DeadLetterSink.RecordDeadLetter(
+					ctx, DeadLetter{
+						Service:     s.Name,
+						Projections: g.projections,
+						Offset:      offset,
+						Next:        next,
+						Time:        tm,
+						Payload:     payload,
+						Err:         err,
+					},
+				); err != nil {
+					return fmt.Errorf("recording dead letter: %w", err)
+				}
+			}
+			latestVersion = next
+			return nil
+		},
+	); err != nil {
+		if s.EventLog.IsOffsetOutOfBoundErr(err) {
+			return latestVersion, nil
+		}
+		return "", err
+	}
+	return latestVersion, nil
+}
+
+// apply applies ev to the given projections retrying up to
+// ApplyRetries times if sp isn't nil, which it always is
+// when the options require a Savepointer. Failed attempts are rolled back
+// to a savepoint set before the first attempt.
+// Returns the error of the last attempt as failure and
+// the error of the savepoint operations as err.
+func (s *Service[T, E]) apply(
+	ctx context.Context,
+	trx T,
+	sp Savepointer,
+	projections Projections,
+	version EventlogVersion,
+	tm time.Time,
+	ev E,
+) (failure, err error) {
+	if sp == nil {
+		return s.ApplyEvent(ctx, trx, projections, version, tm, ev), nil
+	}
+	if err := sp.Savepoint(ctx); err != nil {
+		return nil, fmt.Errorf("setting savepoint: %w", err)
+	}
+	for i := uint(0); ; i++ {
+		failure = s.ApplyEvent(ctx, trx, projections, version, tm, ev)
+		if failure == nil {
+			break
+		}
+		if err := sp.RollbackToSavepoint(ctx); err != nil {
+			return nil, fmt.Errorf("rolling back to savepoint: %w", err)
+		}
+		if i >= s.Options.ApplyRetries {
+			break
+		}
+	}
+	if err := sp.ReleaseSavepoint(ctx); err != nil {
+		return nil, fmt.Errorf("releasing savepoint: %w", err)
+	}
+	return failure, nil
+}
+
+// Transact invokes attempt with the current projection version
+// until it succeeds, fails for a reason other than a conflict,
+// ctx is cancelled or MaxAttempts is reached, in which case
+// a ConflictErr is returned. The projection is synchronized
+// after backing off from a conflicting attempt.
+func (s *Service[T, E]) Transact(
+	ctx context.Context,
+	trx T,
+	method string,
+	attempt func(version EventlogVersion) (conflict bool, err error),
+) error {
+	groups, err := s.groups(ctx, trx)
+	if err != nil {
+		return err
+	}
+	version := groups[0].version
+	if len(groups) > 1 || len(groups[0].projections) < 1 {
+		// Synchronize lagging projections first
+		if version, err = s.Sync(ctx, trx); err != nil {
+			return err
+		}
+	}
+
+	// Repeat until either cancelled, succeeded, failed or out of attempts
+	for n := uint(1); ; n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		conflict, err := attempt(version)
+		if !conflict {
+			return err
+		}
+		if n >= s.Options.MaxAttempts {
+			return ConflictErr{Method: method, Attempts: n, Err: err}
+		}
+		if err := Backoff(
+			ctx, s.Options.Backoff, s.Options.Jitter, n,
+		); err != nil {
+			return err
+		}
+		// The projection is out of sync, synchronize & repeat
+		if version, err = s.Sync(ctx, trx); err != nil {
+			return err
+		}
+	}
+}
+
+// Reprocess applies a dead-lettered event to the projections
+// it failed to be applied to, or all projections if none are specified,
+// leaving the projection versions unchanged.
+func (s *Service[T, E]) Reprocess(
+	ctx context.Context,
+	trx T,
+	deadLetter DeadLetter,
+) error {
+	ev, err := s.DecodeEvent(deadLetter.Payload)
+	if err != nil {
+		return err
+	}
+	projections := deadLetter.Projections
+	if projections == nil {
+		projections = s.Projections
+	}
+	return s.ApplyEvent(
+		ctx, trx, projections, deadLetter.Next, deadLetter.Time, ev,
+	)
+}
+
+// CatchUp synchronizes a projection that was just swapped
+// with a rebuilt one invoking onApplied for every applied event.
+func (s *Service[T, E]) CatchUp(
+	ctx context.Context,
+	trx T,
+	onApplied func(EventlogVersion),
+) (EventlogVersion, error) {
+	s.appliedSinceSnapshot = 0
+	s.OnApplied = onApplied
+	defer func() { s.OnApplied = nil }()
+	return s.Sync(ctx, trx)
+}
+
+// Rebuild replays the event log into shadow, an engine bound to a shadow
+// store with a reset projection, within a transaction of the shadow store
+// run by transactShadow. Once replayed, the projection of s is replaced
+// by the rebuilt one using swap within a transaction of the store of s
+// run by transact, which catches up with events appended during
+// the rebuild. transactShadow and transact must commit the transaction
+// if the given function succeeds and roll it back otherwise.
+// Returns the projection version reached after the swap.
+func (s *Service[T, E]) Rebuild(
+	ctx context.Context,
+	shadow *Service[T, E],
+	options RebuildOptions,
+	transactShadow func(func(T) error) error,
+	transact func(func(T) error) error,
+	swap func(context.Context, T) error,
+) (EventlogVersion, error) {
+	var applied uint
+	shadow.OnApplied = func(v EventlogVersion) {
+		applied++
+		if options.OnProgress != nil {
+			options.OnProgress(RebuildProgress{Version: v, Applied: applied})
+		}
+	}
+
+	if err := transactShadow(func(trx T) error {
+		if options.From != "" {
+			if err := shadow.UpdateProjectionVersion(
+				ctx, trx, options.From,
+			); err != nil {
+				return fmt.Errorf("setting initial version: %w", err)
+			}
+		}
+		if _, err := shadow.Sync(ctx, trx); err != nil {
+			return fmt.Errorf("replaying: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	// Swap and catch up with events appended during the rebuild
+	var latestVersion EventlogVersion
+	if err := transact(func(trx T) (err error) {
+		if err = swap(ctx, trx); err != nil {
+			return fmt.Errorf("swapping store: %w", err)
+		}
+		if latestVersion, err = s.CatchUp(
+			ctx, trx, shadow.OnApplied,
+		); err != nil {
+			return fmt.Errorf("catching up: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	return latestVersion, nil
+}
+
+// Timer is a pending timer of a projection instance
+type Timer struct {
+	// Name is the name of the timer
+	Name string
+
+	// Stream identifies the projection instance the timer belongs to
+	Stream string
+
+	// Key is the JSON encoded key of the stream
+	Key []byte
+
+	// Deadline is the time the timer fires at
+	Deadline time.Time
+}
+
+// TimerStore is the subset of the generated store handler
+// of a service with timers required by FireTimers
+type TimerStore[T any] interface {
+	DueTimers(context.Context, T, time.Time) ([]Timer, error)
+	CancelTimer(ctx context.Context, trx T, name, stream string) error
+}
+
+// StreamsAppender is the subset of the generated EventLogger
+// required by FireTimers
+type StreamsAppender interface {
+	IsMismatchingVersionsErr(error) bool
+	AppendCheckStreamsJSON(
+		ctx context.Context,
+		assumedVersion EventlogVersion,
+		streams []string,
+		payload []byte,
+	) (
+		offset EventlogVersion,
+		newVersion EventlogVersion,
+		tm time.Time,
+		err error,
+	)
+}
+
+// FireTimers synchronizes the projections and appends the encoded event
+// fire returns for every timer that is due according to the Clock option
+// cancelling the fired timers. Returns the number of fired timers.
+// A timer is left pending and fired during the next call
+// if its stream changed concurrently.
+func (s *Service[T, E]) FireTimers(
+	ctx context.Context,
+	trx T,
+	timers TimerStore[T],
+	eventLog StreamsAppender,
+	fire func(context.Context, Timer) ([]byte, error),
+) (
+	fired uint,
+	err error,
+) {
+	currentVersion, err := s.Sync(ctx, trx)
+	if err != nil {
+		return 0, err
+	}
+	due, err := timers.DueTimers(ctx, trx, s.Options.Clock())
+	if err != nil {
+		return 0, fmt.Errorf("reading due timers: %w", err)
+	}
+
+	for _, t := range due {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var b []byte
+		if b, err = fire(ctx, t); err != nil {
+			return
+		}
+		_, _, _, err = eventLog.AppendCheckStreamsJSON(
+			ctx, currentVersion, []string{t.Stream}, b,
+		)
+		if eventLog.IsMismatchingVersionsErr(err) {
+			// Leave the timer pending until the next call
+			err = nil
+			continue
+		} else if err != nil {
+			return
+		}
+		fired++
+		if err = timers.CancelTimer(ctx, trx, t.Name, t.Stream); err != nil {
+			err = fmt.Errorf("cancelling fired timer: %w", err)
+			return
+		}
+	}
+
+	if fired > 0 {
+		// Apply the events of the fired timers
+		_, err = s.Sync(ctx, trx)
+	}
+	return
+}
+
+// loadSnapshot loads the latest snapshot
+// unless any of the projections is already initialized.
+func (s *Service[T, E]) loadSnapshot(ctx context.Context, trx T) error {
+	versions, err := s.ProjectionVersions(ctx, trx)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if v != "" {
+			// Already initialized
+			return nil
+		}
+	}
+	v, err := s.Snapshotter.LoadSnapshot(ctx, trx)
+	if err != nil {
+		return fmt.Errorf("loading snapshot: %w", err)
+	}
+	if v == "" {
+		// No snapshot
+		return nil
+	}
+	if err := s.UpdateProjectionVersion(ctx, trx, v); err != nil {
+		return fmt.Errorf("updating projection version: %w", err)
+	}
+	s.appliedSinceSnapshot = 0
+	return nil
+}
+
+// applied saves a snapshot if necessary after an event was applied
+// and the projections were moved to the given version.
+// Snapshots are only saved if all projections are at the given version.
+func (s *Service[T, E]) applied(
+	ctx context.Context,
+	trx T,
+	version EventlogVersion,
+	all bool,
+) error {
+	if s.OnApplied != nil {
+		s.OnApplied(version)
+	}
+	if !all || s.Snapshotter == nil || s.Options.SnapshotFrequency < 1 {
+		return nil
+	}
+	if s.appliedSinceSnapshot++; s.appliedSinceSnapshot <
+		s.Options.SnapshotFrequency {
+		return nil
+	}
+	if err := s.Snapshotter.SaveSnapshot(ctx, trx, version); err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	s.appliedSinceSnapshot = 0
+	return nil
+}
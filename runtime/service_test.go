@@ -0,0 +1,552 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/romshark/goesgen/runtime"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errMismatch = errors.New("mismatching versions")
+
+// eventLog is an event log of string events
+// versioned by their index
+type eventLog []string
+
+func (eventLog) IsOffsetOutOfBoundErr(error) bool        { return false }
+func (eventLog) IsMismatchingVersionsErr(err error) bool { return err == errMismatch }
+func (eventLog) Begin(context.Context) (string, error)   { return "0", nil }
+
+func (l eventLog) Scan(
+	ctx context.Context,
+	version string,
+	limit uint,
+	onEvent func(string, time.Time, []byte, string) error,
+) error {
+	i, err := strconv.Atoi(version)
+	if err != nil {
+		return err
+	}
+	for ; i < len(l); i++ {
+		if err := onEvent(
+			strconv.Itoa(i), time.Time{}, []byte(l[i]), strconv.Itoa(i+1),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store records the events applied to projections A and B
+type store struct {
+	versions map[runtime.ProjectionName]string
+	applied  map[runtime.ProjectionName][]string
+
+	// savepoint is the number of events applied to each projection
+	// at the savepoint
+	savepoint map[runtime.ProjectionName]int
+}
+
+func (s *store) Savepoint(context.Context) error {
+	s.savepoint = map[runtime.ProjectionName]int{}
+	for p, l := range s.applied {
+		s.savepoint[p] = len(l)
+	}
+	return nil
+}
+
+func (s *store) RollbackToSavepoint(context.Context) error {
+	for p := range s.applied {
+		s.applied[p] = s.applied[p][:s.savepoint[p]]
+	}
+	return nil
+}
+
+func (s *store) ReleaseSavepoint(context.Context) error {
+	s.savepoint = nil
+	return nil
+}
+
+func newStore() *store {
+	return &store{
+		versions: map[runtime.ProjectionName]string{},
+		applied:  map[runtime.ProjectionName][]string{},
+	}
+}
+
+func (s *store) ProjectionVersion(
+	_ context.Context,
+	_ *store,
+	p runtime.ProjectionName,
+) (string, error) {
+	return s.versions[p], nil
+}
+
+func (s *store) UpdateProjectionVersion(
+	_ context.Context,
+	_ *store,
+	p runtime.ProjectionName,
+	v string,
+) error {
+	s.versions[p] = v
+	return nil
+}
+
+func newService(l eventLog, s *store) *runtime.Service[*store, string] {
+	o := runtime.ServiceOptions{Backoff: runtime.ConstantBackoff(0)}
+	o.SetDefaults()
+	return &runtime.Service[*store, string]{
+		Name:        "test",
+		Projections: runtime.Projections{"A", "B"},
+		EventLog:    l,
+		Store:       s,
+		Options:     o,
+		IsSubscribed: func(payload []byte) (bool, error) {
+			return string(payload) != "ignored", nil
+		},
+		DecodeEvent: func(payload []byte) (string, error) {
+			return string(payload), nil
+		},
+		ApplyEvent: func(
+			_ context.Context,
+			trx *store,
+			projections runtime.Projections,
+			_ string,
+			_ time.Time,
+			ev string,
+		) error {
+			for _, p := range projections {
+				trx.applied[p] = append(trx.applied[p], ev)
+			}
+			return nil
+		},
+	}
+}
+
+func TestSync(t *testing.T) {
+	r := require.New(t)
+	st := newStore()
+	s := newService(eventLog{"a", "ignored", "b", "ignored"}, st)
+
+	v, err := s.Sync(context.Background(), st)
+	r.NoError(err)
+	r.Equal("4", v)
+	r.Equal(map[runtime.ProjectionName]string{
+		"A": "4", "B": "4",
+	}, st.versions)
+	r.Equal([]string{"a", "b"}, st.applied["A"])
+	r.Equal([]string{"a", "b"}, st.applied["B"])
+
+	// Already up to date
+	v, err = s.Sync(context.Background(), st)
+	r.NoError(err)
+	r.Equal("4", v)
+	r.Equal([]string{"a", "b"}, st.applied["A"])
+}
+
+func TestSyncBackfill(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	st := newStore()
+	s := newService(eventLog{"a", "b", "c"}, st)
+
+	// Projection B was newly added
+	st.versions["A"] = "2"
+	st.applied["A"] = []string{"a", "b"}
+
+	lagging, err := s.Lagging(ctx, st)
+	r.NoError(err)
+	r.Equal(runtime.Projections{"B"}, lagging)
+
+	_, err = s.ProjectionVersion(ctx, st)
+	var errLagging runtime.LaggingErr
+	r.True(errors.As(err, &errLagging))
+	r.Equal(runtime.Projections{"B"}, errLagging.Projections)
+	r.Equal("projections lagging behind: B", err.Error())
+
+	v, err := s.Sync(ctx, st)
+	r.NoError(err)
+	r.Equal("3", v)
+	r.Equal(map[runtime.ProjectionName]string{
+		"A": "3", "B": "3",
+	}, st.versions)
+
+	// Only B is replayed from the beginning
+	r.Equal([]string{"a", "b", "c"}, st.applied["A"])
+	r.Equal([]string{"a", "b", "c"}, st.applied["B"])
+
+	lagging, err = s.Lagging(ctx, st)
+	r.NoError(err)
+	r.Nil(lagging)
+}
+
+func TestLagging(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		versions map[runtime.ProjectionName]string
+		scanned  int
+		expect   runtime.Projections
+	}{
+		{
+			name: "first behind",
+			versions: map[runtime.ProjectionName]string{
+				"A": "1", "B": "3", "C": "2",
+			},
+			scanned: 2, // Stops when B is reached
+			expect:  runtime.Projections{"A", "C"},
+		},
+		{
+			name: "first ahead",
+			versions: map[runtime.ProjectionName]string{
+				"A": "3", "B": "1", "C": "",
+			},
+			scanned: 1, // Scans until the end
+			expect:  runtime.Projections{"B", "C"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			st := newStore()
+			st.versions = tt.versions
+			l := &countingEventLog{eventLog: eventLog{"a", "b", "c", "d"}}
+			s := newService(nil, st)
+			s.EventLog = l
+			s.Projections = runtime.Projections{"A", "B", "C"}
+
+			lagging, err := s.Lagging(context.Background(), st)
+			r.NoError(err)
+			r.Equal(tt.expect, lagging)
+			r.Equal(1, l.scans)
+			r.Equal(tt.scanned, l.scanned)
+		})
+	}
+}
+
+// countingEventLog counts the scans and the events scanned
+type countingEventLog struct {
+	eventLog
+	scans, scanned int
+}
+
+func (l *countingEventLog) Scan(
+	ctx context.Context,
+	version string,
+	limit uint,
+	onEvent func(string, time.Time, []byte, string) error,
+) error {
+	l.scans++
+	return l.eventLog.Scan(ctx, version, limit, func(
+		v string, tm time.Time, p []byte, next string,
+	) error {
+		l.scanned++
+		return onEvent(v, tm, p, next)
+	})
+}
+
+func TestSyncApplyRetries(t *testing.T) {
+	r := require.New(t)
+	st := newStore()
+	s := newService(eventLog{"a", "b"}, st)
+	s.Options.ApplyRetries = 2
+
+	failures := 2
+	apply := s.ApplyEvent
+	s.ApplyEvent = func(
+		ctx context.Context,
+		trx *store,
+		projections runtime.Projections,
+		v string,
+		tm time.Time,
+		ev string,
+	) error {
+		err := apply(ctx, trx, projections, v, tm, ev)
+		if ev == "b" && failures > 0 {
+			// Fail after partially applying the event
+			failures--
+			return errors.New("apply failed")
+		}
+		return err
+	}
+
+	v, err := s.Sync(context.Background(), st)
+	r.NoError(err)
+	r.Equal("2", v)
+	r.Equal(0, failures)
+
+	// Failed attempts were rolled back
+	r.Equal([]string{"a", "b"}, st.applied["A"])
+	r.Equal([]string{"a", "b"}, st.applied["B"])
+	r.Nil(st.savepoint)
+}
+
+func TestSyncSkipRollback(t *testing.T) {
+	r := require.New(t)
+	st := newStore()
+	s := newService(eventLog{"a", "b", "c"}, st)
+	sink := &deadLetterSink{}
+	s.Options.FailurePolicy = runtime.FailurePolicySkip
+	s.Options.DeadLetterSink = sink
+
+	apply := s.ApplyEvent
+	s.ApplyEvent = func(
+		ctx context.Context,
+		trx *store,
+		projections runtime.Projections,
+		v string,
+		tm time.Time,
+		ev string,
+	) error {
+		err := apply(ctx, trx, projections, v, tm, ev)
+		if ev == "b" {
+			// Fail after partially applying the event
+			return errors.New("apply failed")
+		}
+		return err
+	}
+
+	v, err := s.Sync(context.Background(), st)
+	r.NoError(err)
+	r.Equal("3", v)
+
+	// The failed event was rolled back and dead-lettered
+	r.Equal([]string{"a", "c"}, st.applied["A"])
+	r.Equal([]string{"a", "c"}, st.applied["B"])
+	r.Len(sink.deadLetters, 1)
+	r.Equal("1", sink.deadLetters[0].Offset)
+	r.Nil(st.savepoint)
+}
+
+// deadLetterSink records dead letters
+type deadLetterSink struct{ deadLetters []runtime.DeadLetter }
+
+func (s *deadLetterSink) RecordDeadLetter(
+	_ context.Context,
+	d runtime.DeadLetter,
+) error {
+	s.deadLetters = append(s.deadLetters, d)
+	return nil
+}
+
+func TestSyncApplyRetriesNoSavepointer(t *testing.T) {
+	st := newStore()
+	s := &runtime.Service[string, string]{
+		Name:        "test",
+		Projections: runtime.Projections{"A"},
+		EventLog:    eventLog{"a"},
+		Store:       versionStore{st},
+		Options:     runtime.ServiceOptions{ApplyRetries: 1},
+		IsSubscribed: func([]byte) (bool, error) {
+			return true, nil
+		},
+		DecodeEvent: func(payload []byte) (string, error) {
+			return string(payload), nil
+		},
+		ApplyEvent: func(
+			context.Context,
+			string,
+			runtime.Projections,
+			string,
+			time.Time,
+			string,
+		) error {
+			return nil
+		},
+	}
+	_, err := s.Sync(context.Background(), "trx")
+	require.Error(t, err)
+	require.Equal(t,
+		"ApplyRetries and FailurePolicySkip require "+
+			"transactions of type string to implement Savepointer",
+		err.Error(),
+	)
+}
+
+func TestImplementsSavepointer(t *testing.T) {
+	require.True(t, runtime.ImplementsSavepointer[*store]())
+	require.False(t, runtime.ImplementsSavepointer[string]())
+	require.False(t, runtime.ImplementsSavepointer[interface{}]())
+}
+
+// versionStore stores the projection versions of transactions
+// not implementing runtime.Savepointer
+type versionStore struct{ s *store }
+
+func (s versionStore) ProjectionVersion(
+	ctx context.Context,
+	_ string,
+	p runtime.ProjectionName,
+) (string, error) {
+	return s.s.ProjectionVersion(ctx, s.s, p)
+}
+
+func (s versionStore) UpdateProjectionVersion(
+	ctx context.Context,
+	_ string,
+	p runtime.ProjectionName,
+	v string,
+) error {
+	return s.s.UpdateProjectionVersion(ctx, s.s, p, v)
+}
+
+func TestFireTimers(t *testing.T) {
+	r := require.New(t)
+	st := newStore()
+	s := newService(eventLog{"a"}, st)
+	s.Options.Clock = func() time.Time { return time.Unix(10, 0) }
+	timers := &timerStore{due: []runtime.Timer{
+		{Name: "t", Stream: "s1"},
+		{Name: "t", Stream: "s2"},
+	}}
+	appender := &appender{conflicting: "s2"}
+
+	fired, err := s.FireTimers(context.Background(), st, timers, appender,
+		func(_ context.Context, t runtime.Timer) ([]byte, error) {
+			return []byte(t.Stream), nil
+		},
+	)
+	r.NoError(err)
+	r.Equal(uint(1), fired)
+	r.Equal(time.Unix(10, 0), timers.now)
+	r.Equal([]string{"s1"}, appender.appended)
+
+	// The timer of the concurrently changed stream is left pending
+	r.Equal([]string{"s1"}, timers.cancelled)
+	r.Equal("1", st.versions["A"])
+}
+
+// timerStore returns due timers recording the cancelled ones
+type timerStore struct {
+	now       time.Time
+	due       []runtime.Timer
+	cancelled []string
+}
+
+func (s *timerStore) DueTimers(
+	_ context.Context,
+	_ *store,
+	now time.Time,
+) ([]runtime.Timer, error) {
+	s.now = now
+	return s.due, nil
+}
+
+func (s *timerStore) CancelTimer(
+	_ context.Context,
+	_ *store,
+	_, stream string,
+) error {
+	s.cancelled = append(s.cancelled, stream)
+	return nil
+}
+
+// appender records appended payloads rejecting appends to
+// the conflicting stream
+type appender struct {
+	conflicting string
+	appended    []string
+}
+
+func (a *appender) IsMismatchingVersionsErr(err error) bool {
+	return err == errMismatch
+}
+
+func (a *appender) AppendCheckStreamsJSON(
+	_ context.Context,
+	_ string,
+	streams []string,
+	payload []byte,
+) (string, string, time.Time, error) {
+	if streams[0] == a.conflicting {
+		return "", "", time.Time{}, errMismatch
+	}
+	a.appended = append(a.appended, string(payload))
+	return "", "", time.Time{}, nil
+}
+
+func TestRebuild(t *testing.T) {
+	r := require.New(t)
+	l := eventLog{"a", "b"}
+	st, shadowStore := newStore(), newStore()
+	s, shadow := newService(l, st), newService(l, shadowStore)
+
+	var progress []runtime.RebuildProgress
+	v, err := s.Rebuild(
+		context.Background(),
+		shadow,
+		runtime.RebuildOptions{
+			From: "1",
+			OnProgress: func(p runtime.RebuildProgress) {
+				progress = append(progress, p)
+			},
+		},
+		func(fn func(*store) error) error { return fn(shadowStore) },
+		func(fn func(*store) error) error { return fn(st) },
+		func(_ context.Context, trx *store) error {
+			*trx = *shadowStore
+			return nil
+		},
+	)
+	r.NoError(err)
+	r.Equal("2", v)
+	r.Equal([]runtime.RebuildProgress{{Version: "2", Applied: 1}}, progress)
+
+	// Only the events after From were replayed
+	r.Equal([]string{"b"}, st.applied["A"])
+	r.Equal("2", st.versions["B"])
+}
+
+func TestTransactConflict(t *testing.T) {
+	r := require.New(t)
+	st := newStore()
+	s := newService(eventLog{"a"}, st)
+	s.Options.MaxAttempts = 3
+
+	var versions []string
+	err := s.Transact(context.Background(), st, "test.M", func(
+		version string,
+	) (bool, error) {
+		versions = append(versions, version)
+		return true, errMismatch
+	})
+	var errConflict runtime.ConflictErr
+	r.True(errors.As(err, &errConflict))
+	r.Equal("test.M", errConflict.Method)
+	r.Equal(uint(3), errConflict.Attempts)
+	r.True(errors.Is(err, errMismatch))
+
+	// The projection is synchronized after every conflict
+	r.Equal([]string{"0", "1", "1"}, versions)
+	r.Equal([]string{"a"}, st.applied["A"])
+}
+
+func TestTransactErr(t *testing.T) {
+	r := require.New(t)
+	st := newStore()
+	s := newService(eventLog{"a"}, st)
+
+	errMethod := errors.New("method failed")
+	attempts := 0
+	err := s.Transact(context.Background(), st, "test.M", func(
+		string,
+	) (bool, error) {
+		attempts++
+		return false, errMethod
+	})
+	r.Equal(errMethod, err)
+	r.Equal(1, attempts)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := runtime.ExponentialBackoff(time.Millisecond, 5*time.Millisecond)
+	require.Equal(t, []time.Duration{
+		time.Millisecond,
+		2 * time.Millisecond,
+		4 * time.Millisecond,
+		5 * time.Millisecond,
+	}, []time.Duration{b(1), b(2), b(3), b(4)})
+}
@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CursorStore persists the positions of event log consumers
+type CursorStore interface {
+	// LoadCursor returns the stored version of the given consumer.
+	// Returns an empty string if no cursor was stored yet.
+	//
+	// WARNING: LoadCursor is expected to be thread-safe.
+	LoadCursor(ctx context.Context, consumer string) (EventlogVersion, error)
+
+	// SaveCursor stores the version of the given consumer.
+	//
+	// WARNING: SaveCursor is expected to be thread-safe.
+	SaveCursor(
+		ctx context.Context,
+		consumer string,
+		version EventlogVersion,
+	) error
+}
+
+// Tail scans the event log from the stored cursor of the given consumer
+// advancing the cursor after every event onEvent returned no error for.
+// Returns the latest version of the event log the consumer reached.
+// Tail drives the generated relays, event consumers and processes.
+func Tail(
+	ctx context.Context,
+	consumer string,
+	eventLog EventLog,
+	cursors CursorStore,
+	onEvent func(
+		ctx context.Context,
+		offset EventlogVersion,
+		tm time.Time,
+		payload []byte,
+		next EventlogVersion,
+	) error,
+) (
+	latestVersion EventlogVersion,
+	err error,
+) {
+	if latestVersion, err = cursors.LoadCursor(ctx, consumer); err != nil {
+		return "", fmt.Errorf("loading cursor: %w", err)
+	}
+	if latestVersion == "" {
+		if latestVersion, err = eventLog.Begin(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	if err := eventLog.Scan(
+		ctx,
+		latestVersion,
+		0, // No limit
+		func(
+			offset EventlogVersion,
+			tm time.Time,
+			payload []byte,
+			next EventlogVersion,
+		) error {
+			if err := onEvent(ctx, offset, tm, payload, next); err != nil {
+				return err
+			}
+			if err := cursors.SaveCursor(ctx, consumer, next); err != nil {
+				return fmt.Errorf("saving cursor: %w", err)
+			}
+			latestVersion = next
+			return nil
+		},
+	); err != nil && !eventLog.IsOffsetOutOfBoundErr(err) {
+		return latestVersion, err
+	}
+	return latestVersion, nil
+}
@@ -0,0 +1,55 @@
+package runtime_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romshark/goesgen/runtime"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cursorStore is an in-memory runtime.CursorStore
+type cursorStore map[string]string
+
+func (s cursorStore) LoadCursor(_ context.Context, c string) (string, error) {
+	return s[c], nil
+}
+
+func (s cursorStore) SaveCursor(_ context.Context, c, v string) error {
+	s[c] = v
+	return nil
+}
+
+func TestTail(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	cursors := cursorStore{}
+
+	var received []string
+	onEvent := func(
+		_ context.Context,
+		_ string,
+		_ time.Time,
+		payload []byte,
+		_ string,
+	) error {
+		received = append(received, string(payload))
+		return nil
+	}
+
+	v, err := runtime.Tail(ctx, "c", eventLog{"a", "b"}, cursors, onEvent)
+	r.NoError(err)
+	r.Equal("2", v)
+	r.Equal([]string{"a", "b"}, received)
+	r.Equal("2", cursors["c"])
+
+	// Resumed from the stored cursor
+	v, err = runtime.Tail(
+		ctx, "c", eventLog{"a", "b", "c"}, cursors, onEvent,
+	)
+	r.NoError(err)
+	r.Equal("3", v)
+	r.Equal([]string{"a", "b", "c"}, received)
+}
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+)
+
+// Tenants holds an instance of type V for each tenant identified by K
+// creating the instances on first use. Tenants is thread-safe.
+type Tenants[K comparable, V any] struct {
+	lock    sync.Mutex
+	tenants map[K]*tenant[V]
+}
+
+// tenant is the instance of a tenant that's ready once done is closed
+type tenant[V any] struct {
+	done     chan struct{}
+	instance V
+	err      error
+}
+
+// Get returns the instance of the given tenant invoking create
+// on first use. The instance is created without holding the lock,
+// concurrent calls for the same tenant wait for the instance
+// to be created and receive the error if it fails.
+// An instance that failed to be created is created anew on next use.
+func (t *Tenants[K, V]) Get(
+	ctx context.Context,
+	key K,
+	create func(context.Context) (V, error),
+) (V, error) {
+	t.lock.Lock()
+	e, ok := t.tenants[key]
+	if !ok {
+		if t.tenants == nil {
+			t.tenants = map[K]*tenant[V]{}
+		}
+		e = &tenant[V]{done: make(chan struct{})}
+		t.tenants[key] = e
+	}
+	t.lock.Unlock()
+
+	if ok {
+		select {
+		case <-e.done:
+			return e.instance, e.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+
+	e.instance, e.err = create(ctx)
+	if e.err != nil {
+		t.lock.Lock()
+		if t.tenants[key] == e {
+			delete(t.tenants, key)
+		}
+		t.lock.Unlock()
+	}
+	close(e.done)
+	return e.instance, e.err
+}
+
+// Remove removes the instance of the given tenant, which is created
+// anew on next use. An instance still being created is removed as well
+// and never added once created.
+// Returns false if there's no instance of the given tenant.
+func (t *Tenants[K, V]) Remove(key K) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.tenants[key]; !ok {
+		return false
+	}
+	delete(t.tenants, key)
+	return true
+}
@@ -0,0 +1,123 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/romshark/goesgen/runtime"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenants(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	var tenants runtime.Tenants[string, *int]
+	created := 0
+	create := func(context.Context) (*int, error) {
+		created++
+		n := created
+		return &n, nil
+	}
+
+	a, err := tenants.Get(ctx, "a", create)
+	r.NoError(err)
+	b, err := tenants.Get(ctx, "b", create)
+	r.NoError(err)
+	r.NotSame(a, b)
+
+	a2, err := tenants.Get(ctx, "a", create)
+	r.NoError(err)
+	r.Same(a, a2)
+
+	// Removed instances are created anew
+	r.True(tenants.Remove("a"))
+	r.False(tenants.Remove("a"))
+	a3, err := tenants.Get(ctx, "a", create)
+	r.NoError(err)
+	r.NotSame(a, a3)
+	r.Equal(3, created)
+}
+
+func TestTenantsErr(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	var tenants runtime.Tenants[string, *int]
+	errCreate := errors.New("create failed")
+
+	_, err := tenants.Get(ctx, "a", func(context.Context) (*int, error) {
+		return nil, errCreate
+	})
+	r.Equal(errCreate, err)
+
+	// Failed instances are created anew
+	n := 1
+	a, err := tenants.Get(ctx, "a", func(context.Context) (*int, error) {
+		return &n, nil
+	})
+	r.NoError(err)
+	r.Same(&n, a)
+}
+
+func TestTenantsConcurrent(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	var tenants runtime.Tenants[string, *int]
+	release := make(chan struct{})
+	var lock sync.Mutex
+	created := 0
+	create := func(context.Context) (*int, error) {
+		<-release
+		lock.Lock()
+		defer lock.Unlock()
+		created++
+		return new(int), nil
+	}
+
+	var wg sync.WaitGroup
+	instances := make([]*int, 8)
+	for i := range instances {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			instances[i], _ = tenants.Get(ctx, "a", create)
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+	r.Equal(1, created)
+	for _, s := range instances {
+		r.NotNil(s)
+		r.Same(instances[0], s)
+	}
+}
+
+func TestTenantsRemovePending(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	var tenants runtime.Tenants[string, *int]
+	started, release := make(chan struct{}), make(chan struct{})
+
+	done := make(chan *int)
+	go func() {
+		s, _ := tenants.Get(ctx, "a", func(context.Context) (*int, error) {
+			close(started)
+			<-release
+			return new(int), nil
+		})
+		done <- s
+	}()
+	<-started
+	r.True(tenants.Remove("a"))
+	close(release)
+	stale := <-done
+
+	// The instance created before the removal isn't kept
+	s, err := tenants.Get(ctx, "a", func(context.Context) (*int, error) {
+		return new(int), nil
+	})
+	r.NoError(err)
+	r.NotSame(stale, s)
+}